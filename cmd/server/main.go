@@ -15,6 +15,7 @@ import (
 	"kalshi/internal/cache"
 	"kalshi/internal/config"
 	"kalshi/internal/gateway"
+	"kalshi/internal/health"
 	"kalshi/internal/ratelimit"
 	"kalshi/internal/storage"
 	"kalshi/pkg/logger"
@@ -35,8 +36,10 @@ type Application struct {
 	cacheManager  *cache.Manager
 	gateway       *gateway.Gateway
 	limiter       *ratelimit.Limiter
+	quotas        *ratelimit.QuotaManager // Nil unless quotas are enabled
 	jwtManager    *auth.JWTManager
 	apiKeyManager *auth.APIKeyManager
+	readiness     *health.Readiness
 	server        *http.Server
 	metricsServer *http.Server
 }
@@ -117,8 +120,29 @@ func initializeApplication(configPath string) (*Application, error) {
 	jwtManager := auth.NewJWTManager(cfg.Auth.JWT.Secret, cfg.Auth.JWT.AccessExpiry, cfg.Auth.JWT.RefreshExpiry)
 	apiKeyManager := auth.NewAPIKeyManager(stor)
 
+	// Initialize rate limit schedule
+	schedule, err := initializeSchedule(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize rate limit schedule: %w", err)
+	}
+
 	// Initialize rate limiter
-	limiter := ratelimit.NewLimiter(stor, cfg.RateLimit.DefaultRate, cfg.RateLimit.BurstCapacity)
+	limiter := ratelimit.NewLimiterWithOptions(stor, ratelimit.LimiterOptions{
+		DefaultRate:    cfg.RateLimit.DefaultRate,
+		BurstCapacity:  cfg.RateLimit.BurstCapacity,
+		FailureMode:    ratelimit.FailureMode(cfg.RateLimit.FailureMode),
+		StorageTimeout: cfg.RateLimit.StorageTimeout,
+		Algorithm:      ratelimit.AlgorithmType(cfg.RateLimit.Algorithm),
+		InProcess:      cfg.RateLimit.Storage == "memory",
+		LeaseTTL:       cfg.RateLimit.LeaseTTL,
+		Schedule:       schedule,
+	})
+
+	// Initialize usage quotas
+	quotas, err := initializeQuotas(cfg, stor, schedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize quotas: %w", err)
+	}
 
 	// Initialize gateway
 	gw := gateway.New(cfg, cacheManager, log)
@@ -131,8 +155,10 @@ func initializeApplication(configPath string) (*Application, error) {
 		cacheManager:  cacheManager,
 		gateway:       gw,
 		limiter:       limiter,
+		quotas:        quotas,
 		jwtManager:    jwtManager,
 		apiKeyManager: apiKeyManager,
+		readiness:     initializeReadiness(stor, cacheManager),
 	}
 
 	// Setup HTTP servers
@@ -141,6 +167,55 @@ func initializeApplication(configPath string) (*Application, error) {
 	return app, nil
 }
 
+// initializeSchedule creates the schedule of rate limit and quota policies
+// when it is enabled
+func initializeSchedule(cfg *config.Config) (*ratelimit.Schedule, error) {
+	scheduleCfg := cfg.RateLimit.Schedule
+	if !scheduleCfg.Enabled {
+		return nil, nil
+	}
+
+	open, close, err := scheduleCfg.Hours()
+	if err != nil {
+		return nil, err
+	}
+
+	policy := func(p config.SchedulePolicyConfig) ratelimit.SchedulePolicy {
+		return ratelimit.SchedulePolicy{
+			DefaultRate:       p.DefaultRate,
+			BurstCapacity:     p.BurstCapacity,
+			KeyRateMultiplier: p.KeyRateMultiplier,
+			MaxConcurrent:     p.MaxConcurrent,
+			QuotaMultiplier:   p.QuotaMultiplier,
+		}
+	}
+	return ratelimit.NewSchedule(scheduleCfg.Timezone, open, close, map[ratelimit.SchedulePeriod]ratelimit.SchedulePolicy{
+		ratelimit.PeriodTrading:  policy(scheduleCfg.Trading),
+		ratelimit.PeriodOffHours: policy(scheduleCfg.OffHours),
+		ratelimit.PeriodWeekend:  policy(scheduleCfg.Weekend),
+	})
+}
+
+// initializeQuotas creates the usage quota manager when quotas are enabled
+func initializeQuotas(cfg *config.Config, stor storage.Storage, schedule *ratelimit.Schedule) (*ratelimit.QuotaManager, error) {
+	if !cfg.RateLimit.Quota.Enabled {
+		return nil, nil
+	}
+
+	location, err := cfg.RateLimit.Quota.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	return ratelimit.NewQuotaManager(stor, ratelimit.QuotaOptions{
+		Location:          location,
+		WarningThresholds: cfg.RateLimit.Quota.WarningThresholds,
+		FailureMode:       ratelimit.FailureMode(cfg.RateLimit.FailureMode),
+		StorageTimeout:    cfg.RateLimit.StorageTimeout,
+		Schedule:          schedule,
+	}), nil
+}
+
 // initializeStorage creates the appropriate storage backend
 func initializeStorage(cfg *config.Config, log *logger.Logger) (storage.Storage, error) {
 	if cfg.RateLimit.Storage == "redis" {
@@ -159,14 +234,20 @@ func initializeStorage(cfg *config.Config, log *logger.Logger) (storage.Storage,
 // initializeCache creates the cache manager with L1 and L2 caches
 func initializeCache(cfg *config.Config, log *logger.Logger) (*cache.Manager, error) {
 	// L1 Cache (Memory)
-	l1Cache := cache.NewMemoryCache(cfg.Cache.Memory.MaxSize, cfg.Cache.Memory.TTL)
-	log.Info("Initialized L1 cache (memory)", "max_size", cfg.Cache.Memory.MaxSize)
+	l1Cache := cache.NewMemoryCacheWithOptions(cache.MemoryCacheOptions{
+		MaxEntries: cfg.Cache.Memory.MaxSize,
+		MaxBytes:   cfg.Cache.Memory.MaxBytes,
+		TTL:        cfg.Cache.Memory.TTL,
+		Shards:     cfg.Cache.Memory.Shards,
+	})
+	log.Info("Initialized L1 cache (memory)", "max_size", cfg.Cache.Memory.MaxSize, "max_bytes", cfg.Cache.Memory.MaxBytes)
 
 	// L2 Cache (Redis) - optional
 	var l2Cache cache.Cache
+	var redisCache *cache.RedisCache
 	if cfg.Cache.Redis.Addr != "" {
 		var err error
-		l2Cache, err = cache.NewRedisCache(
+		redisCache, err = cache.NewRedisCache(
 			cfg.Cache.Redis.Addr,
 			cfg.Cache.Redis.Password,
 			cfg.Cache.Redis.DB,
@@ -175,11 +256,52 @@ func initializeCache(cfg *config.Config, log *logger.Logger) (*cache.Manager, er
 		if err != nil {
 			log.Warn("Failed to initialize Redis cache, using memory only", "error", err)
 		} else {
+			l2Cache = redisCache
 			log.Info("Initialized L2 cache (Redis)", "addr", cfg.Cache.Redis.Addr)
 		}
 	}
 
-	return cache.NewManager(l1Cache, l2Cache, true), nil
+	manager := cache.NewManager(l1Cache, l2Cache, true)
+
+	// Count lookups so the most requested keys can be warmed, including
+	// those that were popular before a restart
+	manager.TrackPopularity(cfg.Cache.Warming.TrackedKeys)
+	if err := manager.LoadPopularity(context.Background()); err != nil {
+		log.Warn("Failed to load cache popularity", "error", err)
+	}
+
+	// Keep every replica's L1 consistent with invalidations made elsewhere
+	if l2Cache != nil && cfg.Cache.Coherence.Enabled {
+		bus := cache.NewRedisBus(redisCache, cfg.Cache.Coherence.Channel)
+		err := manager.EnableCoherence(bus, cache.CoherenceOptions{
+			EpochCheckInterval: cfg.Cache.Coherence.EpochCheckInterval,
+		})
+		if err != nil {
+			log.Warn("Failed to enable cache coherence, L1 invalidations stay local", "error", err)
+		} else {
+			log.Info("Enabled cache coherence", "channel", cfg.Cache.Coherence.Channel)
+		}
+	}
+
+	return manager, nil
+}
+
+// initializeReadiness registers the dependency checks behind /ready
+func initializeReadiness(stor storage.Storage, cacheManager *cache.Manager) *health.Readiness {
+	readiness := health.NewReadiness(health.DefaultCheckTimeout)
+
+	// The configuration is validated once at load and never reloaded, so it
+	// has no readiness check
+	readiness.Register("storage", health.StorageRoundTripCheck(stor))
+	if redisStorage, ok := stor.(*storage.RedisStorage); ok {
+		readiness.Register("redis_storage", health.PingCheck(redisStorage))
+	}
+
+	if cacheManager.HasL2() {
+		readiness.Register("redis_cache", health.PingCheck(cacheManager))
+	}
+
+	return readiness
 }
 
 // setupServers configures HTTP servers
@@ -189,8 +311,10 @@ func (app *Application) setupServers() {
 		Config:        app.config,
 		Gateway:       app.gateway,
 		Limiter:       app.limiter,
+		Quotas:        app.quotas,
 		JWTManager:    app.jwtManager,
 		APIKeyManager: app.apiKeyManager,
+		Readiness:     app.readiness,
 		Logger:        app.logger,
 	}
 
@@ -235,7 +359,34 @@ func (app *Application) start() error {
 	}
 
 	// Health check - wait for server to be ready
-	return app.waitForServerReady()
+	if err := app.waitForServerReady(); err != nil {
+		return err
+	}
+
+	if app.config.Cache.Warming.OnStartup {
+		go app.warmCache()
+	}
+	return nil
+}
+
+// warmCache fetches warm URLs and popular keys so a fresh deploy does not
+// start with a cold cache
+func (app *Application) warmCache() {
+	warming := app.config.Cache.Warming
+	ctx := context.Background()
+	if warming.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, warming.Timeout)
+		defer cancel()
+	}
+
+	result := app.gateway.WarmCache(ctx, warming.PopularKeys)
+	app.logger.Info("Cache warmed",
+		"warmed", result.Warmed,
+		"fresh", result.Fresh,
+		"skipped", result.Skipped,
+		"failed", result.Failed,
+	)
 }
 
 // waitForServerReady waits for the server to start accepting connections
@@ -268,6 +419,11 @@ func (app *Application) waitForShutdown() {
 func (app *Application) shutdown() {
 	app.logger.Info("Shutting down application...")
 
+	// Fail readiness first and keep serving while load balancers notice, so
+	// they stop routing new traffic here before the listener closes
+	app.logger.Info("Draining before shutdown", "delay", app.config.Server.ShutdownDrainDelay)
+	app.readiness.Drain(context.Background(), app.config.Server.ShutdownDrainDelay)
+
 	// Create context with timeout for graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -288,6 +444,19 @@ func (app *Application) shutdown() {
 		}
 	}
 
+	// Stop service discovery and close its connections
+	if app.gateway != nil {
+		app.gateway.Close()
+	}
+
+	// Stop the in-process limiter's cleanup
+	if app.limiter != nil {
+		app.limiter.Close()
+	}
+	if app.quotas != nil {
+		app.quotas.Close()
+	}
+
 	// Close storage connections
 	if app.storage != nil {
 		if err := app.storage.Close(); err != nil {
@@ -299,6 +468,9 @@ func (app *Application) shutdown() {
 
 	// Close cache connections
 	if app.cacheManager != nil {
+		if err := app.cacheManager.SavePopularity(ctx); err != nil {
+			app.logger.Error("Error saving cache popularity", "error", err)
+		}
 		// Assuming cache manager has a Close method
 		app.logger.Info("Cache connections closed")
 	}
@@ -1,23 +1,36 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"kalshi/internal/cache"
 	"kalshi/internal/gateway"
+	"kalshi/internal/ratelimit"
 	"kalshi/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
+// defaultTopThrottled is how many clients the rate limit endpoints list
+// when the request does not say
+const defaultTopThrottled = 10
+
 type AdminHandler struct {
 	gateway *gateway.Gateway
+	limiter *ratelimit.Limiter // Nil disables the rate limit endpoints
 	logger  *logger.Logger
 }
 
-func NewAdminHandler(gateway *gateway.Gateway, logger *logger.Logger) *AdminHandler {
+func NewAdminHandler(gateway *gateway.Gateway, limiter *ratelimit.Limiter, logger *logger.Logger) *AdminHandler {
 	return &AdminHandler{
 		gateway: gateway,
+		limiter: limiter,
 		logger:  logger,
 	}
 }
@@ -28,13 +41,19 @@ func (h *AdminHandler) GetBackends(c *gin.Context) {
 
 	backendInfo := make([]gin.H, 0, len(backends))
 	for _, backend := range backends {
-		backendInfo = append(backendInfo, gin.H{
+		info := gin.H{
 			"name":         backend.Name,
 			"url":          backend.URL.String(),
 			"health_check": backend.HealthCheck,
 			"weight":       backend.Weight,
 			"healthy":      backend.IsHealthy,
-		})
+			"pool":         backend.Pool,
+			"ejected":      backend.Ejected(),
+		}
+		if ejectedUntil := backend.EjectedUntil(); !ejectedUntil.IsZero() {
+			info["ejected_until"] = ejectedUntil
+		}
+		backendInfo = append(backendInfo, info)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -229,6 +248,80 @@ func (h *AdminHandler) DisableBackend(c *gin.Context) {
 	})
 }
 
+// GetBackendHistory returns the health transitions of a backend and whether
+// it is flapping. The flap window and threshold can be tuned with the
+// window and threshold query parameters.
+func (h *AdminHandler) GetBackendHistory(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Backend name parameter is required",
+		})
+		return
+	}
+
+	backend, err := h.gateway.GetBackendManager().GetBackendByName(name)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	window := gateway.DefaultFlapWindow
+	if raw := c.Query("window"); raw != "" {
+		window, err = time.ParseDuration(raw)
+		if err != nil || window <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "window must be a positive duration",
+			})
+			return
+		}
+	}
+
+	threshold := gateway.DefaultFlapThreshold
+	if raw := c.Query("threshold"); raw != "" {
+		threshold, err = strconv.Atoi(raw)
+		if err != nil || threshold <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "threshold must be a positive integer",
+			})
+			return
+		}
+	}
+
+	history := backend.History()
+	transitions := make([]gin.H, 0, len(history))
+	for _, transition := range history {
+		entry := gin.H{
+			"time":    transition.Time,
+			"healthy": transition.Healthy,
+			"source":  transition.Source,
+		}
+		if transition.Latency > 0 {
+			entry["latency_ms"] = transition.Latency.Milliseconds()
+		}
+		if transition.Reason != "" {
+			entry["reason"] = transition.Reason
+		}
+		transitions = append(transitions, entry)
+	}
+
+	flap := backend.FlapStatus(window, threshold)
+	c.JSON(http.StatusOK, gin.H{
+		"backend":     backend.Name,
+		"healthy":     backend.Healthy(),
+		"transitions": transitions,
+		"total":       len(transitions),
+		"flapping": gin.H{
+			"flapping":    flap.Flapping,
+			"transitions": flap.Transitions,
+			"window":      flap.Window.String(),
+			"threshold":   flap.Threshold,
+		},
+	})
+}
+
 // GetCircuitBreaker returns a specific circuit breaker state
 func (h *AdminHandler) GetCircuitBreaker(c *gin.Context) {
 	backend := c.Param("backend")
@@ -306,25 +399,42 @@ func (h *AdminHandler) CloseCircuitBreaker(c *gin.Context) {
 
 // GetCacheStats returns cache statistics
 func (h *AdminHandler) GetCacheStats(c *gin.Context) {
-	// Basic cache stats - implement based on your cache implementation
-	c.JSON(http.StatusOK, gin.H{
-		"cache_type": "memory",
-		"status":     "available",
-		"message":    "Cache statistics not implemented yet",
-	})
+	switch cm := h.gateway.GetCacheManager().(type) {
+	case *cache.Manager:
+		c.JSON(http.StatusOK, gin.H{
+			"cache_type": "tiered",
+			"status":     "available",
+			"stats":      cm.Stats(),
+		})
+	case *cache.MemoryCache:
+		c.JSON(http.StatusOK, gin.H{
+			"cache_type": "memory",
+			"status":     "available",
+			"stats":      cm.Stats(),
+		})
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"cache_type": "unknown",
+			"status":     "available",
+			"message":    "Cache does not report statistics",
+		})
+	}
 }
 
 // ClearCache clears all cached data
 func (h *AdminHandler) ClearCache(c *gin.Context) {
-	// Implement cache clearing based on your cache implementation
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Cache cleared successfully",
-	})
+	h.purgeCache(c, gateway.PurgeRequest{All: true})
+}
+
+// cacheKeyParam returns the cache key addressed by a wildcard route. Keys
+// contain slashes, spaces and query strings, so clients percent-encode them.
+func cacheKeyParam(c *gin.Context) string {
+	return strings.TrimPrefix(c.Param("key"), "/")
 }
 
-// DeleteCacheKey deletes a specific cache key
+// DeleteCacheKey deletes a specific cache key and its Vary variants
 func (h *AdminHandler) DeleteCacheKey(c *gin.Context) {
-	key := c.Param("key")
+	key := cacheKeyParam(c)
 	if key == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Cache key parameter is required",
@@ -332,16 +442,137 @@ func (h *AdminHandler) DeleteCacheKey(c *gin.Context) {
 		return
 	}
 
-	// Implement cache key deletion based on your cache implementation
+	h.purgeCache(c, gateway.PurgeRequest{Keys: []string{key}})
+}
+
+// PurgeCache invalidates cached responses by key, key prefix or surrogate tag
+func (h *AdminHandler) PurgeCache(c *gin.Context) {
+	var req gateway.PurgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	if req.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "At least one of keys, prefixes, tags or all is required",
+		})
+		return
+	}
+
+	h.purgeCache(c, req)
+}
+
+// PurgeCacheTag invalidates every cached response carrying a surrogate tag
+func (h *AdminHandler) PurgeCacheTag(c *gin.Context) {
+	tag := c.Param("tag")
+	if tag == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Tag parameter is required",
+		})
+		return
+	}
+
+	h.purgeCache(c, gateway.PurgeRequest{Tags: []string{tag}})
+}
+
+func (h *AdminHandler) purgeCache(c *gin.Context, req gateway.PurgeRequest) {
+	purged, err := h.gateway.GetProxy().Purge(c.Request.Context(), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gateway.ErrInvalidationUnsupported) {
+			status = http.StatusNotImplemented
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.logger.WithFields(map[string]interface{}{
+		"keys":     req.Keys,
+		"prefixes": req.Prefixes,
+		"tags":     req.Tags,
+		"all":      req.All,
+		"purged":   purged,
+		"admin_ip": c.ClientIP(),
+	}).Info("Cache purged")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cache purged successfully",
+		"purged":  purged,
+	})
+}
+
+// ListCacheKeys lists cached keys, optionally filtered by a prefix
+func (h *AdminHandler) ListCacheKeys(c *gin.Context) {
+	keys, err := h.gateway.GetProxy().CacheKeys(c.Request.Context(), c.Query("prefix"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gateway.ErrInvalidationUnsupported) {
+			status = http.StatusNotImplemented
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"keys":  keys,
+		"total": len(keys),
+	})
+}
+
+// WarmCache fetches route warm URLs and the most requested cache keys into
+// the cache. The body may set popular_keys to override the configured count.
+func (h *AdminHandler) WarmCache(c *gin.Context) {
+	cfg := h.gateway.GetConfig().Cache.Warming
+	req := struct {
+		PopularKeys *int `json:"popular_keys"`
+	}{}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil || (req.PopularKeys != nil && *req.PopularKeys < 0) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request body",
+			})
+			return
+		}
+	}
+
+	popularKeys := cfg.PopularKeys
+	if req.PopularKeys != nil {
+		popularKeys = *req.PopularKeys
+	}
+
+	ctx := c.Request.Context()
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	result := h.gateway.WarmCache(ctx, popularKeys)
+
+	h.logger.WithFields(map[string]interface{}{
+		"warmed":   result.Warmed,
+		"fresh":    result.Fresh,
+		"skipped":  result.Skipped,
+		"failed":   result.Failed,
+		"admin_ip": c.ClientIP(),
+	}).Info("Cache warmed")
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Cache key deleted successfully",
-		"key":     key,
+		"message": "Cache warmed",
+		"result":  result,
 	})
 }
 
 // GetCacheKey retrieves a specific cache key
 func (h *AdminHandler) GetCacheKey(c *gin.Context) {
-	key := c.Param("key")
+	key := cacheKeyParam(c)
 	if key == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Cache key parameter is required",
@@ -349,24 +580,71 @@ func (h *AdminHandler) GetCacheKey(c *gin.Context) {
 		return
 	}
 
-	// Implement cache key retrieval based on your cache implementation
+	value, err := h.gateway.GetCacheManager().Get(c.Request.Context(), key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Cache key not found",
+			"key":   key,
+		})
+		return
+	}
+
+	// Cached responses are JSON envelopes; anything else is shown as text
+	var entry interface{} = string(value)
+	if json.Valid(value) {
+		entry = json.RawMessage(value)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"key":   key,
-		"value": "Cache key retrieval not implemented yet",
+		"value": entry,
 	})
 }
 
-// GetRateLimitStats returns rate limiting statistics
+// GetRateLimitStats returns the limiter's configuration and schedule period,
+// the requests it has decided for recently active clients and the most
+// throttled of them
 func (h *AdminHandler) GetRateLimitStats(c *gin.Context) {
-	// Basic rate limit stats - implement based on your rate limiting implementation
+	if !h.requireLimiter(c) {
+		return
+	}
+
+	top, ok := topParam(c)
+	if !ok {
+		return
+	}
+
+	global := h.limiter.GlobalLimit("")
+	c.JSON(http.StatusOK, gin.H{
+		"algorithm":      h.limiter.Algorithm(),
+		"default_rate":   global.Rate,
+		"burst_capacity": global.Burst,
+		"schedule":       h.limiter.ScheduleStatus(),
+		"activity":       h.limiter.ActivityStats(),
+		"top_throttled":  h.limiter.TopThrottled(top),
+	})
+}
+
+// GetTopThrottled lists the clients with the most rejected requests
+func (h *AdminHandler) GetTopThrottled(c *gin.Context) {
+	if !h.requireLimiter(c) {
+		return
+	}
+
+	top, ok := topParam(c)
+	if !ok {
+		return
+	}
+
+	clients := h.limiter.TopThrottled(top)
 	c.JSON(http.StatusOK, gin.H{
-		"rate_limit_type": "token_bucket",
-		"status":          "active",
-		"message":         "Rate limit statistics not implemented yet",
+		"clients": clients,
+		"total":   len(clients),
 	})
 }
 
-// ResetRateLimit resets rate limiting for a specific client
+// ResetRateLimit refills every bucket a client has used and clears its
+// counters
 func (h *AdminHandler) ResetRateLimit(c *gin.Context) {
 	clientId := c.Param("clientId")
 	if clientId == "" {
@@ -376,14 +654,33 @@ func (h *AdminHandler) ResetRateLimit(c *gin.Context) {
 		return
 	}
 
-	// Implement rate limit reset based on your rate limiting implementation
+	if !h.requireLimiter(c) {
+		return
+	}
+
+	reset, err := h.limiter.ResetClient(c.Request.Context(), clientId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.logger.WithFields(map[string]interface{}{
+		"client_id": clientId,
+		"buckets":   reset,
+		"admin_ip":  c.ClientIP(),
+	}).Info("Rate limit reset")
+
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Rate limit reset successfully",
-		"clientId": clientId,
+		"message":       "Rate limit reset successfully",
+		"clientId":      clientId,
+		"buckets_reset": reset,
 	})
 }
 
-// GetRateLimitStatus returns rate limiting status for a specific client
+// GetRateLimitStatus returns the state of a client's buckets, its recent
+// requests and any override
 func (h *AdminHandler) GetRateLimitStatus(c *gin.Context) {
 	clientId := c.Param("clientId")
 	if clientId == "" {
@@ -393,13 +690,146 @@ func (h *AdminHandler) GetRateLimitStatus(c *gin.Context) {
 		return
 	}
 
-	// Implement rate limit status based on your rate limiting implementation
+	if !h.requireLimiter(c) {
+		return
+	}
+
+	status, err := h.limiter.Status(c.Request.Context(), clientId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SetRateLimitOverride temporarily replaces a client's limit. The body sets
+// rate in requests per minute, an optional burst, and either duration or
+// expires_at.
+func (h *AdminHandler) SetRateLimitOverride(c *gin.Context) {
+	clientId := c.Param("clientId")
+	if clientId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Client ID parameter is required",
+		})
+		return
+	}
+
+	if !h.requireLimiter(c) {
+		return
+	}
+
+	req := struct {
+		Rate      int       `json:"rate"`
+		Burst     int       `json:"burst"`
+		Duration  string    `json:"duration"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	override := ratelimit.Override{Rate: req.Rate, Burst: req.Burst, ExpiresAt: req.ExpiresAt}
+	if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid duration",
+			})
+			return
+		}
+		override.ExpiresAt = time.Now().Add(duration)
+	}
+
+	if err := h.limiter.SetOverride(c.Request.Context(), clientId, override); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ratelimit.ErrInvalidOverride) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.logger.WithFields(map[string]interface{}{
+		"client_id":  clientId,
+		"rate":       override.Rate,
+		"burst":      override.Burst,
+		"expires_at": override.ExpiresAt,
+		"admin_ip":   c.ClientIP(),
+	}).Info("Rate limit override set")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Rate limit override set successfully",
+		"clientId": clientId,
+		"override": override,
+	})
+}
+
+// RemoveRateLimitOverride restores a client's configured limits
+func (h *AdminHandler) RemoveRateLimitOverride(c *gin.Context) {
+	clientId := c.Param("clientId")
+	if clientId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Client ID parameter is required",
+		})
+		return
+	}
+
+	if !h.requireLimiter(c) {
+		return
+	}
+
+	if err := h.limiter.RemoveOverride(c.Request.Context(), clientId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.logger.WithFields(map[string]interface{}{
+		"client_id": clientId,
+		"admin_ip":  c.ClientIP(),
+	}).Info("Rate limit override removed")
+
 	c.JSON(http.StatusOK, gin.H{
+		"message":  "Rate limit override removed successfully",
 		"clientId": clientId,
-		"status":   "Rate limit status not implemented yet",
 	})
 }
 
+// requireLimiter responds with 503 when no limiter is configured
+func (h *AdminHandler) requireLimiter(c *gin.Context) bool {
+	if h.limiter == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Rate limiter not configured",
+		})
+		return false
+	}
+	return true
+}
+
+// topParam reads how many clients to list from the top query parameter
+func topParam(c *gin.Context) (int, bool) {
+	top := defaultTopThrottled
+	if value := c.Query("top"); value != "" {
+		var err error
+		if top, err = strconv.Atoi(value); err != nil || top <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "top must be a positive integer",
+			})
+			return 0, false
+		}
+	}
+	return top, true
+}
+
 // GetSystemInfo returns system information
 func (h *AdminHandler) GetSystemInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
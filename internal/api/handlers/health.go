@@ -1,20 +1,60 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"kalshi/internal/gateway"
+	"kalshi/internal/health"
 
 	"github.com/gin-gonic/gin"
 )
 
 type HealthHandler struct {
-	gateway *gateway.Gateway
+	gateway   *gateway.Gateway
+	readiness *health.Readiness
 }
 
-func NewHealthHandler(gateway *gateway.Gateway) *HealthHandler {
-	return &HealthHandler{gateway: gateway}
+// NewHealthHandler creates a health handler. The backend check is always
+// registered on readiness; a nil readiness only checks backends.
+func NewHealthHandler(gateway *gateway.Gateway, readiness *health.Readiness) *HealthHandler {
+	if readiness == nil {
+		readiness = health.NewReadiness(0)
+	}
+
+	h := &HealthHandler{
+		gateway:   gateway,
+		readiness: readiness,
+	}
+	readiness.Register("backends", h.checkBackends)
+	return h
+}
+
+// checkBackends fails when no backend can receive traffic
+func (h *HealthHandler) checkBackends(ctx context.Context) error {
+	if len(h.gateway.GetBackendManager().GetHealthyBackends()) == 0 {
+		return errors.New("no healthy backends available")
+	}
+	return nil
+}
+
+// formatChecks renders check results with millisecond latencies
+func formatChecks(results []health.CheckResult) []gin.H {
+	checks := make([]gin.H, 0, len(results))
+	for _, result := range results {
+		check := gin.H{
+			"name":       result.Name,
+			"healthy":    result.Healthy,
+			"latency_ms": float64(result.Latency.Microseconds()) / 1000,
+		}
+		if result.Error != "" {
+			check["error"] = result.Error
+		}
+		checks = append(checks, check)
+	}
+	return checks
 }
 
 // Health returns basic health status
@@ -52,33 +92,75 @@ func (h *HealthHandler) DetailedHealth(c *gin.Context) {
 		}
 	}
 
+	report := h.readiness.Check(c.Request.Context())
+	status := "healthy"
+	if !report.Ready {
+		status = "degraded"
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"status":           "healthy",
+		"status":           status,
 		"timestamp":        time.Now().UTC(),
 		"version":          "1.0.0",
 		"service":          "kalshi-api-gateway",
 		"healthy_backends": backendStatus,
 		"all_backends":     allBackendStatus,
 		"circuit_breaker":  circuitStates,
+		"readiness":        formatChecks(report.Checks),
+		"shutting_down":    report.ShuttingDown,
 		"uptime":           time.Since(startTime).String(),
 	})
 }
 
-// Readiness check for Kubernetes
+// Readiness check for Kubernetes. Fails as soon as shutdown begins so load
+// balancers drain the gateway, and whenever a dependency check fails.
 func (h *HealthHandler) Readiness(c *gin.Context) {
-	healthyBackends := h.gateway.GetBackendManager().GetHealthyBackends()
+	report := h.readiness.Check(c.Request.Context())
 
-	if len(healthyBackends) == 0 {
+	if report.ShuttingDown {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"status": "not ready",
-			"reason": "no healthy backends available",
+			"reason": health.ErrShuttingDown.Error(),
+		})
+		return
+	}
+
+	if !report.Ready {
+		failed := make([]string, 0)
+		for _, result := range report.Checks {
+			if !result.Healthy {
+				failed = append(failed, result.Name+": "+result.Error)
+			}
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "not ready",
+			"reason": failed,
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":           "ready",
-		"healthy_backends": len(healthyBackends),
+		"healthy_backends": len(h.gateway.GetBackendManager().GetHealthyBackends()),
+	})
+}
+
+// ReadinessDetails reports every dependency check with its latency and error
+func (h *HealthHandler) ReadinessDetails(c *gin.Context) {
+	report := h.readiness.Check(c.Request.Context())
+
+	status := "ready"
+	code := http.StatusOK
+	if !report.Ready {
+		status = "not ready"
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, gin.H{
+		"status":        status,
+		"shutting_down": report.ShuttingDown,
+		"checks":        formatChecks(report.Checks),
+		"timestamp":     time.Now().UTC(),
 	})
 }
 
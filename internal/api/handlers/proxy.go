@@ -2,9 +2,8 @@ package handlers
 
 import (
 	"net/http"
-	"strings"
-	"time"
 
+	"kalshi/internal/api/middleware"
 	"kalshi/internal/config"
 	"kalshi/internal/gateway"
 	"kalshi/pkg/logger"
@@ -17,7 +16,7 @@ type ProxyHandler struct {
 	config  *config.Config
 	logger  *logger.Logger
 	proxy   interface {
-		ServeHTTP(http.ResponseWriter, *http.Request, string, time.Duration)
+		ServeHTTPWithPolicy(http.ResponseWriter, *http.Request, string, gateway.CachePolicy)
 	}
 }
 
@@ -63,10 +62,9 @@ func (h *ProxyHandler) HandleRequest(c *gin.Context) {
 	c.Set("backend", route.Backend)
 	c.Set("route_path", route.Path)
 
-	// Get cache TTL (use route-specific or default)
-	cacheTTL := route.CacheTTL
-	if cacheTTL == 0 {
-		cacheTTL = 5 * time.Minute // default cache TTL
+	policy := gateway.RouteCachePolicy(route)
+	if policy.Key.PerUser {
+		policy.Key.Identity = c.GetString(middleware.ContextUserID)
 	}
 
 	// Log the request
@@ -74,68 +72,21 @@ func (h *ProxyHandler) HandleRequest(c *gin.Context) {
 		"path":      path,
 		"method":    method,
 		"backend":   route.Backend,
-		"cache_ttl": cacheTTL.String(),
+		"cache_ttl": policy.TTL.String(),
 		"client_ip": c.ClientIP(),
 	}).Info("Proxying request")
 
 	// Proxy the request
 	if h.proxy != nil {
-		h.proxy.ServeHTTP(c.Writer, c.Request, route.Backend, cacheTTL)
+		h.proxy.ServeHTTPWithPolicy(c.Writer, c.Request, route.Backend, policy)
 	} else {
-		h.gateway.GetProxy().ServeHTTP(c.Writer, c.Request, route.Backend, cacheTTL)
+		h.gateway.GetProxy().ServeHTTPWithPolicy(c.Writer, c.Request, route.Backend, policy)
 	}
 }
 
 // findMatchingRoute finds the first route that matches the given path
 func (h *ProxyHandler) findMatchingRoute(path, method string) *config.RouteConfig {
-	for _, route := range h.config.Routes {
-		if h.matchRoute(path, route.Path) {
-			return &route
-		}
-	}
-	return nil
-}
-
-// matchRoute performs path matching with wildcard support
-func (h *ProxyHandler) matchRoute(path, pattern string) bool {
-	// Exact match
-	if path == pattern {
-		return true
-	}
-
-	// Wildcard matching
-	if strings.HasSuffix(pattern, "*") {
-		prefix := strings.TrimSuffix(pattern, "*")
-		return strings.HasPrefix(path, prefix)
-	}
-
-	// Path parameter matching (basic implementation)
-	if strings.HasPrefix(pattern, ":") {
-		return h.matchWithParams(path, pattern)
-	}
-	return false
-}
-
-// matchWithParams handles path parameters like /api/users/:id
-func (h *ProxyHandler) matchWithParams(path, pattern string) bool {
-	pathParts := strings.Split(strings.Trim(path, "/"), "/")
-	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
-
-	if len(pathParts) != len(patternParts) {
-		return false
-	}
-
-	for i, part := range patternParts {
-		if strings.HasPrefix(part, ":") {
-			// This is a parameter, skip validation
-			continue
-		}
-
-		if part != pathParts[i] {
-			return false
-		}
-	}
-	return true
+	return h.config.MatchRoute(path)
 }
 
 // isMethodAllowed checks if the HTTP method is allowed for the route
@@ -154,11 +105,15 @@ func (h *ProxyHandler) GetRoutes(c *gin.Context) {
 
 	for _, route := range h.config.Routes {
 		routes = append(routes, gin.H{
-			"path":       route.Path,
-			"backend":    route.Backend,
-			"methods":    route.Methods,
-			"rate_limit": route.RateLimit,
-			"cache_ttl":  route.CacheTTL.String(),
+			"path":                 route.Path,
+			"backend":              route.Backend,
+			"methods":              route.Methods,
+			"rate_limit":           route.RateLimit,
+			"rate_limit_algorithm": route.RateLimitAlgorithm,
+			"cost":                 route.Cost,
+			"cache_ttl":            route.CacheTTL.String(),
+			"cache_mode":           route.CacheMode,
+			"cache_key":            route.CacheKey,
 		})
 	}
 
@@ -182,11 +137,15 @@ func (h *ProxyHandler) GetRoute(c *gin.Context) {
 	for _, route := range h.config.Routes {
 		if route.Path == id {
 			c.JSON(http.StatusOK, gin.H{
-				"path":       route.Path,
-				"backend":    route.Backend,
-				"methods":    route.Methods,
-				"rate_limit": route.RateLimit,
-				"cache_ttl":  route.CacheTTL.String(),
+				"path":                 route.Path,
+				"backend":              route.Backend,
+				"methods":              route.Methods,
+				"rate_limit":           route.RateLimit,
+				"rate_limit_algorithm": route.RateLimitAlgorithm,
+				"cost":                 route.Cost,
+				"cache_ttl":            route.CacheTTL.String(),
+				"cache_mode":           route.CacheMode,
+				"cache_key":            route.CacheKey,
 			})
 			return
 		}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"kalshi/internal/auth"
+	"kalshi/internal/ratelimit"
 	"kalshi/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -19,10 +20,12 @@ const (
 	ErrInvalidAPIKey     = "Invalid API key"
 
 	// Context keys
-	ContextUserID     = "user_id"
-	ContextRole       = "role"
-	ContextAuthMethod = "auth_method"
-	ContextRateLimit  = "rate_limit"
+	ContextUserID        = "user_id"
+	ContextRole          = "role"
+	ContextAuthMethod    = "auth_method"
+	ContextRateLimit     = "rate_limit"
+	ContextQuotas        = "quotas"
+	ContextMaxConcurrent = "max_concurrent"
 
 	// Auth methods
 	AuthMethodJWT    = "jwt"
@@ -115,9 +118,7 @@ func APIKeyAuth(apiKeyManager *auth.APIKeyManager, header string, log *logger.Lo
 		}
 
 		// Store key info in context
-		c.Set(ContextUserID, keyInfo.UserID)
-		c.Set(ContextRateLimit, keyInfo.RateLimit)
-		c.Set(ContextAuthMethod, AuthMethodAPIKey)
+		setAPIKeyContext(c, keyInfo)
 
 		log.Debug("API key authentication successful",
 			"user_id", keyInfo.UserID,
@@ -157,9 +158,7 @@ func OptionalAuth(jwtManager *auth.JWTManager, apiKeyManager *auth.APIKeyManager
 
 		if apiKey != "" {
 			if keyInfo, err := apiKeyManager.ValidateAPIKey(c.Request.Context(), apiKey); err == nil {
-				c.Set(ContextUserID, keyInfo.UserID)
-				c.Set(ContextRateLimit, keyInfo.RateLimit)
-				c.Set(ContextAuthMethod, AuthMethodAPIKey)
+				setAPIKeyContext(c, keyInfo)
 				c.Next()
 				return
 			}
@@ -201,9 +200,7 @@ func RequireAuth(jwtManager *auth.JWTManager, apiKeyManager *auth.APIKeyManager,
 
 		if apiKey != "" {
 			if keyInfo, err := apiKeyManager.ValidateAPIKey(c.Request.Context(), apiKey); err == nil {
-				c.Set(ContextUserID, keyInfo.UserID)
-				c.Set(ContextRateLimit, keyInfo.RateLimit)
-				c.Set(ContextAuthMethod, AuthMethodAPIKey)
+				setAPIKeyContext(c, keyInfo)
 				c.Next()
 				return
 			}
@@ -217,3 +214,25 @@ func RequireAuth(jwtManager *auth.JWTManager, apiKeyManager *auth.APIKeyManager,
 		c.Abort()
 	}
 }
+
+// setAPIKeyContext stores an authenticated API key's user and limits in the
+// request context
+func setAPIKeyContext(c *gin.Context, keyInfo *auth.APIKeyInfo) {
+	c.Set(ContextUserID, keyInfo.UserID)
+	c.Set(ContextRateLimit, keyInfo.RateLimit)
+	c.Set(ContextAuthMethod, AuthMethodAPIKey)
+	if keyInfo.MaxConcurrent > 0 {
+		c.Set(ContextMaxConcurrent, keyInfo.MaxConcurrent)
+	}
+
+	var quotas []ratelimit.Quota
+	if keyInfo.DailyQuota > 0 {
+		quotas = append(quotas, ratelimit.Quota{Period: ratelimit.QuotaDaily, Limit: keyInfo.DailyQuota})
+	}
+	if keyInfo.MonthlyQuota > 0 {
+		quotas = append(quotas, ratelimit.Quota{Period: ratelimit.QuotaMonthly, Limit: keyInfo.MonthlyQuota})
+	}
+	if len(quotas) > 0 {
+		c.Set(ContextQuotas, quotas)
+	}
+}
@@ -14,7 +14,7 @@ const (
 	// DefaultAllowedHeaders are the default headers allowed in CORS
 	DefaultAllowedHeaders = "Origin, Content-Type, Authorization, X-API-Key, X-Client-ID"
 	// DefaultExposeHeaders are the default headers exposed in CORS responses
-	DefaultExposeHeaders = "Content-Length, X-RateLimit-Limit, X-RateLimit-Remaining, X-Cache"
+	DefaultExposeHeaders = "Content-Length, X-RateLimit-Limit, X-RateLimit-Remaining, X-Cache, Age"
 )
 
 // CORS handles Cross-Origin Resource Sharing with permissive settings.
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"kalshi/internal/config"
+	"kalshi/internal/ratelimit"
+	"kalshi/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Quota headers
+	QuotaLimitHeader     = "X-Quota-Limit"
+	QuotaRemainingHeader = "X-Quota-Remaining"
+	QuotaResetHeader     = "X-Quota-Reset"
+	QuotaPeriodHeader    = "X-Quota-Period"
+	QuotaWarningHeader   = "X-Quota-Warning"
+)
+
+// Quota counts requests against the client's daily and monthly quotas and
+// rejects them once a quota is used up. The configured defaults apply to
+// every client; an API key's own quotas replace them. With weighted quotas
+// a request counts as the cost of the route it matches, and a higher cost
+// reported by the backend is charged after the response.
+func Quota(quotas *ratelimit.QuotaManager, cfg *config.Config, log *logger.Logger) gin.HandlerFunc {
+	weighted := cfg.RateLimit.Quota.Weighted
+
+	return func(c *gin.Context) {
+		requested := requestQuotas(c, cfg.RateLimit.Quota)
+		if len(requested) == 0 {
+			c.Next()
+			return
+		}
+
+		clientID := getClientID(c)
+		cost := int64(1)
+		if weighted {
+			cost = routeCost(matchRoute(c, cfg))
+		}
+		decision, err := quotas.Consume(c.Request.Context(), clientID, requested, cost)
+		if err != nil {
+			log.Error("Quota check failed",
+				"error", err,
+				"client_id", clientID,
+			)
+
+			if errors.Is(err, ratelimit.ErrLimiterUnavailable) {
+				c.Header(RetryAfterHeader, "1")
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error": "Quota service unavailable",
+				})
+				c.Abort()
+				return
+			}
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Quota check failed",
+			})
+			c.Abort()
+			return
+		}
+
+		usage := decision.Tightest()
+		if usage == nil {
+			c.Next()
+			return
+		}
+		setQuotaHeaders(c, usage)
+
+		if !decision.Allowed {
+			retryAfter := retryAfterSeconds(time.Until(usage.ResetsAt))
+			c.Header(RetryAfterHeader, strconv.FormatInt(retryAfter, 10))
+
+			log.Warn("Quota exceeded",
+				"client_id", clientID,
+				"period", usage.Period,
+				"quota", usage.Limit,
+			)
+
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":     "Quota exceeded",
+				"message":   fmt.Sprintf("%s quota of %d requests used up", usage.Period, usage.Limit),
+				"period":    usage.Period,
+				"limit":     usage.Limit,
+				"used":      usage.Used,
+				"resets_at": usage.ResetsAt.UTC().Format(time.RFC3339),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+
+		if !weighted {
+			return
+		}
+		if actual := reportedCost(c, cfg.RateLimit.CostHeader); actual > cost {
+			if err := quotas.Charge(c.Request.Context(), clientID, requested, actual-cost); err != nil {
+				log.Warn("Failed to charge reported request cost to quotas",
+					"error", err,
+					"client_id", clientID,
+					"cost", actual,
+				)
+			}
+		}
+	}
+}
+
+// requestQuotas returns the quotas the request counts against: the API
+// key's own where it sets them, and the configured defaults otherwise
+func requestQuotas(c *gin.Context, cfg config.QuotaConfig) []ratelimit.Quota {
+	limits := map[ratelimit.QuotaPeriod]int64{
+		ratelimit.QuotaDaily:   cfg.Daily,
+		ratelimit.QuotaMonthly: cfg.Monthly,
+	}
+
+	// Set by authentication middleware from the API key
+	if value, exists := c.Get(ContextQuotas); exists {
+		if keyQuotas, ok := value.([]ratelimit.Quota); ok {
+			for _, quota := range keyQuotas {
+				limits[quota.Period] = quota.Limit
+			}
+		}
+	}
+
+	var quotas []ratelimit.Quota
+	for _, period := range []ratelimit.QuotaPeriod{ratelimit.QuotaDaily, ratelimit.QuotaMonthly} {
+		if limits[period] > 0 {
+			quotas = append(quotas, ratelimit.Quota{Period: period, Limit: limits[period]})
+		}
+	}
+	return quotas
+}
+
+// setQuotaHeaders reports the quota closest to running out, and a warning
+// once its usage has reached a warning threshold
+func setQuotaHeaders(c *gin.Context, usage *ratelimit.QuotaUsage) {
+	c.Header(QuotaPeriodHeader, string(usage.Period))
+	c.Header(QuotaLimitHeader, strconv.FormatInt(usage.Limit, 10))
+	c.Header(QuotaRemainingHeader, strconv.FormatInt(usage.Remaining, 10))
+	c.Header(QuotaResetHeader, strconv.FormatInt(usage.ResetsAt.Unix(), 10))
+
+	if usage.Warning > 0 && !usage.Exceeded {
+		c.Header(QuotaWarningHeader, fmt.Sprintf("%.0f%% of %s quota used", usage.Warning*100, usage.Period))
+	}
+}
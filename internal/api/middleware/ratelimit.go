@@ -1,10 +1,15 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"kalshi/internal/config"
 	"kalshi/internal/ratelimit"
 	"kalshi/pkg/logger"
 
@@ -17,6 +22,10 @@ const (
 	RateLimitHeader          = "X-RateLimit-Limit"
 	RateLimitRemainingHeader = "X-RateLimit-Remaining"
 	RateLimitResetHeader     = "X-RateLimit-Reset"
+	RateLimitScheduleHeader  = "X-RateLimit-Schedule"
+	RetryAfterHeader         = "Retry-After"
+	RateLimitPolicyHeader    = "RateLimit-Policy" // IETF draft-ietf-httpapi-ratelimit-headers
+	RateLimitIETFHeader      = "RateLimit"
 	ClientIDHeader           = "X-Client-ID"
 )
 
@@ -24,6 +33,29 @@ const (
 // It supports custom rate limits set by authentication middleware
 // and provides rate limit headers in responses.
 func RateLimit(limiter *ratelimit.Limiter, log *logger.Logger) gin.HandlerFunc {
+	return LayeredRateLimit(limiter, nil, log)
+}
+
+// LayeredRateLimit enforces the global limit together with the rate_limit
+// of the configured route the request matches and of the API key that
+// authenticated it. Every layer must admit the request, so the most
+// restrictive one wins. A request takes its route's cost in tokens, and when
+// the backend reports a higher cost in the configured cost header the
+// difference is charged after the response. An admitted request also holds
+// one of the client's concurrent request slots until its response is
+// written, when the configuration or API key bounds them. With a schedule
+// the limits of the current period apply, and the period is reported in the
+// X-RateLimit-Schedule header. cfg may be nil to skip the route layer, costs
+// and the default concurrency limit.
+func LayeredRateLimit(limiter *ratelimit.Limiter, cfg *config.Config, log *logger.Logger) gin.HandlerFunc {
+	ietf := cfg != nil && cfg.RateLimit.IETFHeaders
+	costHeader := ""
+	maxConcurrent := 0
+	if cfg != nil {
+		costHeader = cfg.RateLimit.CostHeader
+		maxConcurrent = cfg.RateLimit.MaxConcurrent
+	}
+
 	return func(c *gin.Context) {
 		// Get client identifier
 		clientID := getClientID(c)
@@ -32,22 +64,28 @@ func RateLimit(limiter *ratelimit.Limiter, log *logger.Logger) gin.HandlerFunc {
 			path = c.Request.URL.Path
 		}
 
-		// Get custom rate limit from context (set by auth middleware)
-		customRate := 0
-		if rate, exists := c.Get("rate_limit"); exists {
-			if r, ok := rate.(int); ok {
-				customRate = r
-			}
-		}
-
 		// Check rate limit
-		allowed, err := limiter.Allow(c.Request.Context(), clientID, path)
+		route := matchRoute(c, cfg)
+		layers := rateLimitLayers(c, route)
+		cost := routeCost(route)
+		decision, err := limiter.CheckN(c.Request.Context(), clientID, path, cost, layers...)
 		if err != nil {
 			log.Error("Rate limit check failed",
 				"error", err,
 				"client_id", clientID,
 				"path", path,
 			)
+
+			// A fail-closed limiter rejects requests until storage is back
+			if errors.Is(err, ratelimit.ErrLimiterUnavailable) {
+				c.Header(RetryAfterHeader, "1")
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error": "Rate limiter unavailable",
+				})
+				c.Abort()
+				return
+			}
+
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Rate limit check failed",
 			})
@@ -55,42 +93,222 @@ func RateLimit(limiter *ratelimit.Limiter, log *logger.Logger) gin.HandlerFunc {
 			return
 		}
 
-		if !allowed {
-			// Add rate limit headers
-			effectiveRate := customRate
-			if effectiveRate == 0 {
-				effectiveRate = DefaultRateLimit // Default rate limit
+		setRateLimitHeaders(c, decision)
+		if period, _ := limiter.SchedulePolicy(); period != "" {
+			c.Header(RateLimitScheduleHeader, string(period))
+		}
+		if ietf {
+			policies := make([]ratelimit.Limit, 0, len(layers)+1)
+			for _, layer := range layers {
+				policies = append(policies, limiter.Scheduled(layer))
 			}
+			setIETFRateLimitHeaders(c, decision, append(policies, limiter.GlobalLimit(path)))
+		}
 
-			c.Header(RateLimitHeader, strconv.Itoa(effectiveRate))
-			c.Header(RateLimitRemainingHeader, "0")
-			c.Header(RateLimitResetHeader, strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+		if !decision.Allowed {
+			retryAfter := retryAfterSeconds(decision.RetryAfter)
+			c.Header(RetryAfterHeader, strconv.FormatInt(retryAfter, 10))
 
 			log.Warn("Rate limit exceeded",
 				"client_id", clientID,
 				"path", path,
-				"rate_limit", effectiveRate,
+				"layer", decision.Layer,
+				"rate_limit", decision.Rate,
+				"cost", cost,
 			)
 
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error":       "Rate limit exceeded",
-				"retry_after": "60 seconds",
+				"layer":       decision.Layer,
+				"retry_after": fmt.Sprintf("%d seconds", retryAfter),
 			})
 			c.Abort()
 			return
 		}
 
-		// Add rate limit headers for successful requests
-		effectiveRate := customRate
-		if effectiveRate == 0 {
-			effectiveRate = DefaultRateLimit // Default rate limit
+		if limit := concurrencyLimit(c, limiter, maxConcurrent); limit > 0 {
+			lease, ok := acquireLease(c, limiter, clientID, path, limit, log)
+			if !ok {
+				return
+			}
+			defer lease.Release()
 		}
-		c.Header(RateLimitHeader, strconv.Itoa(effectiveRate))
 
 		c.Next()
+
+		// Charge what the backend reports beyond the cost already taken
+		if actual := reportedCost(c, costHeader); actual > cost {
+			if err := limiter.Charge(c.Request.Context(), clientID, path, actual-cost, layers...); err != nil {
+				log.Warn("Failed to charge reported request cost",
+					"error", err,
+					"client_id", clientID,
+					"path", path,
+					"cost", actual,
+				)
+			}
+		}
 	}
 }
 
+// concurrencyLimit is the requests the client may have in flight: the API
+// key's own limit where it sets one, then the current schedule period's,
+// and the configured default otherwise. Zero is unlimited.
+func concurrencyLimit(c *gin.Context, limiter *ratelimit.Limiter, defaultLimit int) int64 {
+	// Set by authentication middleware from the API key
+	if limit := c.GetInt(ContextMaxConcurrent); limit > 0 {
+		return int64(limit)
+	}
+	if _, policy := limiter.SchedulePolicy(); policy.MaxConcurrent > 0 {
+		return int64(policy.MaxConcurrent)
+	}
+	return int64(defaultLimit)
+}
+
+// acquireLease takes one of the client's concurrent request slots. When none
+// is free, or a fail-closed limiter cannot reach storage, it rejects the
+// request and returns false.
+func acquireLease(c *gin.Context, limiter *ratelimit.Limiter, clientID, path string, limit int64, log *logger.Logger) (*ratelimit.Lease, bool) {
+	lease, err := limiter.Acquire(c.Request.Context(), clientID, path, limit)
+	if err != nil {
+		log.Error("Concurrency limit check failed",
+			"error", err,
+			"client_id", clientID,
+			"path", path,
+		)
+
+		c.Header(RetryAfterHeader, "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Rate limiter unavailable",
+		})
+		c.Abort()
+		return nil, false
+	}
+
+	if !lease.Acquired {
+		log.Warn("Concurrency limit exceeded",
+			"client_id", clientID,
+			"path", path,
+			"max_concurrent", limit,
+			"in_flight", lease.InFlight,
+		)
+
+		c.Header(RetryAfterHeader, "1")
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":     "Too many concurrent requests",
+			"layer":     ratelimit.LayerConcurrency,
+			"limit":     limit,
+			"in_flight": lease.InFlight,
+		})
+		c.Abort()
+		return nil, false
+	}
+
+	return lease, true
+}
+
+// matchRoute returns the configured route the request matches, or nil
+func matchRoute(c *gin.Context, cfg *config.Config) *config.RouteConfig {
+	if cfg == nil {
+		return nil
+	}
+	return cfg.MatchRoute(c.Request.URL.Path)
+}
+
+// routeCost is the tokens a request to route takes; one without a route or
+// cost
+func routeCost(route *config.RouteConfig) int64 {
+	if route != nil && route.Cost > 0 {
+		return int64(route.Cost)
+	}
+	return 1
+}
+
+// reportedCost is the cost the backend reported in header, or zero when it
+// reported none
+func reportedCost(c *gin.Context, header string) int64 {
+	if header == "" {
+		return 0
+	}
+	cost, err := strconv.ParseInt(c.Writer.Header().Get(header), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return cost
+}
+
+// rateLimitLayers returns the route and API key layers that apply to the
+// request in addition to the global one
+func rateLimitLayers(c *gin.Context, route *config.RouteConfig) []ratelimit.Limit {
+	var layers []ratelimit.Limit
+
+	if route != nil && route.RateLimit > 0 {
+		limit := ratelimit.RouteLimit(route.Path, route.RateLimit)
+		limit.Algorithm = ratelimit.AlgorithmType(route.RateLimitAlgorithm)
+		layers = append(layers, limit)
+	}
+
+	// Set by RouteMiddleware.WithRateLimit for a whole route group
+	if rate := c.GetInt("custom_rate_limit"); rate > 0 {
+		layers = append(layers, ratelimit.RouteLimit(c.FullPath(), rate))
+	}
+
+	// Set by authentication middleware from the API key
+	if rate := c.GetInt(ContextRateLimit); rate > 0 {
+		layers = append(layers, ratelimit.KeyLimit(rate))
+	}
+
+	return layers
+}
+
+// setRateLimitHeaders reports the state of the layer closest to rejecting
+// the request: its bucket size, the requests left and the Unix time at which
+// it is full again. A fail-open limiter that could not check has no state.
+func setRateLimitHeaders(c *gin.Context, decision ratelimit.Decision) {
+	if decision.Layer == "" {
+		return
+	}
+
+	c.Header(RateLimitHeader, strconv.FormatInt(decision.Limit, 10))
+	c.Header(RateLimitRemainingHeader, strconv.FormatInt(decision.Remaining, 10))
+	c.Header(RateLimitResetHeader, strconv.FormatInt(time.Now().Add(decision.ResetAfter).Unix(), 10))
+}
+
+// setIETFRateLimitHeaders adds the structured RateLimit-Policy and RateLimit
+// headers. Every layer is listed as a policy of its rate per 60 second
+// window, and RateLimit reports the deciding layer's remaining quota and the
+// seconds until it can be used: until the retry when rejected, otherwise
+// until the layer is full again.
+func setIETFRateLimitHeaders(c *gin.Context, decision ratelimit.Decision, layers []ratelimit.Limit) {
+	if decision.Layer == "" {
+		return
+	}
+
+	policies := make([]string, 0, len(layers))
+	for _, layer := range layers {
+		if layer.Rate > 0 {
+			policies = append(policies, fmt.Sprintf("%q;q=%d;w=60", layer.Layer, layer.Rate))
+		}
+	}
+	c.Header(RateLimitPolicyHeader, strings.Join(policies, ", "))
+
+	reset := decision.ResetAfter
+	if !decision.Allowed {
+		reset = decision.RetryAfter
+	}
+	c.Header(RateLimitIETFHeader, fmt.Sprintf("%q;r=%d;t=%d", decision.Layer, decision.Remaining, ceilSeconds(reset)))
+}
+
+// retryAfterSeconds is the whole seconds a rejected client should wait. It
+// is at least one so clients never retry immediately.
+func retryAfterSeconds(d time.Duration) int64 {
+	return max(ceilSeconds(d), 1)
+}
+
+// ceilSeconds rounds d up to whole seconds
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
 // getClientID determines the client identifier for rate limiting
 func getClientID(c *gin.Context) string {
 	// Try to get user ID from context (set by auth middleware)
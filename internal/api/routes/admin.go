@@ -9,7 +9,7 @@ import (
 
 // setupAdminRoutes configures administrative endpoints
 func setupAdminRoutes(router *gin.Engine, cfg *RouterConfig) {
-	adminHandler := handlers.NewAdminHandler(cfg.Gateway, cfg.Logger)
+	adminHandler := handlers.NewAdminHandler(cfg.Gateway, cfg.Limiter, cfg.Logger)
 	proxyHandler := handlers.NewProxyHandler(cfg.Gateway, cfg.Config, cfg.Logger)
 
 	admin := router.Group("/admin")
@@ -30,6 +30,7 @@ func setupAdminRoutes(router *gin.Engine, cfg *RouterConfig) {
 		backends.POST("/:name/health", adminHandler.CheckBackendHealth)
 		backends.PUT("/:name/enable", adminHandler.EnableBackend)
 		backends.PUT("/:name/disable", adminHandler.DisableBackend)
+		backends.GET("/:name/history", adminHandler.GetBackendHistory)
 	}
 
 	// Circuit breaker management
@@ -55,8 +56,13 @@ func setupAdminRoutes(router *gin.Engine, cfg *RouterConfig) {
 	{
 		cache.GET("/stats", adminHandler.GetCacheStats)
 		cache.DELETE("/clear", adminHandler.ClearCache)
-		cache.DELETE("/:key", adminHandler.DeleteCacheKey)
-		cache.GET("/:key", adminHandler.GetCacheKey)
+		cache.POST("/purge", adminHandler.PurgeCache)
+		cache.DELETE("/tags/:tag", adminHandler.PurgeCacheTag)
+		cache.GET("/keys", adminHandler.ListCacheKeys)
+		cache.POST("/warm", adminHandler.WarmCache)
+		// Cache keys contain slashes, so they are matched by a wildcard
+		cache.GET("/keys/*key", adminHandler.GetCacheKey)
+		cache.DELETE("/keys/*key", adminHandler.DeleteCacheKey)
 	}
 
 	// Rate limiting management
@@ -65,6 +71,9 @@ func setupAdminRoutes(router *gin.Engine, cfg *RouterConfig) {
 		rateLimit.GET("/stats", adminHandler.GetRateLimitStats)
 		rateLimit.DELETE("/reset/:clientId", adminHandler.ResetRateLimit)
 		rateLimit.GET("/status/:clientId", adminHandler.GetRateLimitStatus)
+		rateLimit.GET("/top", adminHandler.GetTopThrottled)
+		rateLimit.PUT("/overrides/:clientId", adminHandler.SetRateLimitOverride)
+		rateLimit.DELETE("/overrides/:clientId", adminHandler.RemoveRateLimitOverride)
 	}
 
 	// System information
@@ -19,8 +19,8 @@ func setupAPIRoutes(router *gin.Engine, cfg *RouterConfig) {
 		// Apply authentication middleware
 		applyAuthMiddleware(api, cfg)
 
-		// Apply rate limiting
-		api.Use(middleware.RateLimit(cfg.Limiter, cfg.Logger))
+		// Apply rate limiting and usage quotas
+		applyRateLimitMiddleware(api, cfg)
 
 		// Apply content validation for write operations
 		api.Use(middleware.ValidateContentType("application/json", "application/xml", "text/plain"))
@@ -32,7 +32,7 @@ func setupAPIRoutes(router *gin.Engine, cfg *RouterConfig) {
 	v1 := router.Group("/api/v1")
 	{
 		applyAuthMiddleware(v1, cfg)
-		v1.Use(middleware.RateLimit(cfg.Limiter, cfg.Logger))
+		applyRateLimitMiddleware(v1, cfg)
 		v1.Use(middleware.ValidateContentType("application/json"))
 
 		// Version-specific proxy handling
@@ -43,7 +43,7 @@ func setupAPIRoutes(router *gin.Engine, cfg *RouterConfig) {
 	v2 := router.Group("/api/v2")
 	{
 		applyAuthMiddleware(v2, cfg)
-		v2.Use(middleware.RateLimit(cfg.Limiter, cfg.Logger))
+		applyRateLimitMiddleware(v2, cfg)
 		v2.Use(middleware.ValidateContentType("application/json"))
 
 		v2.Any("/*path", proxyHandler.HandleRequest)
@@ -53,7 +53,7 @@ func setupAPIRoutes(router *gin.Engine, cfg *RouterConfig) {
 	publicAPI := router.Group("/public")
 	{
 		// Only rate limiting, no auth
-		publicAPI.Use(middleware.RateLimit(cfg.Limiter, cfg.Logger))
+		applyRateLimitMiddleware(publicAPI, cfg)
 		publicAPI.Any("/*path", proxyHandler.HandleRequest)
 	}
 
@@ -64,7 +64,7 @@ func setupAPIRoutes(router *gin.Engine, cfg *RouterConfig) {
 		if cfg.Config.Auth.APIKey.Enabled {
 			internal.Use(middleware.APIKeyAuth(cfg.APIKeyManager, "X-Internal-Key", cfg.Logger))
 		}
-		internal.Use(middleware.RateLimit(cfg.Limiter, cfg.Logger))
+		applyRateLimitMiddleware(internal, cfg)
 		internal.Any("/*path", proxyHandler.HandleRequest)
 	}
 }
@@ -88,6 +88,16 @@ func applyAuthMiddleware(group *gin.RouterGroup, cfg *RouterConfig) {
 	}
 }
 
+// applyRateLimitMiddleware applies the layered rate limits and, when
+// configured, usage quotas. Quotas are counted after rate limiting so
+// throttled requests do not use them up.
+func applyRateLimitMiddleware(group *gin.RouterGroup, cfg *RouterConfig) {
+	group.Use(middleware.LayeredRateLimit(cfg.Limiter, cfg.Config, cfg.Logger))
+	if cfg.Quotas != nil {
+		group.Use(middleware.Quota(cfg.Quotas, cfg.Config, cfg.Logger))
+	}
+}
+
 // setupRouteSpecificRules configures rules for specific route patterns
 func setupRouteSpecificRules(router *gin.Engine, cfg *RouterConfig) {
 	proxyHandler := handlers.NewProxyHandler(cfg.Gateway, cfg.Config, cfg.Logger)
@@ -97,7 +107,7 @@ func setupRouteSpecificRules(router *gin.Engine, cfg *RouterConfig) {
 	{
 		applyAuthMiddleware(upload, cfg)
 		// Stricter rate limiting for uploads
-		upload.Use(middleware.RateLimit(cfg.Limiter, cfg.Logger))
+		applyRateLimitMiddleware(upload, cfg)
 		// Larger timeout for file uploads
 		upload.Use(middleware.Timeout(5 * time.Minute))
 		upload.POST("/*path", proxyHandler.HandleRequest)
@@ -108,7 +118,7 @@ func setupRouteSpecificRules(router *gin.Engine, cfg *RouterConfig) {
 	{
 		applyAuthMiddleware(stream, cfg)
 		// No timeout for streaming
-		stream.Use(middleware.RateLimit(cfg.Limiter, cfg.Logger))
+		applyRateLimitMiddleware(stream, cfg)
 		stream.GET("/*path", proxyHandler.HandleRequest)
 	}
 
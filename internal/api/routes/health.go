@@ -8,7 +8,7 @@ import (
 
 // setupHealthRoutes configures health check endpoints
 func setupHealthRoutes(router *gin.Engine, cfg *RouterConfig) {
-	healthHandler := handlers.NewHealthHandler(cfg.Gateway)
+	healthHandler := handlers.NewHealthHandler(cfg.Gateway, cfg.Readiness)
 
 	// Standard health endpoints
 	health := router.Group("/health")
@@ -24,6 +24,7 @@ func setupHealthRoutes(router *gin.Engine, cfg *RouterConfig) {
 		k8s.GET("/healthz", healthHandler.Health)
 		k8s.GET("/ready", healthHandler.Readiness)
 		k8s.GET("/readiness", healthHandler.Readiness)
+		k8s.GET("/ready/detailed", healthHandler.ReadinessDetails)
 		k8s.GET("/live", healthHandler.Liveness)
 		k8s.GET("/liveness", healthHandler.Liveness)
 	}
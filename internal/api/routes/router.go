@@ -9,6 +9,7 @@ import (
 	"kalshi/internal/auth"
 	"kalshi/internal/config"
 	"kalshi/internal/gateway"
+	"kalshi/internal/health"
 	"kalshi/internal/ratelimit"
 	"kalshi/pkg/logger"
 	"kalshi/pkg/metrics"
@@ -19,8 +20,10 @@ type RouterConfig struct {
 	Config        *config.Config
 	Gateway       *gateway.Gateway
 	Limiter       *ratelimit.Limiter
+	Quotas        *ratelimit.QuotaManager // Optional; requests are then not counted against quotas
 	JWTManager    *auth.JWTManager
 	APIKeyManager *auth.APIKeyManager
+	Readiness     *health.Readiness // Optional; readiness then only checks backends
 	Logger        *logger.Logger
 }
 
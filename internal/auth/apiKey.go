@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"kalshi/internal/storage"
+	"math"
 	"time"
 )
 
@@ -30,13 +31,15 @@ func (e *APIKeyError) Unwrap() error {
 
 // Common API key errors
 var (
-	ErrInvalidAPIKey    = &APIKeyError{Type: "invalid_key", Message: "invalid API key"}
-	ErrAPIKeyDisabled   = &APIKeyError{Type: "disabled", Message: "API key is disabled"}
-	ErrAPIKeyExpired    = &APIKeyError{Type: "expired", Message: "API key has expired"}
-	ErrAPIKeyNotFound   = &APIKeyError{Type: "not_found", Message: "API key not found"}
-	ErrAPIKeyExists     = &APIKeyError{Type: "exists", Message: "API key already exists"}
-	ErrInvalidUserID    = &APIKeyError{Type: "invalid_user", Message: "invalid user ID"}
-	ErrInvalidRateLimit = &APIKeyError{Type: "invalid_rate_limit", Message: "invalid rate limit"}
+	ErrInvalidAPIKey        = &APIKeyError{Type: "invalid_key", Message: "invalid API key"}
+	ErrAPIKeyDisabled       = &APIKeyError{Type: "disabled", Message: "API key is disabled"}
+	ErrAPIKeyExpired        = &APIKeyError{Type: "expired", Message: "API key has expired"}
+	ErrAPIKeyNotFound       = &APIKeyError{Type: "not_found", Message: "API key not found"}
+	ErrAPIKeyExists         = &APIKeyError{Type: "exists", Message: "API key already exists"}
+	ErrInvalidUserID        = &APIKeyError{Type: "invalid_user", Message: "invalid user ID"}
+	ErrInvalidRateLimit     = &APIKeyError{Type: "invalid_rate_limit", Message: "invalid rate limit"}
+	ErrInvalidQuota         = &APIKeyError{Type: "invalid_quota", Message: "invalid quota"}
+	ErrInvalidMaxConcurrent = &APIKeyError{Type: "invalid_max_concurrent", Message: "invalid max concurrent requests"}
 )
 
 type APIKeyManager struct {
@@ -44,13 +47,16 @@ type APIKeyManager struct {
 }
 
 type APIKeyInfo struct {
-	UserID      string    `json:"user_id"`
-	RateLimit   int       `json:"rate_limit"`
-	Enabled     bool      `json:"enabled"`
-	CreatedAt   time.Time `json:"created_at"`
-	LastUsed    time.Time `json:"last_used,omitempty"`
-	ExpiresAt   time.Time `json:"expires_at,omitempty"`
-	Description string    `json:"description,omitempty"`
+	UserID        string    `json:"user_id"`
+	RateLimit     int       `json:"rate_limit"`
+	DailyQuota    int64     `json:"daily_quota,omitempty"`    // Requests per day, zero uses the configured default
+	MonthlyQuota  int64     `json:"monthly_quota,omitempty"`  // Requests per month, zero uses the configured default
+	MaxConcurrent int       `json:"max_concurrent,omitempty"` // Requests in flight at once, zero uses the configured default
+	Enabled       bool      `json:"enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastUsed      time.Time `json:"last_used,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at,omitempty"`
+	Description   string    `json:"description,omitempty"`
 }
 
 // APIKeyList represents a list of API keys for a user
@@ -246,6 +252,24 @@ func (a *APIKeyManager) UpdateAPIKey(ctx context.Context, apiKey string, updates
 			} else {
 				return ErrInvalidRateLimit
 			}
+		case "daily_quota":
+			if quota, ok := quotaValue(value); ok {
+				info.DailyQuota = quota
+			} else {
+				return ErrInvalidQuota
+			}
+		case "monthly_quota":
+			if quota, ok := quotaValue(value); ok {
+				info.MonthlyQuota = quota
+			} else {
+				return ErrInvalidQuota
+			}
+		case "max_concurrent":
+			if maxConcurrent, ok := value.(int); ok && maxConcurrent >= 0 {
+				info.MaxConcurrent = maxConcurrent
+			} else {
+				return ErrInvalidMaxConcurrent
+			}
 		case "enabled":
 			if enabled, ok := value.(bool); ok {
 				info.Enabled = enabled
@@ -264,6 +288,27 @@ func (a *APIKeyManager) UpdateAPIKey(ctx context.Context, apiKey string, updates
 	return a.updateAPIKeyInfo(ctx, apiKey, info)
 }
 
+// quotaValue converts a quota update to a request count. Quotas may be given
+// as Go integers or as numbers decoded from JSON, and must be whole and
+// non-negative.
+func quotaValue(value interface{}) (int64, bool) {
+	var quota int64
+	switch v := value.(type) {
+	case int:
+		quota = int64(v)
+	case int64:
+		quota = v
+	case float64:
+		if v != math.Trunc(v) || v > math.MaxInt64 {
+			return 0, false
+		}
+		quota = int64(v)
+	default:
+		return 0, false
+	}
+	return quota, quota >= 0
+}
+
 // DeleteAPIKey permanently removes an API key
 func (a *APIKeyManager) DeleteAPIKey(ctx context.Context, apiKey string) error {
 	if apiKey == "" {
@@ -0,0 +1,254 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultEpochCheckInterval is how often replicas compare their applied
+// invalidation epoch with the shared one
+const DefaultEpochCheckInterval = 5 * time.Second
+
+// Invalidation describes entries removed on one replica that every other
+// replica must evict from its L1 cache. Epoch orders invalidations globally.
+type Invalidation struct {
+	Origin   string   `json:"origin"`
+	Epoch    int64    `json:"epoch"`
+	Keys     []string `json:"keys,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	All      bool     `json:"all,omitempty"`
+}
+
+// InvalidationBus broadcasts invalidations between gateway replicas
+type InvalidationBus interface {
+	// NextEpoch advances and returns the shared invalidation epoch
+	NextEpoch(ctx context.Context) (int64, error)
+	// Epoch returns the shared invalidation epoch
+	Epoch(ctx context.Context) (int64, error)
+	// Publish sends an invalidation to every replica, including the sender
+	Publish(ctx context.Context, inv Invalidation) error
+	// Subscribe delivers invalidations to handler until ctx is done
+	Subscribe(ctx context.Context, handler func(Invalidation)) error
+}
+
+// CoherenceOptions configures cross-replica L1 invalidation
+type CoherenceOptions struct {
+	ReplicaID          string        // Identifies this replica's own messages; defaults to host and pid
+	EpochCheckInterval time.Duration // How often to detect missed messages
+}
+
+// coherence keeps a manager's L1 consistent with invalidations made on other
+// replicas. Every invalidation advances a shared epoch; a replica that sees
+// the epoch jump past the next one it expected has missed a message and
+// flushes its L1, so a lost message costs cache hits rather than serving
+// stale data until the entries expire.
+type coherence struct {
+	bus      InvalidationBus
+	origin   string
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu      sync.Mutex
+	applied int64 // Highest epoch reflected in L1
+	lagging int64 // Shared epoch seen ahead of applied at the last check
+	flushes int64
+}
+
+// EnableCoherence subscribes the manager to invalidations from other
+// replicas and broadcasts its own. It must be called before the manager is
+// used and is a no-op without an L2 cache, since a lone L1 has nothing to
+// stay coherent with.
+func (m *Manager) EnableCoherence(bus InvalidationBus, opts CoherenceOptions) error {
+	if !m.useL2 || bus == nil {
+		return nil
+	}
+
+	if opts.ReplicaID == "" {
+		hostname, _ := os.Hostname()
+		opts.ReplicaID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if opts.EpochCheckInterval <= 0 {
+		opts.EpochCheckInterval = DefaultEpochCheckInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	epoch, err := bus.Epoch(ctx)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to read invalidation epoch: %w", err)
+	}
+
+	c := &coherence{
+		bus:      bus,
+		origin:   opts.ReplicaID,
+		interval: opts.EpochCheckInterval,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		applied:  epoch,
+	}
+	m.coherence = c
+
+	go func() {
+		defer close(c.done)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.checkEpochs(ctx)
+		}()
+
+		// Subscribe returns when the connection fails; missed messages are
+		// caught by the epoch check, so just resubscribe
+		for ctx.Err() == nil {
+			_ = bus.Subscribe(ctx, m.applyInvalidation)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+		wg.Wait()
+	}()
+
+	return nil
+}
+
+// CoherenceFlushes returns how many times L1 was flushed after missed
+// invalidations
+func (m *Manager) CoherenceFlushes() int64 {
+	if m.coherence == nil {
+		return 0
+	}
+	m.coherence.mu.Lock()
+	defer m.coherence.mu.Unlock()
+	return m.coherence.flushes
+}
+
+// broadcast publishes a local invalidation to the other replicas
+func (m *Manager) broadcast(ctx context.Context, inv Invalidation) error {
+	c := m.coherence
+	if c == nil {
+		return nil
+	}
+
+	epoch, err := c.bus.NextEpoch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to broadcast invalidation: %w", err)
+	}
+	inv.Origin = c.origin
+	inv.Epoch = epoch
+	m.observeEpoch(epoch)
+
+	if err := c.bus.Publish(ctx, inv); err != nil {
+		return fmt.Errorf("failed to broadcast invalidation: %w", err)
+	}
+	return nil
+}
+
+// applyInvalidation evicts the entries another replica invalidated from L1
+func (m *Manager) applyInvalidation(inv Invalidation) {
+	m.observeEpoch(inv.Epoch)
+	if inv.Origin == m.coherence.origin {
+		return
+	}
+
+	ctx := context.Background()
+	m.evictL1(ctx, inv)
+}
+
+// evictL1 removes the entries an invalidation names from L1 only
+func (m *Manager) evictL1(ctx context.Context, inv Invalidation) {
+	l1, ok := m.l1Cache.(Invalidator)
+	if inv.All {
+		if ok {
+			_ = l1.Clear(ctx)
+		}
+		return
+	}
+
+	for _, key := range inv.Keys {
+		_ = m.l1Cache.Delete(ctx, key)
+	}
+	if !ok {
+		return
+	}
+	for _, prefix := range inv.Prefixes {
+		_, _ = l1.DeletePrefix(ctx, prefix)
+	}
+	if len(inv.Tags) > 0 {
+		_, _ = l1.PurgeTags(ctx, inv.Tags...)
+	}
+}
+
+// observeEpoch records an invalidation epoch, flushing L1 when it skips
+// past the next expected one
+func (m *Manager) observeEpoch(epoch int64) {
+	c := m.coherence
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case epoch <= c.applied:
+		return
+	case epoch > c.applied+1:
+		m.flushL1Locked()
+	}
+	c.applied = epoch
+}
+
+// checkEpochs periodically compares the shared epoch with the applied one.
+// A gap is given one interval for in-flight messages to arrive before L1 is
+// flushed.
+func (m *Manager) checkEpochs(ctx context.Context) {
+	c := m.coherence
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		epoch, err := c.bus.Epoch(ctx)
+		if err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		switch {
+		case epoch <= c.applied:
+			c.lagging = 0
+		case c.lagging != 0 && c.applied < c.lagging:
+			m.flushL1Locked()
+			c.applied = epoch
+			c.lagging = 0
+		default:
+			c.lagging = epoch
+		}
+		c.mu.Unlock()
+	}
+}
+
+// flushL1Locked drops every L1 entry. The caller must hold coherence.mu.
+func (m *Manager) flushL1Locked() {
+	m.coherence.flushes++
+	if l1, ok := m.l1Cache.(Invalidator); ok {
+		_ = l1.Clear(context.Background())
+	}
+}
+
+// stopCoherence ends the subscription and epoch checks
+func (m *Manager) stopCoherence() {
+	if m.coherence == nil {
+		return
+	}
+	m.coherence.cancel()
+	<-m.coherence.done
+}
@@ -14,5 +14,19 @@ type Cache interface {
 	Close() error
 }
 
+// MetadataStore is implemented by caches that can keep the cache's own
+// bookkeeping apart from its entries, so listing, clearing or invalidating
+// entries never touches it
+type MetadataStore interface {
+	GetMetadata(ctx context.Context, key string) ([]byte, error)
+	SetMetadata(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
 // Ensure RedisCache implements the Cache interface
 var _ Cache = (*RedisCache)(nil)
+
+// Ensure the cache implementations keep metadata
+var (
+	_ MetadataStore = (*MemoryCache)(nil)
+	_ MetadataStore = (*RedisCache)(nil)
+)
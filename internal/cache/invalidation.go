@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Invalidator is implemented by caches that can enumerate and bulk delete
+// entries. Tags group keys so that related entries can be purged together.
+type Invalidator interface {
+	// Keys lists the live keys starting with prefix
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	// Tag associates key with tags for as long as the entry lives
+	Tag(ctx context.Context, key string, tags []string, ttl time.Duration) error
+	// DeletePrefix removes every key starting with prefix
+	DeletePrefix(ctx context.Context, prefix string) (int, error)
+	// PurgeTags removes every key carrying any of the tags and returns them
+	PurgeTags(ctx context.Context, tags ...string) ([]string, error)
+	// Clear removes every entry
+	Clear(ctx context.Context) error
+}
+
+// Ensure the cache implementations support invalidation
+var (
+	_ Invalidator = (*MemoryCache)(nil)
+	_ Invalidator = (*RedisCache)(nil)
+	_ Invalidator = (*Manager)(nil)
+)
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,6 +11,23 @@ type Manager struct {
 	l1Cache Cache // Memory cache (fast)
 	l2Cache Cache // Redis cache (persistent)
 	useL2   bool  // Whether to use L2 cache
+
+	coherence  *coherence  // Cross-replica L1 invalidation; nil when disabled
+	popularity *popularity // Lookup counts for cache warming; nil when disabled
+
+	l1Hits, l2Hits, misses atomic.Int64
+}
+
+// ManagerStats reports two-tier cache usage
+type ManagerStats struct {
+	Hits             int64        `json:"hits"`
+	L1Hits           int64        `json:"l1_hits"`
+	L2Hits           int64        `json:"l2_hits"`
+	Misses           int64        `json:"misses"`
+	HitRatio         float64      `json:"hit_ratio"`
+	L2Enabled        bool         `json:"l2_enabled"`
+	CoherenceFlushes int64        `json:"coherence_flushes"`
+	L1               *MemoryStats `json:"l1,omitempty"` // Present when L1 is a MemoryCache
 }
 
 // NewManager creates a new cache manager with L1 and optional L2 cache
@@ -26,6 +44,7 @@ func (m *Manager) Get(ctx context.Context, key string) ([]byte, error) {
 	// Try L1 cache first
 	value, err := m.l1Cache.Get(ctx, key)
 	if err == nil {
+		m.l1Hits.Add(1)
 		return value, nil
 	}
 
@@ -35,10 +54,12 @@ func (m *Manager) Get(ctx context.Context, key string) ([]byte, error) {
 		if err == nil {
 			// Populate L1 cache with the value from L2
 			_ = m.l1Cache.Set(ctx, key, value, 0) // Use default TTL
+			m.l2Hits.Add(1)
 			return value, nil
 		}
 	}
 
+	m.misses.Add(1)
 	return nil, ErrCacheMiss
 }
 
@@ -72,7 +93,7 @@ func (m *Manager) Delete(ctx context.Context, key string) error {
 		_ = m.l2Cache.Delete(ctx, key) // Ignore errors for L2
 	}
 
-	return nil
+	return m.broadcast(ctx, Invalidation{Keys: []string{key}})
 }
 
 // Exists checks if a key exists in either cache
@@ -98,6 +119,8 @@ func (m *Manager) Exists(ctx context.Context, key string) (bool, error) {
 func (m *Manager) Close() error {
 	var l1Err, l2Err error
 
+	m.stopCoherence()
+
 	// Close L1 cache
 	if m.l1Cache != nil {
 		l1Err = m.l1Cache.Close()
@@ -114,3 +137,126 @@ func (m *Manager) Close() error {
 	}
 	return l2Err
 }
+
+// Stats returns hit counters for both tiers and the L1 memory statistics
+func (m *Manager) Stats() ManagerStats {
+	stats := ManagerStats{
+		L1Hits:           m.l1Hits.Load(),
+		L2Hits:           m.l2Hits.Load(),
+		Misses:           m.misses.Load(),
+		L2Enabled:        m.useL2,
+		CoherenceFlushes: m.CoherenceFlushes(),
+	}
+	stats.Hits = stats.L1Hits + stats.L2Hits
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+
+	if memory, ok := m.l1Cache.(*MemoryCache); ok {
+		l1 := memory.Stats()
+		stats.L1 = &l1
+	}
+	return stats
+}
+
+// HasL2 reports whether the manager is backed by an L2 cache
+func (m *Manager) HasL2() bool {
+	return m.useL2
+}
+
+// Ping checks that the L2 cache is reachable. Caches that cannot be pinged,
+// such as the in-memory L1, are always considered reachable.
+func (m *Manager) Ping(ctx context.Context) error {
+	if !m.useL2 {
+		return nil
+	}
+
+	if pinger, ok := m.l2Cache.(interface{ Ping(context.Context) error }); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// invalidators returns the tiers that support bulk invalidation
+func (m *Manager) invalidators() []Invalidator {
+	var tiers []Invalidator
+	if inv, ok := m.l1Cache.(Invalidator); ok {
+		tiers = append(tiers, inv)
+	}
+	if m.useL2 {
+		if inv, ok := m.l2Cache.(Invalidator); ok {
+			tiers = append(tiers, inv)
+		}
+	}
+	return tiers
+}
+
+// Keys lists the keys starting with prefix, from L2 when available since it
+// holds the complete set
+func (m *Manager) Keys(ctx context.Context, prefix string) ([]string, error) {
+	tiers := m.invalidators()
+	if len(tiers) == 0 {
+		return nil, nil
+	}
+	return tiers[len(tiers)-1].Keys(ctx, prefix)
+}
+
+// Tag associates key with tags in every tier
+func (m *Manager) Tag(ctx context.Context, key string, tags []string, ttl time.Duration) error {
+	for _, tier := range m.invalidators() {
+		if err := tier.Tag(ctx, key, tags, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeletePrefix removes keys starting with prefix from every tier and
+// returns the largest count removed from a single tier
+func (m *Manager) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	deleted := 0
+	for _, tier := range m.invalidators() {
+		n, err := tier.DeletePrefix(ctx, prefix)
+		if err != nil {
+			return deleted, err
+		}
+		deleted = max(deleted, n)
+	}
+	return deleted, m.broadcast(ctx, Invalidation{Prefixes: []string{prefix}})
+}
+
+// PurgeTags removes tagged keys from every tier. Entries copied into L1
+// from L2 carry no tags, so keys purged from L2 are also deleted from L1.
+func (m *Manager) PurgeTags(ctx context.Context, tags ...string) ([]string, error) {
+	seen := make(map[string]bool)
+	purged := make([]string, 0)
+	for _, tier := range m.invalidators() {
+		keys, err := tier.PurgeTags(ctx, tags...)
+		if err != nil {
+			return purged, err
+		}
+		for _, key := range keys {
+			if !seen[key] {
+				seen[key] = true
+				purged = append(purged, key)
+			}
+		}
+	}
+
+	if m.useL2 {
+		for _, key := range purged {
+			_ = m.l1Cache.Delete(ctx, key)
+		}
+	}
+	return purged, m.broadcast(ctx, Invalidation{Keys: purged, Tags: tags})
+}
+
+// Clear removes every entry from every tier
+func (m *Manager) Clear(ctx context.Context) error {
+	for _, tier := range m.invalidators() {
+		if err := tier.Clear(ctx); err != nil {
+			return err
+		}
+	}
+	return m.broadcast(ctx, Invalidation{All: true})
+}
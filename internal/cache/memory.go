@@ -1,31 +1,123 @@
 package cache
 
 import (
+	"container/list"
 	"context"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
-// MemoryCache provides an in-memory cache implementation
+const (
+	// DefaultMemoryShards is the shard count for caches large enough to use it
+	DefaultMemoryShards = 16
+	// minShardEntries keeps small caches on fewer shards so per-shard limits
+	// do not evict long before the overall limit is reached
+	minShardEntries = 64
+	// entryOverhead approximates the bookkeeping memory of one entry (map
+	// slot, list element and item header) on top of its key and value
+	entryOverhead = 96
+)
+
+// MemoryCacheOptions configures a MemoryCache
+type MemoryCacheOptions struct {
+	MaxEntries int           // Entry limit; zero means unlimited
+	MaxBytes   int64         // Limit on the total cost of entries; zero means unlimited
+	TTL        time.Duration // Default TTL when Set is given none
+	Shards     int           // Lock shards, rounded up to a power of two; zero picks one from MaxEntries
+}
+
+// MemoryStats reports a memory cache's usage
+type MemoryStats struct {
+	Entries     int     `json:"entries"`
+	Bytes       int64   `json:"bytes"`
+	MaxEntries  int     `json:"max_entries"`
+	MaxBytes    int64   `json:"max_bytes"`
+	Shards      int     `json:"shards"`
+	Hits        int64   `json:"hits"`
+	Misses      int64   `json:"misses"`
+	HitRatio    float64 `json:"hit_ratio"`
+	Sets        int64   `json:"sets"`
+	Evictions   int64   `json:"evictions"`   // Entries removed to stay within limits
+	Expirations int64   `json:"expirations"` // Entries removed after their TTL
+	Rejections  int64   `json:"rejections"`  // Entries too large to store at all
+}
+
+// MemoryCache provides an in-memory cache implementation. Keys are spread
+// over independently locked shards, each evicting its least recently used
+// entries to stay within its share of the entry and byte limits.
 type MemoryCache struct {
-	data    map[string]cacheItem
-	mutex   sync.RWMutex
-	maxSize int
-	ttl     time.Duration
+	shards     []*memoryShard
+	mask       uint32
+	maxEntries int
+	maxBytes   int64
+	ttl        time.Duration
+
+	// Metadata is kept apart from entries, so it is never evicted or cleared
+	metaMu sync.Mutex
+	meta   map[string]*cacheItem
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 // cacheItem represents a cached item with expiration
 type cacheItem struct {
+	key        string
 	value      []byte
 	expiration time.Time
+	tags       []string
+	cost       int64
+}
+
+func (item *cacheItem) expired(now time.Time) bool {
+	return !item.expiration.IsZero() && now.After(item.expiration)
+}
+
+// memoryShard is one independently locked LRU partition of a MemoryCache
+type memoryShard struct {
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	lru        *list.List                     // Front is the most recently used
+	tags       map[string]map[string]struct{} // Tag to tagged keys
+	bytes      int64
+	maxEntries int
+	maxBytes   int64
+
+	hits, misses, sets, evictions, expirations, rejections int64
 }
 
 // NewMemoryCache creates a new memory cache with specified max size and TTL
 func NewMemoryCache(maxSize int, ttl time.Duration) *MemoryCache {
+	return NewMemoryCacheWithOptions(MemoryCacheOptions{
+		MaxEntries: maxSize,
+		TTL:        ttl,
+	})
+}
+
+// NewMemoryCacheWithOptions creates a sharded memory cache
+func NewMemoryCacheWithOptions(opts MemoryCacheOptions) *MemoryCache {
+	shardCount := shardCountFor(opts)
+
 	cache := &MemoryCache{
-		data:    make(map[string]cacheItem),
-		maxSize: maxSize,
-		ttl:     ttl,
+		shards:     make([]*memoryShard, shardCount),
+		mask:       uint32(shardCount - 1),
+		maxEntries: opts.MaxEntries,
+		maxBytes:   opts.MaxBytes,
+		ttl:        opts.TTL,
+		meta:       make(map[string]*cacheItem),
+		stop:       make(chan struct{}),
+	}
+
+	for i := range cache.shards {
+		cache.shards[i] = &memoryShard{
+			items:      make(map[string]*list.Element),
+			lru:        list.New(),
+			tags:       make(map[string]map[string]struct{}),
+			maxEntries: ceilDiv(opts.MaxEntries, shardCount),
+			maxBytes:   ceilDiv(opts.MaxBytes, int64(shardCount)),
+		}
 	}
 
 	// Start cleanup goroutine
@@ -34,40 +126,78 @@ func NewMemoryCache(maxSize int, ttl time.Duration) *MemoryCache {
 	return cache
 }
 
+// shardCountFor picks a power of two shard count
+func shardCountFor(opts MemoryCacheOptions) int {
+	shards := opts.Shards
+	if shards <= 0 {
+		shards = DefaultMemoryShards
+		for shards > 1 && opts.MaxEntries > 0 && opts.MaxEntries/shards < minShardEntries {
+			shards /= 2
+		}
+	}
+
+	count := 1
+	for count < shards {
+		count <<= 1
+	}
+	return count
+}
+
+func ceilDiv[T int | int64](n, d T) T {
+	if n <= 0 {
+		return 0
+	}
+	return (n + d - 1) / d
+}
+
+// shardHash hashes key with FNV-1a to pick its shard
+func shardHash(key string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return hash
+}
+
+// shard selects the shard for key
+func (mc *MemoryCache) shard(key string) *memoryShard {
+	return mc.shards[shardHash(key)&mc.mask]
+}
+
+// entryCost is the number of bytes an entry counts against MaxBytes
+func entryCost(key string, value []byte) int64 {
+	return int64(len(key) + len(value) + entryOverhead)
+}
+
 // Get retrieves a value from memory cache
 func (mc *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
-	mc.mutex.RLock()
-	defer mc.mutex.RUnlock()
+	s := mc.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	item, exists := mc.data[key]
+	elem, exists := s.items[key]
 	if !exists {
+		s.misses++
 		return nil, ErrCacheMiss
 	}
 
 	// Check if item has expired
-	if !item.expiration.IsZero() && time.Now().After(item.expiration) {
-		// Remove expired item
-		mc.mutex.RUnlock()
-		mc.mutex.Lock()
-		delete(mc.data, key)
-		mc.mutex.Unlock()
-		mc.mutex.RLock()
+	item := elem.Value.(*cacheItem)
+	if item.expired(time.Now()) {
+		s.removeLocked(elem)
+		s.expirations++
+		s.misses++
 		return nil, ErrCacheMiss
 	}
 
+	s.lru.MoveToFront(elem)
+	s.hits++
 	return item.value, nil
 }
 
 // Set stores a value in memory cache
 func (mc *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
-	mc.mutex.Lock()
-	defer mc.mutex.Unlock()
-
-	// If max size reached, remove oldest item
-	if len(mc.data) >= mc.maxSize {
-		mc.evictOldest()
-	}
-
 	// Calculate expiration
 	expiration := time.Time{}
 	if ttl > 0 {
@@ -76,61 +206,150 @@ func (mc *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl ti
 		expiration = time.Now().Add(mc.ttl)
 	}
 
-	mc.data[key] = cacheItem{
+	s := mc.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Replacing an entry drops its tags
+	if elem, exists := s.items[key]; exists {
+		s.removeLocked(elem)
+	}
+
+	cost := entryCost(key, value)
+	if s.maxBytes > 0 && cost > s.maxBytes {
+		s.rejections++
+		return nil
+	}
+
+	s.items[key] = s.lru.PushFront(&cacheItem{
+		key:        key,
 		value:      value,
 		expiration: expiration,
-	}
+		cost:       cost,
+	})
+	s.bytes += cost
+	s.sets++
 
+	s.evictLocked()
 	return nil
 }
 
 // Delete removes a key from memory cache
 func (mc *MemoryCache) Delete(ctx context.Context, key string) error {
-	mc.mutex.Lock()
-	defer mc.mutex.Unlock()
+	s := mc.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	delete(mc.data, key)
+	if elem, exists := s.items[key]; exists {
+		s.removeLocked(elem)
+	}
 	return nil
 }
 
 // Exists checks if a key exists in memory cache
 func (mc *MemoryCache) Exists(ctx context.Context, key string) (bool, error) {
-	mc.mutex.RLock()
-	defer mc.mutex.RUnlock()
+	s := mc.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	item, exists := mc.data[key]
+	elem, exists := s.items[key]
 	if !exists {
 		return false, nil
 	}
 
 	// Check if item has expired
-	if !item.expiration.IsZero() && time.Now().After(item.expiration) {
-		return false, nil
+	return !elem.Value.(*cacheItem).expired(time.Now()), nil
+}
+
+// GetMetadata reads metadata stored by SetMetadata
+func (mc *MemoryCache) GetMetadata(ctx context.Context, key string) ([]byte, error) {
+	mc.metaMu.Lock()
+	defer mc.metaMu.Unlock()
+
+	item, exists := mc.meta[key]
+	if !exists || item.expired(time.Now()) {
+		return nil, ErrCacheMiss
 	}
+	return item.value, nil
+}
 
-	return true, nil
+// SetMetadata stores metadata apart from the cache entries. A zero ttl
+// keeps it until it is replaced.
+func (mc *MemoryCache) SetMetadata(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	item := &cacheItem{key: key, value: value}
+	if ttl > 0 {
+		item.expiration = time.Now().Add(ttl)
+	}
+
+	mc.metaMu.Lock()
+	defer mc.metaMu.Unlock()
+	mc.meta[key] = item
+	return nil
 }
 
-// Close releases resources (no-op for memory cache)
+// Close stops the background cleanup
 func (mc *MemoryCache) Close() error {
+	mc.stopOnce.Do(func() { close(mc.stop) })
 	return nil
 }
 
-// evictOldest removes the oldest item from cache
-func (mc *MemoryCache) evictOldest() {
-	var oldestKey string
-	var oldestTime time.Time
+// Stats returns usage counters aggregated over all shards
+func (mc *MemoryCache) Stats() MemoryStats {
+	stats := MemoryStats{
+		MaxEntries: mc.maxEntries,
+		MaxBytes:   mc.maxBytes,
+		Shards:     len(mc.shards),
+	}
 
-	for key, item := range mc.data {
-		if oldestKey == "" || item.expiration.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = item.expiration
+	for _, s := range mc.shards {
+		s.mu.Lock()
+		stats.Entries += len(s.items)
+		stats.Bytes += s.bytes
+		stats.Hits += s.hits
+		stats.Misses += s.misses
+		stats.Sets += s.sets
+		stats.Evictions += s.evictions
+		stats.Expirations += s.expirations
+		stats.Rejections += s.rejections
+		s.mu.Unlock()
+	}
+
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+// evictLocked removes least recently used entries until the shard is within
+// its limits. The caller must hold the shard lock.
+func (s *memoryShard) evictLocked() {
+	for s.lru.Len() > 0 {
+		overEntries := s.maxEntries > 0 && s.lru.Len() > s.maxEntries
+		overBytes := s.maxBytes > 0 && s.bytes > s.maxBytes
+		if !overEntries && !overBytes {
+			return
 		}
+		s.removeLocked(s.lru.Back())
+		s.evictions++
 	}
+}
 
-	if oldestKey != "" {
-		delete(mc.data, oldestKey)
+// removeLocked deletes an entry and its tag associations. The caller must
+// hold the shard lock.
+func (s *memoryShard) removeLocked(elem *list.Element) {
+	item := elem.Value.(*cacheItem)
+	for _, tag := range item.tags {
+		if keys := s.tags[tag]; keys != nil {
+			delete(keys, item.key)
+			if len(keys) == 0 {
+				delete(s.tags, tag)
+			}
+		}
 	}
+
+	s.lru.Remove(elem)
+	delete(s.items, item.key)
+	s.bytes -= item.cost
 }
 
 // cleanup periodically removes expired items
@@ -138,14 +357,114 @@ func (mc *MemoryCache) cleanup() {
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		mc.mutex.Lock()
+	for {
+		select {
+		case <-mc.stop:
+			return
+		case <-ticker.C:
+		}
+
 		now := time.Now()
-		for key, item := range mc.data {
-			if !item.expiration.IsZero() && now.After(item.expiration) {
-				delete(mc.data, key)
+		for _, s := range mc.shards {
+			s.mu.Lock()
+			for _, elem := range s.items {
+				if elem.Value.(*cacheItem).expired(now) {
+					s.removeLocked(elem)
+					s.expirations++
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Keys lists the unexpired keys starting with prefix in sorted order
+func (mc *MemoryCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	now := time.Now()
+	keys := make([]string, 0)
+	for _, s := range mc.shards {
+		s.mu.Lock()
+		for key, elem := range s.items {
+			if strings.HasPrefix(key, prefix) && !elem.Value.(*cacheItem).expired(now) {
+				keys = append(keys, key)
 			}
 		}
-		mc.mutex.Unlock()
+		s.mu.Unlock()
 	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Tag associates an existing key with tags. The association is dropped when
+// the key is deleted, replaced or expires, so ttl is not needed here.
+func (mc *MemoryCache) Tag(ctx context.Context, key string, tags []string, ttl time.Duration) error {
+	s := mc.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, exists := s.items[key]
+	if !exists {
+		return nil
+	}
+
+	item := elem.Value.(*cacheItem)
+	for _, tag := range tags {
+		keys := s.tags[tag]
+		if keys == nil {
+			keys = make(map[string]struct{})
+			s.tags[tag] = keys
+		}
+		if _, tagged := keys[key]; !tagged {
+			keys[key] = struct{}{}
+			item.tags = append(item.tags, tag)
+		}
+	}
+
+	return nil
+}
+
+// DeletePrefix removes every key starting with prefix
+func (mc *MemoryCache) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	deleted := 0
+	for _, s := range mc.shards {
+		s.mu.Lock()
+		for key, elem := range s.items {
+			if strings.HasPrefix(key, prefix) {
+				s.removeLocked(elem)
+				deleted++
+			}
+		}
+		s.mu.Unlock()
+	}
+	return deleted, nil
+}
+
+// PurgeTags removes every key carrying any of the tags
+func (mc *MemoryCache) PurgeTags(ctx context.Context, tags ...string) ([]string, error) {
+	purged := make([]string, 0)
+	for _, s := range mc.shards {
+		s.mu.Lock()
+		for _, tag := range tags {
+			for key := range s.tags[tag] {
+				s.removeLocked(s.items[key])
+				purged = append(purged, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return purged, nil
+}
+
+// Clear removes every entry
+func (mc *MemoryCache) Clear(ctx context.Context) error {
+	for _, s := range mc.shards {
+		s.mu.Lock()
+		s.items = make(map[string]*list.Element)
+		s.lru.Init()
+		s.tags = make(map[string]map[string]struct{})
+		s.bytes = 0
+		s.mu.Unlock()
+	}
+	return nil
 }
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// popularityKey holds the saved popularity counts in L2's metadata, so a
+	// restarted replica can warm the keys that were popular before it went
+	// down
+	popularityKey = "popularity:snapshot"
+	// popularityTTL bounds how long a saved snapshot is trusted
+	popularityTTL = 7 * 24 * time.Hour
+)
+
+// popularity counts lookups per key within a bounded number of keys. Keys
+// are spread over independently locked shards so that concurrent lookups
+// rarely contend. When a shard reaches its share of the bound its least
+// requested half is dropped, so a new key has to earn its place before the
+// next trim.
+type popularity struct {
+	shards []*popularityShard
+	mask   uint32
+}
+
+// popularityShard is one independently locked partition of the counts
+type popularityShard struct {
+	mu      sync.Mutex
+	counts  map[string]int64
+	maxKeys int
+}
+
+func newPopularity(maxKeys int) *popularity {
+	shardCount := shardCountFor(MemoryCacheOptions{MaxEntries: maxKeys})
+	p := &popularity{
+		shards: make([]*popularityShard, shardCount),
+		mask:   uint32(shardCount - 1),
+	}
+	for i := range p.shards {
+		p.shards[i] = &popularityShard{
+			counts:  make(map[string]int64),
+			maxKeys: ceilDiv(maxKeys, shardCount),
+		}
+	}
+	return p
+}
+
+func (p *popularity) record(key string, n int64) {
+	s := p.shards[shardHash(key)&p.mask]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, tracked := s.counts[key]; !tracked && len(s.counts) >= s.maxKeys {
+		s.trimLocked()
+	}
+	s.counts[key] += n
+}
+
+// trimLocked keeps the most requested half of the shard's keys. The caller
+// must hold s.mu.
+func (s *popularityShard) trimLocked() {
+	keep := make(map[string]int64, s.maxKeys)
+	for _, key := range topKeys(s.counts, max(s.maxKeys/2, 1)) {
+		keep[key] = s.counts[key]
+	}
+	s.counts = keep
+}
+
+func (p *popularity) top(n int) []string {
+	return topKeys(p.snapshot(), n)
+}
+
+// topKeys returns up to n keys of counts, most requested first
+func topKeys(counts map[string]int64, n int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	if n > 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+func (p *popularity) snapshot() map[string]int64 {
+	counts := make(map[string]int64)
+	for _, s := range p.shards {
+		s.mu.Lock()
+		for key, count := range s.counts {
+			counts[key] = count
+		}
+		s.mu.Unlock()
+	}
+	return counts
+}
+
+// TrackPopularity starts counting lookups of up to maxKeys keys so the most
+// requested ones can be warmed. It must be called before the manager is used.
+func (m *Manager) TrackPopularity(maxKeys int) {
+	if maxKeys <= 0 {
+		return
+	}
+	m.popularity = newPopularity(maxKeys)
+}
+
+// RecordLookup counts a lookup of key towards its popularity. Only client
+// lookups should be recorded, so admin reads and cache warming do not make
+// keys look popular.
+func (m *Manager) RecordLookup(key string) {
+	if m.popularity != nil {
+		m.popularity.record(key, 1)
+	}
+}
+
+// PopularKeys returns up to n of the most looked up keys, most popular first
+func (m *Manager) PopularKeys(n int) []string {
+	if m.popularity == nil {
+		return nil
+	}
+	return m.popularity.top(n)
+}
+
+// metadata returns the L2 tier's metadata store, if it has one
+func (m *Manager) metadata() (MetadataStore, bool) {
+	if !m.useL2 {
+		return nil, false
+	}
+	store, ok := m.l2Cache.(MetadataStore)
+	return store, ok
+}
+
+// SavePopularity stores the lookup counts in L2 for LoadPopularity to pick
+// up after a restart. They are kept apart from cache entries, so clearing
+// the cache does not lose them.
+func (m *Manager) SavePopularity(ctx context.Context) error {
+	store, ok := m.metadata()
+	if m.popularity == nil || !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(m.popularity.snapshot())
+	if err != nil {
+		return fmt.Errorf("failed to encode popularity: %w", err)
+	}
+	return store.SetMetadata(ctx, popularityKey, data, popularityTTL)
+}
+
+// LoadPopularity merges the counts saved by SavePopularity into the tracker
+func (m *Manager) LoadPopularity(ctx context.Context) error {
+	store, ok := m.metadata()
+	if m.popularity == nil || !ok {
+		return nil
+	}
+
+	data, err := store.GetMetadata(ctx, popularityKey)
+	if err == ErrCacheMiss {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var counts map[string]int64
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return fmt.Errorf("failed to decode popularity: %w", err)
+	}
+	for key, count := range counts {
+		m.popularity.record(key, count)
+	}
+	return nil
+}
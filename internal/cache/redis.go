@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -10,6 +11,18 @@ import (
 
 var ErrCacheMiss = errors.New("cache miss")
 
+// Redis key namespaces. Cache entries are kept apart from the rate limiting
+// and storage keys sharing the database so they can be enumerated and
+// cleared safely, and from the cache's own metadata, which survives clears.
+const (
+	redisKeyPrefix  = "cache:"
+	redisTagPrefix  = "cache-tag:"
+	redisMetaPrefix = "cache-meta:"
+)
+
+// redisScanCount is the batch size hint for SCAN during bulk deletes
+const redisScanCount = 500
+
 type RedisCache struct {
 	client *redis.Client
 	ttl    time.Duration
@@ -37,7 +50,7 @@ func NewRedisCache(addr, password string, db int, ttl time.Duration) (*RedisCach
 }
 
 func (rc *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
-	result, err := rc.client.Get(ctx, key).Bytes()
+	result, err := rc.client.Get(ctx, redisKeyPrefix+key).Bytes()
 	if err == redis.Nil {
 		return nil, ErrCacheMiss
 	}
@@ -48,18 +61,154 @@ func (rc *RedisCache) Set(ctx context.Context, key string, value []byte, ttl tim
 	if ttl == 0 {
 		ttl = rc.ttl
 	}
-	return rc.client.Set(ctx, key, value, ttl).Err()
+	return rc.client.Set(ctx, redisKeyPrefix+key, value, ttl).Err()
 }
 
 func (rc *RedisCache) Delete(ctx context.Context, key string) error {
-	return rc.client.Del(ctx, key).Err()
+	return rc.client.Del(ctx, redisKeyPrefix+key).Err()
 }
 
 func (rc *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
-	result, err := rc.client.Exists(ctx, key).Result()
+	result, err := rc.client.Exists(ctx, redisKeyPrefix+key).Result()
 	return result > 0, err
 }
 
+// GetMetadata reads metadata stored by SetMetadata
+func (rc *RedisCache) GetMetadata(ctx context.Context, key string) ([]byte, error) {
+	result, err := rc.client.Get(ctx, redisMetaPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrCacheMiss
+	}
+	return result, err
+}
+
+// SetMetadata stores metadata outside the cache keyspace
+func (rc *RedisCache) SetMetadata(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return rc.client.Set(ctx, redisMetaPrefix+key, value, ttl).Err()
+}
+
+// Ping checks that Redis is reachable
+func (rc *RedisCache) Ping(ctx context.Context) error {
+	return rc.client.Ping(ctx).Err()
+}
+
 func (rc *RedisCache) Close() error {
 	return rc.client.Close()
 }
+
+// Keys lists the cache keys starting with prefix
+func (rc *RedisCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	err := rc.scan(ctx, redisKeyPrefix+escapePattern(prefix)+"*", func(batch []string) error {
+		for _, key := range batch {
+			keys = append(keys, strings.TrimPrefix(key, redisKeyPrefix))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// Tag adds key to a set per tag. The set lives at least as long as the
+// longest-lived entry added to it; members that expired earlier are simply
+// skipped when the tag is purged.
+func (rc *RedisCache) Tag(ctx context.Context, key string, tags []string, ttl time.Duration) error {
+	if ttl == 0 {
+		ttl = rc.ttl
+	}
+
+	pipe := rc.client.TxPipeline()
+	for _, tag := range tags {
+		tagKey := redisTagPrefix + tag
+		pipe.SAdd(ctx, tagKey, key)
+		if ttl > 0 {
+			pipe.ExpireNX(ctx, tagKey, ttl)
+			pipe.ExpireGT(ctx, tagKey, ttl)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DeletePrefix removes every cache key starting with prefix
+func (rc *RedisCache) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	return rc.deletePattern(ctx, redisKeyPrefix+escapePattern(prefix)+"*")
+}
+
+// PurgeTags removes every key carrying any of the tags along with the tag
+// sets. Members that already expired are reported as purged too.
+func (rc *RedisCache) PurgeTags(ctx context.Context, tags ...string) ([]string, error) {
+	purged := make([]string, 0)
+	for _, tag := range tags {
+		tagKey := redisTagPrefix + tag
+		members, err := rc.client.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return purged, err
+		}
+
+		keys := make([]string, 0, len(members)+1)
+		for _, member := range members {
+			keys = append(keys, redisKeyPrefix+member)
+		}
+		keys = append(keys, tagKey)
+
+		if err := rc.client.Unlink(ctx, keys...).Err(); err != nil {
+			return purged, err
+		}
+		purged = append(purged, members...)
+	}
+	return purged, nil
+}
+
+// Clear removes every cache entry and tag set, leaving other keys in the
+// database untouched
+func (rc *RedisCache) Clear(ctx context.Context) error {
+	if _, err := rc.deletePattern(ctx, redisKeyPrefix+"*"); err != nil {
+		return err
+	}
+	_, err := rc.deletePattern(ctx, redisTagPrefix+"*")
+	return err
+}
+
+// deletePattern unlinks every key matching a SCAN pattern
+func (rc *RedisCache) deletePattern(ctx context.Context, pattern string) (int, error) {
+	deleted := 0
+	err := rc.scan(ctx, pattern, func(batch []string) error {
+		n, err := rc.client.Unlink(ctx, batch...).Result()
+		deleted += int(n)
+		return err
+	})
+	return deleted, err
+}
+
+// scan walks the keys matching pattern in batches
+func (rc *RedisCache) scan(ctx context.Context, pattern string, fn func([]string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := rc.client.Scan(ctx, cursor, pattern, redisScanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// escapePattern escapes glob metacharacters so prefix matches literally
+func escapePattern(prefix string) string {
+	var b strings.Builder
+	for _, r := range prefix {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Default Redis names used for cross-replica invalidation
+const (
+	DefaultInvalidationChannel = "cache-invalidations"
+	redisEpochKey              = "cache-epoch"
+)
+
+// RedisBus broadcasts invalidations over Redis pub/sub and keeps the shared
+// epoch in a counter
+type RedisBus struct {
+	client  *redis.Client
+	channel string
+}
+
+// Ensure RedisBus implements the InvalidationBus interface
+var _ InvalidationBus = (*RedisBus)(nil)
+
+// NewRedisBus creates an invalidation bus on the Redis cache's connection
+func NewRedisBus(rc *RedisCache, channel string) *RedisBus {
+	if channel == "" {
+		channel = DefaultInvalidationChannel
+	}
+	return &RedisBus{
+		client:  rc.client,
+		channel: channel,
+	}
+}
+
+// NextEpoch advances the shared epoch
+func (b *RedisBus) NextEpoch(ctx context.Context) (int64, error) {
+	return b.client.Incr(ctx, b.epochKey()).Result()
+}
+
+// Epoch returns the shared epoch, zero before the first invalidation
+func (b *RedisBus) Epoch(ctx context.Context) (int64, error) {
+	epoch, err := b.client.Get(ctx, b.epochKey()).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return epoch, err
+}
+
+// Publish sends an invalidation on the channel
+func (b *RedisBus) Publish(ctx context.Context, inv Invalidation) error {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+// Subscribe delivers invalidations until ctx is done. Undecodable messages
+// are skipped.
+func (b *RedisBus) Subscribe(ctx context.Context, handler func(Invalidation)) error {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	defer pubsub.Close()
+
+	// Wait for the subscription to be confirmed so connection errors surface
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			var inv Invalidation
+			if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+				continue
+			}
+			handler(inv)
+		}
+	}
+}
+
+// epochKey keeps one epoch per channel so separate deployments sharing a
+// Redis database do not interfere
+func (b *RedisBus) epochKey() string {
+	return redisEpochKey + ":" + b.channel
+}
@@ -0,0 +1,184 @@
+package testing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryBus is an in-process InvalidationBus that can drop messages
+type memoryBus struct {
+	mu       sync.Mutex
+	epoch    int64
+	handlers []func(cache.Invalidation)
+	drop     atomic.Bool
+}
+
+func (b *memoryBus) NextEpoch(ctx context.Context) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.epoch++
+	return b.epoch, nil
+}
+
+func (b *memoryBus) Epoch(ctx context.Context) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.epoch, nil
+}
+
+func (b *memoryBus) Publish(ctx context.Context, inv cache.Invalidation) error {
+	if b.drop.Load() {
+		return nil
+	}
+	b.mu.Lock()
+	handlers := append([]func(cache.Invalidation){}, b.handlers...)
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(inv)
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(ctx context.Context, handler func(cache.Invalidation)) error {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mu.Unlock()
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// replica is a gateway process: its own L1 over the shared L2
+type replica struct {
+	l1      *cache.MemoryCache
+	manager *cache.Manager
+}
+
+func newReplicas(t *testing.T, bus *memoryBus, n int, interval time.Duration) []replica {
+	t.Helper()
+
+	l2 := cache.NewMemoryCache(100, time.Minute)
+	replicas := make([]replica, n)
+	for i := range replicas {
+		l1 := cache.NewMemoryCache(100, time.Minute)
+		manager := cache.NewManager(l1, l2, true)
+		require.NoError(t, manager.EnableCoherence(bus, cache.CoherenceOptions{
+			ReplicaID:          string(rune('a' + i)),
+			EpochCheckInterval: interval,
+		}))
+		t.Cleanup(func() { manager.Close() })
+		replicas[i] = replica{l1: l1, manager: manager}
+	}
+
+	// Wait for every replica to subscribe
+	require.Eventually(t, func() bool {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		return len(bus.handlers) == n
+	}, time.Second, time.Millisecond)
+
+	return replicas
+}
+
+// warm loads key into every replica's L1
+func warm(t *testing.T, replicas []replica, key string) {
+	t.Helper()
+	require.NoError(t, replicas[0].manager.Set(context.Background(), key, []byte("v"), 0))
+	for _, r := range replicas {
+		_, err := r.manager.Get(context.Background(), key)
+		require.NoError(t, err)
+	}
+}
+
+func inL1(r replica, key string) bool {
+	_, err := r.l1.Get(context.Background(), key)
+	return err == nil
+}
+
+func TestManager_CoherenceEvictsOtherReplicas(t *testing.T) {
+	ctx := context.Background()
+	bus := &memoryBus{}
+	replicas := newReplicas(t, bus, 3, time.Hour)
+
+	warm(t, replicas, "GET:/markets")
+	warm(t, replicas, "GET:/markets/1")
+	warm(t, replicas, "GET:/events")
+
+	require.NoError(t, replicas[0].manager.Delete(ctx, "GET:/events"))
+	_, err := replicas[1].manager.DeletePrefix(ctx, "GET:/markets/")
+	require.NoError(t, err)
+
+	for _, r := range replicas {
+		assert.False(t, inL1(r, "GET:/events"))
+		assert.False(t, inL1(r, "GET:/markets/1"))
+		assert.True(t, inL1(r, "GET:/markets"))
+		assert.Zero(t, r.manager.CoherenceFlushes())
+	}
+
+	require.NoError(t, replicas[2].manager.Clear(ctx))
+	for _, r := range replicas {
+		assert.False(t, inL1(r, "GET:/markets"))
+	}
+}
+
+func TestManager_CoherencePurgeTags(t *testing.T) {
+	ctx := context.Background()
+	bus := &memoryBus{}
+	replicas := newReplicas(t, bus, 2, time.Hour)
+
+	warm(t, replicas, "GET:/markets/1")
+	require.NoError(t, replicas[0].manager.Tag(ctx, "GET:/markets/1", []string{"markets"}, 0))
+
+	_, err := replicas[0].manager.PurgeTags(ctx, "markets")
+	require.NoError(t, err)
+
+	// The other replica's L1 copy was promoted from L2 without tags
+	assert.False(t, inL1(replicas[1], "GET:/markets/1"))
+}
+
+func TestManager_CoherenceMissedMessageFlushesL1(t *testing.T) {
+	ctx := context.Background()
+	bus := &memoryBus{}
+	replicas := newReplicas(t, bus, 2, 10*time.Millisecond)
+
+	warm(t, replicas, "GET:/markets")
+	warm(t, replicas, "GET:/events")
+
+	bus.drop.Store(true)
+	require.NoError(t, replicas[0].manager.Delete(ctx, "GET:/markets"))
+	assert.True(t, inL1(replicas[1], "GET:/markets"), "the message was lost")
+
+	// The epoch check notices the gap and flushes the stale L1
+	assert.Eventually(t, func() bool {
+		return !inL1(replicas[1], "GET:/markets")
+	}, time.Second, 5*time.Millisecond)
+	assert.False(t, inL1(replicas[1], "GET:/events"))
+	assert.Equal(t, int64(1), replicas[1].manager.CoherenceFlushes())
+	assert.Zero(t, replicas[0].manager.CoherenceFlushes())
+}
+
+func TestManager_CoherenceEpochGapFlushesL1(t *testing.T) {
+	ctx := context.Background()
+	bus := &memoryBus{}
+	replicas := newReplicas(t, bus, 2, time.Hour)
+
+	warm(t, replicas, "GET:/events")
+
+	// One invalidation is lost, the next arrives with a skipped epoch
+	bus.drop.Store(true)
+	require.NoError(t, replicas[0].manager.Delete(ctx, "GET:/markets"))
+	bus.drop.Store(false)
+	require.NoError(t, replicas[0].manager.Delete(ctx, "GET:/other"))
+
+	assert.False(t, inL1(replicas[1], "GET:/events"))
+	assert.Equal(t, int64(1), replicas[1].manager.CoherenceFlushes())
+}
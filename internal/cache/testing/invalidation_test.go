@@ -0,0 +1,114 @@
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kalshi/internal/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache_DeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	mc := cache.NewMemoryCache(100, time.Minute)
+
+	for _, key := range []string{"GET:/markets", "GET:/markets/1", "GET:/events"} {
+		require.NoError(t, mc.Set(ctx, key, []byte("v"), 0))
+	}
+
+	keys, err := mc.Keys(ctx, "GET:/markets")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET:/markets", "GET:/markets/1"}, keys)
+
+	deleted, err := mc.DeletePrefix(ctx, "GET:/markets")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	keys, err = mc.Keys(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET:/events"}, keys)
+}
+
+func TestMemoryCache_PurgeTags(t *testing.T) {
+	ctx := context.Background()
+	mc := cache.NewMemoryCache(100, time.Minute)
+
+	require.NoError(t, mc.Set(ctx, "a", []byte("v"), 0))
+	require.NoError(t, mc.Set(ctx, "b", []byte("v"), 0))
+	require.NoError(t, mc.Set(ctx, "c", []byte("v"), 0))
+	require.NoError(t, mc.Tag(ctx, "a", []string{"market-1", "markets"}, 0))
+	require.NoError(t, mc.Tag(ctx, "b", []string{"markets"}, 0))
+
+	purged, err := mc.PurgeTags(ctx, "market-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, purged)
+
+	purged, err = mc.PurgeTags(ctx, "markets")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, purged, "purged keys leave their other tags")
+
+	exists, err := mc.Exists(ctx, "c")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestMemoryCache_ReplacingEntryDropsTags(t *testing.T) {
+	ctx := context.Background()
+	mc := cache.NewMemoryCache(100, time.Minute)
+
+	require.NoError(t, mc.Set(ctx, "a", []byte("v1"), 0))
+	require.NoError(t, mc.Tag(ctx, "a", []string{"old"}, 0))
+	require.NoError(t, mc.Set(ctx, "a", []byte("v2"), 0))
+
+	purged, err := mc.PurgeTags(ctx, "old")
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+
+	// Tagging a missing key is a no-op
+	require.NoError(t, mc.Tag(ctx, "missing", []string{"old"}, 0))
+	purged, err = mc.PurgeTags(ctx, "old")
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+}
+
+func TestManager_PurgeTagsClearsPromotedEntries(t *testing.T) {
+	ctx := context.Background()
+	l1 := cache.NewMemoryCache(100, time.Minute)
+	l2 := cache.NewMemoryCache(100, time.Minute)
+	manager := cache.NewManager(l1, l2, true)
+
+	require.NoError(t, l2.Set(ctx, "a", []byte("v"), 0))
+	require.NoError(t, l2.Tag(ctx, "a", []string{"markets"}, 0))
+
+	// Reading through the manager copies the entry into L1 without its tags
+	_, err := manager.Get(ctx, "a")
+	require.NoError(t, err)
+
+	purged, err := manager.PurgeTags(ctx, "markets")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, purged)
+
+	_, err = l1.Get(ctx, "a")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+	_, err = manager.Get(ctx, "a")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestManager_Clear(t *testing.T) {
+	ctx := context.Background()
+	l1 := cache.NewMemoryCache(100, time.Minute)
+	l2 := cache.NewMemoryCache(100, time.Minute)
+	manager := cache.NewManager(l1, l2, true)
+
+	require.NoError(t, manager.Set(ctx, "a", []byte("v"), 0))
+	require.NoError(t, manager.Clear(ctx))
+
+	keys, err := manager.Keys(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	_, err = l1.Get(ctx, "a")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
@@ -0,0 +1,154 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"kalshi/internal/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	mc := cache.NewMemoryCacheWithOptions(cache.MemoryCacheOptions{MaxEntries: 3, Shards: 1})
+	defer mc.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, mc.Set(ctx, key, []byte(key), 0))
+	}
+
+	// Reading a makes b the least recently used
+	_, err := mc.Get(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, mc.Set(ctx, "d", []byte("d"), 0))
+
+	keys, err := mc.Keys(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c", "d"}, keys)
+	assert.Equal(t, int64(1), mc.Stats().Evictions)
+}
+
+func TestMemoryCache_ByteLimit(t *testing.T) {
+	ctx := context.Background()
+	value := make([]byte, 100)
+	cost := int64(1 + len(value) + 96) // key, value and per-entry overhead
+
+	mc := cache.NewMemoryCacheWithOptions(cache.MemoryCacheOptions{MaxBytes: 3 * cost, Shards: 1})
+	defer mc.Close()
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, mc.Set(ctx, key, value, 0))
+	}
+
+	stats := mc.Stats()
+	assert.Equal(t, 3, stats.Entries)
+	assert.Equal(t, 3*cost, stats.Bytes)
+	assert.Equal(t, int64(1), stats.Evictions)
+
+	// Replacing an entry updates its cost rather than adding to it
+	require.NoError(t, mc.Set(ctx, "d", []byte("small"), 0))
+	assert.Equal(t, 2*cost+int64(1+5+96), mc.Stats().Bytes)
+
+	// Entries larger than the budget are not cached at all
+	require.NoError(t, mc.Set(ctx, "huge", make([]byte, 4*cost), 0))
+	_, err := mc.Get(ctx, "huge")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+	assert.Equal(t, int64(1), mc.Stats().Rejections)
+	assert.Equal(t, 3, mc.Stats().Entries)
+}
+
+func TestMemoryCache_Stats(t *testing.T) {
+	ctx := context.Background()
+	mc := cache.NewMemoryCacheWithOptions(cache.MemoryCacheOptions{MaxEntries: 1000})
+	defer mc.Close()
+
+	require.NoError(t, mc.Set(ctx, "a", []byte("v"), 0))
+	require.NoError(t, mc.Set(ctx, "short", []byte("v"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	_, _ = mc.Get(ctx, "a")
+	_, _ = mc.Get(ctx, "a")
+	_, _ = mc.Get(ctx, "missing")
+	_, _ = mc.Get(ctx, "short")
+
+	stats := mc.Stats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+	assert.Equal(t, 0.5, stats.HitRatio)
+	assert.Equal(t, int64(2), stats.Sets)
+	assert.Equal(t, int64(1), stats.Expirations)
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, 8, stats.Shards, "small caches use fewer shards")
+}
+
+func TestMemoryCache_ShardCount(t *testing.T) {
+	tests := []struct {
+		opts     cache.MemoryCacheOptions
+		expected int
+	}{
+		{opts: cache.MemoryCacheOptions{MaxEntries: 100000}, expected: 16},
+		{opts: cache.MemoryCacheOptions{MaxEntries: 100}, expected: 1},
+		{opts: cache.MemoryCacheOptions{}, expected: 16},
+		{opts: cache.MemoryCacheOptions{MaxEntries: 100, Shards: 5}, expected: 8},
+	}
+
+	for _, tt := range tests {
+		mc := cache.NewMemoryCacheWithOptions(tt.opts)
+		assert.Equal(t, tt.expected, mc.Stats().Shards, "%+v", tt.opts)
+		mc.Close()
+	}
+}
+
+func TestMemoryCache_ConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	mc := cache.NewMemoryCacheWithOptions(cache.MemoryCacheOptions{MaxEntries: 500, MaxBytes: 64 << 10})
+	defer mc.Close()
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := strconv.Itoa((worker*31 + i) % 1000)
+				_ = mc.Set(ctx, key, []byte(key), 0)
+				_, _ = mc.Get(ctx, key)
+				if i%100 == 0 {
+					_ = mc.Delete(ctx, key)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	stats := mc.Stats()
+	assert.LessOrEqual(t, stats.Entries, 500+stats.Shards)
+	assert.LessOrEqual(t, stats.Bytes, int64(64<<10)+int64(stats.Shards))
+}
+
+func BenchmarkMemoryCache_Get(b *testing.B) {
+	ctx := context.Background()
+	mc := cache.NewMemoryCacheWithOptions(cache.MemoryCacheOptions{MaxEntries: 100000})
+	defer mc.Close()
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("GET:/markets/%d", i)
+		_ = mc.Set(ctx, keys[i], []byte("value"), time.Hour)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			_, _ = mc.Get(ctx, keys[i%len(keys)])
+			i++
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"kalshi/internal/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookup(m *cache.Manager, key string, times int) {
+	for i := 0; i < times; i++ {
+		m.RecordLookup(key)
+	}
+}
+
+func TestManager_PopularKeys(t *testing.T) {
+	m := cache.NewManager(cache.NewMemoryCache(100, time.Minute), nil, false)
+	defer m.Close()
+
+	assert.Empty(t, m.PopularKeys(10), "tracking is off until enabled")
+
+	m.TrackPopularity(100)
+	lookup(m, "a", 1)
+	lookup(m, "b", 3)
+	lookup(m, "c", 2)
+
+	assert.Equal(t, []string{"b", "c", "a"}, m.PopularKeys(10))
+	assert.Equal(t, []string{"b", "c"}, m.PopularKeys(2))
+}
+
+func TestManager_PopularKeys_Bounded(t *testing.T) {
+	m := cache.NewManager(cache.NewMemoryCache(100, time.Minute), nil, false)
+	defer m.Close()
+	m.TrackPopularity(10)
+
+	lookup(m, "hot", 50)
+	for i := 0; i < 100; i++ {
+		lookup(m, fmt.Sprintf("cold-%d", i), 1)
+	}
+
+	keys := m.PopularKeys(0)
+	assert.LessOrEqual(t, len(keys), 10)
+	assert.Equal(t, "hot", keys[0])
+}
+
+func TestManager_PopularKeys_Sharded(t *testing.T) {
+	m := cache.NewManager(cache.NewMemoryCache(100, time.Minute), nil, false)
+	defer m.Close()
+	m.TrackPopularity(10000)
+
+	// Lookups from many goroutines are counted without losing any
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				lookup(m, fmt.Sprintf("key-%d", i), 1)
+				lookup(m, "hot", 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	keys := m.PopularKeys(0)
+	assert.Len(t, keys, 101)
+	assert.Equal(t, "hot", keys[0])
+
+	m.TrackPopularity(1000)
+	for i := 0; i < 5000; i++ {
+		lookup(m, fmt.Sprintf("cold-%d", i), 1)
+	}
+	assert.LessOrEqual(t, len(m.PopularKeys(0)), 1000)
+}
+
+func TestManager_PopularKeys_IgnoresReads(t *testing.T) {
+	ctx := context.Background()
+	m := cache.NewManager(cache.NewMemoryCache(100, time.Minute), nil, false)
+	defer m.Close()
+	m.TrackPopularity(100)
+
+	// Admin reads and warming go through Get and must not count
+	require.NoError(t, m.Set(ctx, "a", []byte("value"), time.Minute))
+	_, _ = m.Get(ctx, "a")
+	_, _ = m.Get(ctx, "b")
+	assert.Empty(t, m.PopularKeys(10))
+}
+
+func TestManager_PopularitySurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	l2 := cache.NewMemoryCache(100, time.Minute)
+
+	before := cache.NewManager(cache.NewMemoryCache(100, time.Minute), l2, true)
+	before.TrackPopularity(100)
+	lookup(before, "a", 1)
+	lookup(before, "b", 2)
+	require.NoError(t, before.SavePopularity(ctx))
+
+	after := cache.NewManager(cache.NewMemoryCache(100, time.Minute), l2, true)
+	after.TrackPopularity(100)
+	require.NoError(t, after.LoadPopularity(ctx))
+
+	assert.Equal(t, []string{"b", "a"}, after.PopularKeys(10))
+}
+
+func TestManager_PopularitySurvivesClear(t *testing.T) {
+	ctx := context.Background()
+	l2 := cache.NewMemoryCache(100, time.Minute)
+
+	before := cache.NewManager(cache.NewMemoryCache(100, time.Minute), l2, true)
+	before.TrackPopularity(100)
+	lookup(before, "a", 1)
+	require.NoError(t, before.SavePopularity(ctx))
+
+	// The snapshot is neither listed as a cache key nor cleared with them
+	keys, err := before.Keys(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	require.NoError(t, before.Clear(ctx))
+
+	after := cache.NewManager(cache.NewMemoryCache(100, time.Minute), l2, true)
+	after.TrackPopularity(100)
+	require.NoError(t, after.LoadPopularity(ctx))
+	assert.Equal(t, []string{"a"}, after.PopularKeys(10))
+}
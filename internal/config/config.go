@@ -35,6 +35,10 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"read_timeout" json:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout" json:"write_timeout"`
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout" json:"idle_timeout"`
+
+	// ShutdownDrainDelay is how long readiness fails before the server stops
+	// accepting connections, so load balancers notice and stop routing here
+	ShutdownDrainDelay time.Duration `mapstructure:"shutdown_drain_delay" json:"shutdown_drain_delay"`
 }
 
 // AuthConfig defines authentication configuration
@@ -58,16 +62,118 @@ type APIKeyConfig struct {
 
 // RateLimitConfig defines rate limiting configuration
 type RateLimitConfig struct {
-	DefaultRate     int           `mapstructure:"default_rate" json:"default_rate"`
-	BurstCapacity   int           `mapstructure:"burst_capacity" json:"burst_capacity"`
-	Storage         string        `mapstructure:"storage" json:"storage"`
-	CleanupInterval time.Duration `mapstructure:"cleanup_interval" json:"cleanup_interval"`
+	DefaultRate     int            `mapstructure:"default_rate" json:"default_rate"`
+	BurstCapacity   int            `mapstructure:"burst_capacity" json:"burst_capacity"`
+	Storage         string         `mapstructure:"storage" json:"storage"`
+	CleanupInterval time.Duration  `mapstructure:"cleanup_interval" json:"cleanup_interval"`
+	FailureMode     string         `mapstructure:"failure_mode" json:"failure_mode"`       // open (default) admits requests while storage is down, closed rejects them
+	StorageTimeout  time.Duration  `mapstructure:"storage_timeout" json:"storage_timeout"` // Bounds each rate limit check against storage
+	Algorithm       string         `mapstructure:"algorithm" json:"algorithm"`             // Default algorithm; see validRateLimitAlgorithm
+	IETFHeaders     bool           `mapstructure:"ietf_headers" json:"ietf_headers"`       // Also send the RateLimit-Policy and RateLimit headers
+	CostHeader      string         `mapstructure:"cost_header" json:"cost_header"`         // Response header in which backends report a request's actual cost; empty ignores it
+	MaxConcurrent   int            `mapstructure:"max_concurrent" json:"max_concurrent"`   // Default requests in flight per client; zero is unlimited
+	LeaseTTL        time.Duration  `mapstructure:"lease_ttl" json:"lease_ttl"`             // How long an in-flight request's lease outlives a replica that stops renewing it
+	Quota           QuotaConfig    `mapstructure:"quota" json:"quota"`                     // Daily and monthly usage quotas
+	Schedule        ScheduleConfig `mapstructure:"schedule" json:"schedule"`               // Limits by trading hours, off-hours and weekends
+}
+
+// ScheduleConfig varies rate limits and quotas over the week. Business days
+// are split into trading hours and off-hours; weekends have their own
+// policy.
+type ScheduleConfig struct {
+	Enabled  bool                 `mapstructure:"enabled" json:"enabled"`
+	Timezone string               `mapstructure:"timezone" json:"timezone"`   // IANA zone of the trading hours
+	Open     string               `mapstructure:"open" json:"open"`           // Trading hours start, as HH:MM
+	Close    string               `mapstructure:"close" json:"close"`         // Trading hours end, as HH:MM
+	Trading  SchedulePolicyConfig `mapstructure:"trading" json:"trading"`     // Business days during trading hours
+	OffHours SchedulePolicyConfig `mapstructure:"off_hours" json:"off_hours"` // Business days outside trading hours
+	Weekend  SchedulePolicyConfig `mapstructure:"weekend" json:"weekend"`     // Saturdays and Sundays
+}
+
+// SchedulePolicyConfig adjusts limits while its period applies. Zero fields
+// keep the configured limits.
+type SchedulePolicyConfig struct {
+	DefaultRate       int     `mapstructure:"default_rate" json:"default_rate"`               // Global rate per minute
+	BurstCapacity     int     `mapstructure:"burst_capacity" json:"burst_capacity"`           // Global burst
+	KeyRateMultiplier float64 `mapstructure:"key_rate_multiplier" json:"key_rate_multiplier"` // Scales every API key's rate_limit
+	MaxConcurrent     int     `mapstructure:"max_concurrent" json:"max_concurrent"`           // Default requests in flight per client
+	QuotaMultiplier   float64 `mapstructure:"quota_multiplier" json:"quota_multiplier"`       // Scales every daily and monthly quota
+}
+
+// Hours returns the start and end of trading hours as wall clock time since
+// midnight
+func (s *ScheduleConfig) Hours() (open, close time.Duration, err error) {
+	if open, err = clockTime(s.Open); err != nil {
+		return 0, 0, fmt.Errorf("invalid open time %q: %w", s.Open, err)
+	}
+	if close, err = clockTime(s.Close); err != nil {
+		return 0, 0, fmt.Errorf("invalid close time %q: %w", s.Close, err)
+	}
+	return open, close, nil
+}
+
+// clockTime parses an HH:MM time of day, allowing 24:00 for midnight at the
+// end of the day
+func clockTime(value string) (time.Duration, error) {
+	if value == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// QuotaConfig defines long-window usage quotas. API keys may set their own
+// daily and monthly quotas in place of the defaults.
+type QuotaConfig struct {
+	Enabled           bool      `mapstructure:"enabled" json:"enabled"`
+	Timezone          string    `mapstructure:"timezone" json:"timezone"`                     // IANA zone whose midnights reset quotas; empty is UTC
+	Daily             int64     `mapstructure:"daily" json:"daily"`                           // Default requests per day; zero is unlimited
+	Monthly           int64     `mapstructure:"monthly" json:"monthly"`                       // Default requests per month; zero is unlimited
+	WarningThresholds []float64 `mapstructure:"warning_thresholds" json:"warning_thresholds"` // Fractions of a quota that raise a warning
+	Weighted          bool      `mapstructure:"weighted" json:"weighted"`                     // Count each request as its route's cost
+}
+
+// Location is the time zone quota periods start in
+func (q *QuotaConfig) Location() (*time.Location, error) {
+	return time.LoadLocation(q.Timezone)
 }
 
 // CacheConfig defines caching configuration
 type CacheConfig struct {
-	Redis  RedisConfig  `mapstructure:"redis" json:"redis"`
-	Memory MemoryConfig `mapstructure:"memory" json:"memory"`
+	Redis      RedisConfig      `mapstructure:"redis" json:"redis"`
+	Memory     MemoryConfig     `mapstructure:"memory" json:"memory"`
+	Coalescing CoalescingConfig `mapstructure:"coalescing" json:"coalescing"`
+	Coherence  CoherenceConfig  `mapstructure:"coherence" json:"coherence"`
+	Warming    WarmingConfig    `mapstructure:"warming" json:"warming"`
+}
+
+// WarmingConfig fetches route warm URLs and the most requested cache keys
+// into the cache, at startup or through the admin API
+type WarmingConfig struct {
+	OnStartup   bool          `mapstructure:"on_startup" json:"on_startup"`
+	PopularKeys int           `mapstructure:"popular_keys" json:"popular_keys"` // Most requested keys replayed; zero warms only warm URLs
+	TrackedKeys int           `mapstructure:"tracked_keys" json:"tracked_keys"` // Keys whose lookups are counted; zero disables tracking
+	Concurrency int           `mapstructure:"concurrency" json:"concurrency"`   // Warm-up fetches in flight at once
+	Timeout     time.Duration `mapstructure:"timeout" json:"timeout"`           // Bounds a whole warm-up
+}
+
+// CoalescingConfig collapses concurrent cache misses for the same key into
+// one upstream request
+type CoalescingConfig struct {
+	Enabled    bool          `mapstructure:"enabled" json:"enabled"`
+	MaxWaiters int           `mapstructure:"max_waiters" json:"max_waiters"` // Requests beyond this fetch on their own
+	MaxWait    time.Duration `mapstructure:"max_wait" json:"max_wait"`       // Waiters fetch on their own after this
+}
+
+// CoherenceConfig propagates L1 invalidations between gateway replicas over
+// Redis pub/sub
+type CoherenceConfig struct {
+	Enabled            bool          `mapstructure:"enabled" json:"enabled"`
+	Channel            string        `mapstructure:"channel" json:"channel"`
+	EpochCheckInterval time.Duration `mapstructure:"epoch_check_interval" json:"epoch_check_interval"` // Bounds staleness after a missed message
 }
 
 // RedisConfig defines Redis-specific configuration
@@ -80,8 +186,10 @@ type RedisConfig struct {
 
 // MemoryConfig defines in-memory cache configuration
 type MemoryConfig struct {
-	MaxSize int           `mapstructure:"max_size" json:"max_size"`
-	TTL     time.Duration `mapstructure:"ttl" json:"ttl"`
+	MaxSize  int           `mapstructure:"max_size" json:"max_size"`
+	MaxBytes int64         `mapstructure:"max_bytes" json:"max_bytes"` // Limit on cached keys and values; 0 means unlimited
+	TTL      time.Duration `mapstructure:"ttl" json:"ttl"`
+	Shards   int           `mapstructure:"shards" json:"shards"` // Lock shards; 0 picks a count from max_size
 }
 
 // CircuitConfig defines circuit breaker configuration
@@ -93,10 +201,62 @@ type CircuitConfig struct {
 
 // BackendConfig defines backend service configuration
 type BackendConfig struct {
-	Name        string `mapstructure:"name" json:"name"`
-	URL         string `mapstructure:"url" json:"url"`
-	HealthCheck string `mapstructure:"health_check" json:"health_check"`
-	Weight      int    `mapstructure:"weight" json:"weight"`
+	Name        string            `mapstructure:"name" json:"name"`
+	URL         string            `mapstructure:"url" json:"url"`
+	HealthCheck string            `mapstructure:"health_check" json:"health_check"`
+	Weight      int               `mapstructure:"weight" json:"weight"`
+	Health      HealthCheckConfig `mapstructure:"health" json:"health"`                       // Active health check settings
+	Outlier     OutlierConfig     `mapstructure:"outlier_detection" json:"outlier_detection"` // Passive health checking from live traffic
+	Discovery   DiscoveryConfig   `mapstructure:"discovery" json:"discovery"`                 // Dynamic membership; replaces url when set
+}
+
+// HealthCheckConfig defines how a backend is actively probed. Zero values
+// fall back to the gateway defaults (30s interval, 5s timeout, thresholds
+// of 1, any 2xx status, GET).
+type HealthCheckConfig struct {
+	Interval           time.Duration     `mapstructure:"interval" json:"interval"`
+	Timeout            time.Duration     `mapstructure:"timeout" json:"timeout"`
+	HealthyThreshold   int               `mapstructure:"healthy_threshold" json:"healthy_threshold"`     // Consecutive passes to mark healthy
+	UnhealthyThreshold int               `mapstructure:"unhealthy_threshold" json:"unhealthy_threshold"` // Consecutive failures to mark unhealthy
+	ExpectedStatuses   []int             `mapstructure:"expected_statuses" json:"expected_statuses"`     // Empty means any 2xx
+	ExpectedBody       string            `mapstructure:"expected_body" json:"expected_body"`             // Substring the body must contain
+	ExpectedJSONPath   string            `mapstructure:"expected_json_path" json:"expected_json_path"`   // Dot-separated field that must exist
+	ExpectedJSONValue  string            `mapstructure:"expected_json_value" json:"expected_json_value"` // Required value at expected_json_path
+	Method             string            `mapstructure:"method" json:"method"`
+	Headers            map[string]string `mapstructure:"headers" json:"headers"`
+}
+
+// OutlierConfig ejects backend instances that fail live requests. Detection
+// is disabled when ConsecutiveErrors is zero.
+type OutlierConfig struct {
+	ConsecutiveErrors  int           `mapstructure:"consecutive_errors" json:"consecutive_errors"`     // 5xx responses or connection failures before ejection
+	BaseEjectionTime   time.Duration `mapstructure:"base_ejection_time" json:"base_ejection_time"`     // Doubled for every repeated ejection
+	MaxEjectionTime    time.Duration `mapstructure:"max_ejection_time" json:"max_ejection_time"`       // Upper bound on a single ejection
+	MaxEjectionPercent int           `mapstructure:"max_ejection_percent" json:"max_ejection_percent"` // Share of the pool that may be ejected at once
+}
+
+// Enabled reports whether outlier detection is configured
+func (o OutlierConfig) Enabled() bool {
+	return o.ConsecutiveErrors > 0
+}
+
+// DiscoveryConfig defines how backend instances are discovered at runtime
+type DiscoveryConfig struct {
+	Type            string        `mapstructure:"type" json:"type"`                         // dns, dns_srv, file or redis
+	Host            string        `mapstructure:"host" json:"host"`                         // DNS name to resolve (dns, dns_srv)
+	Port            int           `mapstructure:"port" json:"port"`                         // Port used for A/AAAA results (dns)
+	Scheme          string        `mapstructure:"scheme" json:"scheme"`                     // URL scheme for discovered endpoints
+	Service         string        `mapstructure:"service" json:"service"`                   // SRV service name (dns_srv)
+	Proto           string        `mapstructure:"proto" json:"proto"`                       // SRV protocol (dns_srv)
+	Path            string        `mapstructure:"path" json:"path"`                         // JSON/YAML endpoint file (file)
+	Key             string        `mapstructure:"key" json:"key"`                           // Membership set key (redis)
+	HeartbeatTTL    time.Duration `mapstructure:"heartbeat_ttl" json:"heartbeat_ttl"`       // Heartbeat age after which a member is dropped (redis)
+	RefreshInterval time.Duration `mapstructure:"refresh_interval" json:"refresh_interval"` // How often membership is re-resolved
+}
+
+// Enabled reports whether dynamic discovery is configured
+func (d *DiscoveryConfig) Enabled() bool {
+	return d.Type != ""
 }
 
 // RouteConfig defines route-specific configuration
@@ -106,6 +266,29 @@ type RouteConfig struct {
 	Methods   []string      `mapstructure:"methods" json:"methods"`
 	RateLimit int           `mapstructure:"rate_limit" json:"rate_limit"`
 	CacheTTL  time.Duration `mapstructure:"cache_ttl" json:"cache_ttl"`
+	CacheMode string        `mapstructure:"cache_mode" json:"cache_mode"` // ttl (default), headers or override
+
+	RateLimitAlgorithm string `mapstructure:"rate_limit_algorithm" json:"rate_limit_algorithm"` // Empty uses rate_limit.algorithm
+	Cost               int    `mapstructure:"cost" json:"cost"`                                 // Tokens a request takes from each rate limit layer, and quota units when weighted; zero counts as one
+
+	StaleWhileRevalidate time.Duration `mapstructure:"stale_while_revalidate" json:"stale_while_revalidate"` // Serve expired entries while refreshing them
+	StaleIfError         time.Duration `mapstructure:"stale_if_error" json:"stale_if_error"`                 // Serve expired entries while the backend fails
+	GenerateETag         bool          `mapstructure:"generate_etag" json:"generate_etag"`                   // Hash the body into an ETag when the backend sends none
+
+	CacheKey CacheKeyConfig `mapstructure:"cache_key" json:"cache_key"`
+
+	NegativeCacheTTL      time.Duration `mapstructure:"negative_cache_ttl" json:"negative_cache_ttl"`           // Cache error responses briefly; zero disables
+	NegativeCacheStatuses []int         `mapstructure:"negative_cache_statuses" json:"negative_cache_statuses"` // Error statuses cached; defaults to 404
+	WarmURLs              []string      `mapstructure:"warm_urls" json:"warm_urls"`                             // Paths fetched into the cache when warming
+}
+
+// CacheKeyConfig selects the request components a route's responses are
+// cached under. Keys are always namespaced by the route path.
+type CacheKeyConfig struct {
+	Query       string   `mapstructure:"query" json:"query"`               // full (default), sorted, allowlist or none
+	QueryParams []string `mapstructure:"query_params" json:"query_params"` // Parameters kept by the allowlist mode
+	Headers     []string `mapstructure:"headers" json:"headers"`           // Request headers whose values are part of the key
+	User        bool     `mapstructure:"user" json:"user"`                 // Cache separately per authenticated user or API key
 }
 
 // LoggingConfig defines logging configuration
@@ -154,6 +337,8 @@ func DefaultConfig() *Config {
 			ReadTimeout:  30 * time.Second,
 			WriteTimeout: 30 * time.Second,
 			IdleTimeout:  60 * time.Second,
+
+			ShutdownDrainDelay: 5 * time.Second,
 		},
 		Auth: AuthConfig{
 			JWT: JWTConfig{
@@ -171,6 +356,19 @@ func DefaultConfig() *Config {
 			BurstCapacity:   200,
 			Storage:         "memory",
 			CleanupInterval: 1 * time.Hour,
+			FailureMode:     "open",
+			Algorithm:       "token_bucket",
+			StorageTimeout:  100 * time.Millisecond,
+			LeaseTTL:        30 * time.Second,
+			Quota: QuotaConfig{
+				Timezone:          "UTC",
+				WarningThresholds: []float64{0.8, 0.95},
+			},
+			Schedule: ScheduleConfig{
+				Timezone: "America/New_York",
+				Open:     "09:30",
+				Close:    "16:00",
+			},
 		},
 		Cache: CacheConfig{
 			Redis: RedisConfig{
@@ -183,6 +381,21 @@ func DefaultConfig() *Config {
 				MaxSize: 1000,
 				TTL:     1 * time.Minute,
 			},
+			Coalescing: CoalescingConfig{
+				Enabled:    true,
+				MaxWaiters: 1000,
+				MaxWait:    5 * time.Second,
+			},
+			Coherence: CoherenceConfig{
+				Enabled:            true,
+				Channel:            "cache-invalidations",
+				EpochCheckInterval: 5 * time.Second,
+			},
+			Warming: WarmingConfig{
+				TrackedKeys: 10000,
+				Concurrency: 4,
+				Timeout:     30 * time.Second,
+			},
 		},
 		Circuit: CircuitConfig{
 			FailureThreshold: 5,
@@ -304,6 +517,16 @@ func (c *Config) Validate() error {
 		if err := route.Validate(); err != nil {
 			return fmt.Errorf("route[%d]: %w", i, err)
 		}
+		if route.Cost > c.RateLimit.BurstCapacity {
+			return fmt.Errorf("route[%d]: cost %d exceeds the rate limit burst capacity %d", i, route.Cost, c.RateLimit.BurstCapacity)
+		}
+		if schedule := c.RateLimit.Schedule; schedule.Enabled {
+			for _, policy := range []SchedulePolicyConfig{schedule.Trading, schedule.OffHours, schedule.Weekend} {
+				if policy.BurstCapacity > 0 && route.Cost > policy.BurstCapacity {
+					return fmt.Errorf("route[%d]: cost %d exceeds a schedule's burst capacity %d", i, route.Cost, policy.BurstCapacity)
+				}
+			}
+		}
 	}
 
 	return nil
@@ -331,6 +554,10 @@ func (s *ServerConfig) Validate() error {
 		return fmt.Errorf("idle timeout must be positive")
 	}
 
+	if s.ShutdownDrainDelay < 0 {
+		return fmt.Errorf("shutdown drain delay cannot be negative")
+	}
+
 	return nil
 }
 
@@ -399,9 +626,110 @@ func (r *RateLimitConfig) Validate() error {
 		return fmt.Errorf("rate limit cleanup interval must be positive, got %v", r.CleanupInterval)
 	}
 
+	switch r.FailureMode {
+	case "", "open", "closed":
+	default:
+		return fmt.Errorf("rate limit failure mode must be 'open' or 'closed', got %s", r.FailureMode)
+	}
+
+	if r.StorageTimeout < 0 {
+		return fmt.Errorf("rate limit storage timeout cannot be negative")
+	}
+
+	if !validRateLimitAlgorithm(r.Algorithm) {
+		return fmt.Errorf("unknown rate limit algorithm: %s", r.Algorithm)
+	}
+
+	if r.MaxConcurrent < 0 {
+		return fmt.Errorf("rate limit max concurrent cannot be negative, got %d", r.MaxConcurrent)
+	}
+
+	if r.LeaseTTL < 0 {
+		return fmt.Errorf("rate limit lease ttl cannot be negative")
+	}
+
+	if err := r.Quota.Validate(); err != nil {
+		return fmt.Errorf("quota: %w", err)
+	}
+
+	if r.Schedule.Enabled {
+		if err := r.Schedule.Validate(); err != nil {
+			return fmt.Errorf("schedule: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// Validate validates quota configuration
+func (q *QuotaConfig) Validate() error {
+	if _, err := q.Location(); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", q.Timezone, err)
+	}
+
+	if q.Daily < 0 || q.Monthly < 0 {
+		return fmt.Errorf("quotas cannot be negative")
+	}
+
+	for _, threshold := range q.WarningThresholds {
+		if threshold <= 0 || threshold > 1 {
+			return fmt.Errorf("warning thresholds must be between 0 and 1, got %v", threshold)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates schedule configuration
+func (s *ScheduleConfig) Validate() error {
+	if _, err := time.LoadLocation(s.Timezone); err != nil || s.Timezone == "" {
+		return fmt.Errorf("invalid timezone %q", s.Timezone)
+	}
+
+	open, close, err := s.Hours()
+	if err != nil {
+		return err
+	}
+	if open >= close {
+		return fmt.Errorf("trading hours must open before they close, got %s to %s", s.Open, s.Close)
+	}
+
+	for name, policy := range map[string]SchedulePolicyConfig{
+		"trading":   s.Trading,
+		"off_hours": s.OffHours,
+		"weekend":   s.Weekend,
+	} {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates a schedule period's policy
+func (p *SchedulePolicyConfig) Validate() error {
+	if p.DefaultRate < 0 || p.BurstCapacity < 0 || p.MaxConcurrent < 0 {
+		return fmt.Errorf("rates, burst capacity and max concurrent cannot be negative")
+	}
+
+	if p.KeyRateMultiplier < 0 || p.QuotaMultiplier < 0 {
+		return fmt.Errorf("multipliers cannot be negative")
+	}
+
+	return nil
+}
+
+// validRateLimitAlgorithm reports whether algorithm names a supported rate
+// limiting algorithm; empty selects the default
+func validRateLimitAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case "", "token_bucket", "gcra", "fixed_window", "sliding_window_counter", "sliding_window_log":
+		return true
+	}
+	return false
+}
+
 // Validate validates cache configuration
 func (c *CacheConfig) Validate() error {
 	if err := c.Redis.Validate(); err != nil {
@@ -412,6 +740,18 @@ func (c *CacheConfig) Validate() error {
 		return fmt.Errorf("memory: %w", err)
 	}
 
+	if c.Coalescing.MaxWaiters < 0 || c.Coalescing.MaxWait < 0 {
+		return fmt.Errorf("coalescing: limits cannot be negative")
+	}
+
+	if c.Coherence.EpochCheckInterval < 0 {
+		return fmt.Errorf("coherence: epoch check interval cannot be negative")
+	}
+
+	if c.Warming.PopularKeys < 0 || c.Warming.TrackedKeys < 0 || c.Warming.Concurrency < 0 || c.Warming.Timeout < 0 {
+		return fmt.Errorf("warming: limits cannot be negative")
+	}
+
 	return nil
 }
 
@@ -442,6 +782,14 @@ func (m *MemoryConfig) Validate() error {
 		return fmt.Errorf("ttl must be positive")
 	}
 
+	if m.MaxBytes < 0 {
+		return fmt.Errorf("max bytes cannot be negative")
+	}
+
+	if m.Shards < 0 {
+		return fmt.Errorf("shards cannot be negative")
+	}
+
 	return nil
 }
 
@@ -468,7 +816,7 @@ func (b *BackendConfig) Validate() error {
 		return fmt.Errorf("name cannot be empty")
 	}
 
-	if b.URL == "" {
+	if b.URL == "" && !b.Discovery.Enabled() {
 		return fmt.Errorf("url cannot be empty")
 	}
 
@@ -476,6 +824,114 @@ func (b *BackendConfig) Validate() error {
 		return fmt.Errorf("weight must be positive")
 	}
 
+	if err := b.Health.Validate(); err != nil {
+		return fmt.Errorf("health: %w", err)
+	}
+
+	if err := b.Outlier.Validate(); err != nil {
+		return fmt.Errorf("outlier_detection: %w", err)
+	}
+
+	if b.Discovery.Enabled() {
+		if err := b.Discovery.Validate(); err != nil {
+			return fmt.Errorf("discovery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates health check configuration
+func (h *HealthCheckConfig) Validate() error {
+	if h.Interval < 0 {
+		return fmt.Errorf("interval cannot be negative")
+	}
+
+	if h.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+
+	if h.HealthyThreshold < 0 || h.UnhealthyThreshold < 0 {
+		return fmt.Errorf("thresholds cannot be negative")
+	}
+
+	for _, status := range h.ExpectedStatuses {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("expected status must be between 100 and 599, got %d", status)
+		}
+	}
+
+	if h.ExpectedJSONValue != "" && h.ExpectedJSONPath == "" {
+		return fmt.Errorf("expected json value requires expected json path")
+	}
+
+	switch strings.ToUpper(h.Method) {
+	case "", "GET", "HEAD", "POST", "OPTIONS":
+	default:
+		return fmt.Errorf("method must be GET, HEAD, POST or OPTIONS, got %s", h.Method)
+	}
+
+	return nil
+}
+
+// Validate validates outlier detection configuration
+func (o *OutlierConfig) Validate() error {
+	if o.ConsecutiveErrors < 0 {
+		return fmt.Errorf("consecutive errors cannot be negative")
+	}
+
+	if o.BaseEjectionTime < 0 || o.MaxEjectionTime < 0 {
+		return fmt.Errorf("ejection times cannot be negative")
+	}
+
+	if o.MaxEjectionTime > 0 && o.MaxEjectionTime < o.BaseEjectionTime {
+		return fmt.Errorf("max ejection time cannot be less than base ejection time")
+	}
+
+	if o.MaxEjectionPercent < 0 || o.MaxEjectionPercent > 100 {
+		return fmt.Errorf("max ejection percent must be between 0 and 100, got %d", o.MaxEjectionPercent)
+	}
+
+	return nil
+}
+
+// Validate validates discovery configuration
+func (d *DiscoveryConfig) Validate() error {
+	switch d.Type {
+	case "dns":
+		if d.Host == "" {
+			return fmt.Errorf("host cannot be empty for dns discovery")
+		}
+		if d.Port < 1 || d.Port > 65535 {
+			return fmt.Errorf("port must be between 1 and 65535, got %d", d.Port)
+		}
+	case "dns_srv":
+		if d.Host == "" {
+			return fmt.Errorf("host cannot be empty for dns_srv discovery")
+		}
+	case "file":
+		if d.Path == "" {
+			return fmt.Errorf("path cannot be empty for file discovery")
+		}
+	case "redis":
+		if d.Key == "" {
+			return fmt.Errorf("key cannot be empty for redis discovery")
+		}
+		if d.HeartbeatTTL < 0 {
+			return fmt.Errorf("heartbeat ttl cannot be negative")
+		}
+	default:
+		return fmt.Errorf("type must be 'dns', 'dns_srv', 'file' or 'redis', got %s", d.Type)
+	}
+
+	if d.Scheme != "" && d.Scheme != "http" && d.Scheme != "https" {
+		return fmt.Errorf("scheme must be 'http' or 'https', got %s", d.Scheme)
+	}
+
+	if d.RefreshInterval < 0 {
+		return fmt.Errorf("refresh interval cannot be negative")
+	}
+
 	return nil
 }
 
@@ -497,10 +953,74 @@ func (r *RouteConfig) Validate() error {
 		return fmt.Errorf("rate limit cannot be negative")
 	}
 
+	if !validRateLimitAlgorithm(r.RateLimitAlgorithm) {
+		return fmt.Errorf("unknown rate limit algorithm: %s", r.RateLimitAlgorithm)
+	}
+
+	if r.Cost < 0 {
+		return fmt.Errorf("cost cannot be negative")
+	}
+
+	if r.RateLimit > 0 && r.Cost > r.RateLimit {
+		return fmt.Errorf("cost %d exceeds the route rate limit %d", r.Cost, r.RateLimit)
+	}
+
 	if r.CacheTTL < 0 {
 		return fmt.Errorf("cache ttl cannot be negative")
 	}
 
+	if r.StaleWhileRevalidate < 0 || r.StaleIfError < 0 {
+		return fmt.Errorf("stale windows cannot be negative")
+	}
+
+	switch r.CacheMode {
+	case "", "ttl", "headers", "override":
+	default:
+		return fmt.Errorf("cache mode must be ttl, headers or override, got %s", r.CacheMode)
+	}
+
+	if r.NegativeCacheTTL < 0 {
+		return fmt.Errorf("negative cache ttl cannot be negative")
+	}
+
+	// 5xx responses count as backend failures and are never cached
+	for _, status := range r.NegativeCacheStatuses {
+		if status < 400 || status > 499 {
+			return fmt.Errorf("negative cache statuses must be 4xx, got %d", status)
+		}
+	}
+
+	for _, target := range r.WarmURLs {
+		if !strings.HasPrefix(target, "/") {
+			return fmt.Errorf("warm url must be a path starting with /, got %s", target)
+		}
+	}
+
+	if err := r.CacheKey.Validate(); err != nil {
+		return fmt.Errorf("cache key: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates cache key configuration
+func (k *CacheKeyConfig) Validate() error {
+	switch k.Query {
+	case "", "full", "sorted", "none":
+	case "allowlist":
+		if len(k.QueryParams) == 0 {
+			return fmt.Errorf("allowlist query mode requires query params")
+		}
+	default:
+		return fmt.Errorf("query mode must be full, sorted, allowlist or none, got %s", k.Query)
+	}
+
+	for _, header := range k.Headers {
+		if strings.TrimSpace(header) == "" {
+			return fmt.Errorf("header names cannot be empty")
+		}
+	}
+
 	return nil
 }
 
@@ -565,6 +1085,60 @@ func (c *Config) GetRouteByPath(path string) (*RouteConfig, error) {
 	return nil, fmt.Errorf("route not found: %s", path)
 }
 
+// MatchRoute returns the first route whose pattern matches the request path.
+// Patterns match exactly, by prefix when they end in "*", or segment by
+// segment when they start with a ":" parameter.
+func (c *Config) MatchRoute(path string) *RouteConfig {
+	for i := range c.Routes {
+		if matchRoutePattern(path, c.Routes[i].Path) {
+			return &c.Routes[i]
+		}
+	}
+	return nil
+}
+
+// matchRoutePattern performs path matching with wildcard support
+func matchRoutePattern(path, pattern string) bool {
+	// Exact match
+	if path == pattern {
+		return true
+	}
+
+	// Wildcard matching
+	if strings.HasSuffix(pattern, "*") {
+		prefix := strings.TrimSuffix(pattern, "*")
+		return strings.HasPrefix(path, prefix)
+	}
+
+	// Path parameter matching (basic implementation)
+	if strings.HasPrefix(pattern, ":") {
+		return matchRouteParams(path, pattern)
+	}
+	return false
+}
+
+// matchRouteParams handles path parameters like /api/users/:id
+func matchRouteParams(path, pattern string) bool {
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+
+	if len(pathParts) != len(patternParts) {
+		return false
+	}
+
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, ":") {
+			// This is a parameter, skip validation
+			continue
+		}
+
+		if part != pathParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // IsDevelopment returns true if running in development mode
 func (c *Config) IsDevelopment() bool {
 	return os.Getenv("KALSHI_ENV") == "development"
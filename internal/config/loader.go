@@ -48,6 +48,7 @@ func setDefaults() {
 	viper.SetDefault("server.read_timeout", "30s")
 	viper.SetDefault("server.write_timeout", "30s")
 	viper.SetDefault("server.idle_timeout", "60s")
+	viper.SetDefault("server.shutdown_drain_delay", "5s")
 
 	// Auth Defaults - Authentication and authorization settings
 	viper.SetDefault("auth.jwt.secret", "your-secret-key-change-this-in-production")
@@ -60,6 +61,20 @@ func setDefaults() {
 	viper.SetDefault("rate_limit.burst_capacity", 200) // burst capacity
 	viper.SetDefault("rate_limit.storage", "memory")   // memory or redis
 	viper.SetDefault("rate_limit.cleanup_interval", "60s")
+	viper.SetDefault("rate_limit.failure_mode", "open")
+	viper.SetDefault("rate_limit.algorithm", "token_bucket")
+	viper.SetDefault("rate_limit.ietf_headers", false)
+	viper.SetDefault("rate_limit.cost_header", "")
+	viper.SetDefault("rate_limit.storage_timeout", "100ms")
+	viper.SetDefault("rate_limit.max_concurrent", 0)
+	viper.SetDefault("rate_limit.lease_ttl", "30s")
+	viper.SetDefault("rate_limit.quota.enabled", false)
+	viper.SetDefault("rate_limit.quota.timezone", "UTC")
+	viper.SetDefault("rate_limit.quota.warning_thresholds", []float64{0.8, 0.95})
+	viper.SetDefault("rate_limit.schedule.enabled", false)
+	viper.SetDefault("rate_limit.schedule.timezone", "America/New_York")
+	viper.SetDefault("rate_limit.schedule.open", "09:30")
+	viper.SetDefault("rate_limit.schedule.close", "16:00")
 
 	// Cache Defaults - Caching configuration for Redis and memory
 	viper.SetDefault("cache.redis.addr", "localhost:6379")
@@ -67,6 +82,17 @@ func setDefaults() {
 	viper.SetDefault("cache.redis.ttl", "300s")
 	viper.SetDefault("cache.memory.max_size", 1000)
 	viper.SetDefault("cache.memory.ttl", "60s")
+	viper.SetDefault("cache.memory.max_bytes", 0)
+	viper.SetDefault("cache.memory.shards", 0)
+	viper.SetDefault("cache.coalescing.enabled", true)
+	viper.SetDefault("cache.coalescing.max_waiters", 1000)
+	viper.SetDefault("cache.coalescing.max_wait", "5s")
+	viper.SetDefault("cache.coherence.enabled", true)
+	viper.SetDefault("cache.coherence.channel", "cache-invalidations")
+	viper.SetDefault("cache.coherence.epoch_check_interval", "5s")
+	viper.SetDefault("cache.warming.tracked_keys", 10000)
+	viper.SetDefault("cache.warming.concurrency", 4)
+	viper.SetDefault("cache.warming.timeout", "30s")
 
 	// Circuit Breaker Defaults - Circuit breaker pattern configuration
 	viper.SetDefault("circuit.failure_threshold", 5)    // failures before opening circuit
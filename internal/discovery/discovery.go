@@ -0,0 +1,86 @@
+// Package discovery resolves the live set of instances behind a backend.
+// Providers are polled periodically by the gateway, which reconciles the
+// returned endpoints into the backend pool used for load balancing.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"kalshi/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultRefreshInterval is used when a backend does not set refresh_interval
+	DefaultRefreshInterval = 10 * time.Second
+	// DefaultHeartbeatTTL is used when a redis backend does not set heartbeat_ttl
+	DefaultHeartbeatTTL = 30 * time.Second
+	// DefaultScheme is used for endpoints that are discovered without a scheme
+	DefaultScheme = "http"
+)
+
+// Endpoint is a single backend instance reported by a Provider
+type Endpoint struct {
+	URL    string `mapstructure:"url" json:"url"`
+	Weight int    `mapstructure:"weight" json:"weight"` // 0 means use the backend's configured weight
+}
+
+// Provider resolves the current set of endpoints for a backend
+type Provider interface {
+	// Discover returns the endpoints that should currently receive traffic
+	Discover(ctx context.Context) ([]Endpoint, error)
+	// Name identifies the provider type in logs and admin output
+	Name() string
+}
+
+// NewProvider builds the provider described by cfg. Redis providers connect
+// using redisCfg, which is normally the gateway's cache.redis section, with a
+// client of their own that is released by closing the provider.
+func NewProvider(cfg config.DiscoveryConfig, redisCfg config.RedisConfig) (Provider, error) {
+	scheme := cfg.Scheme
+	if scheme == "" {
+		scheme = DefaultScheme
+	}
+
+	switch cfg.Type {
+	case "dns":
+		return NewDNSProvider(nil, cfg.Host, cfg.Port, scheme), nil
+	case "dns_srv":
+		return NewSRVProvider(nil, cfg.Service, cfg.Proto, cfg.Host, scheme), nil
+	case "file":
+		return NewFileProvider(cfg.Path), nil
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     redisCfg.Addr,
+			Password: redisCfg.Password,
+			DB:       redisCfg.DB,
+		})
+		ttl := cfg.HeartbeatTTL
+		if ttl <= 0 {
+			ttl = DefaultHeartbeatTTL
+		}
+		return NewRedisProvider(client, cfg.Key, ttl), nil
+	default:
+		return nil, fmt.Errorf("unknown discovery type: %s", cfg.Type)
+	}
+}
+
+// RefreshInterval returns the configured refresh interval or the default
+func RefreshInterval(cfg config.DiscoveryConfig) time.Duration {
+	if cfg.RefreshInterval > 0 {
+		return cfg.RefreshInterval
+	}
+	return DefaultRefreshInterval
+}
+
+// sortEndpoints orders endpoints by URL so that providers return stable results
+func sortEndpoints(endpoints []Endpoint) []Endpoint {
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].URL < endpoints[j].URL
+	})
+	return endpoints
+}
@@ -0,0 +1,102 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DNSProvider discovers endpoints from A/AAAA or SRV records. It keeps no
+// state between calls, so every Discover re-resolves the name.
+type DNSProvider struct {
+	resolver *net.Resolver
+	host     string
+	port     int
+	scheme   string
+	srv      bool
+	service  string
+	proto    string
+}
+
+// NewDNSProvider creates a provider that resolves host's A/AAAA records and
+// pairs every address with port. A nil resolver uses net.DefaultResolver.
+func NewDNSProvider(resolver *net.Resolver, host string, port int, scheme string) *DNSProvider {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &DNSProvider{
+		resolver: resolver,
+		host:     host,
+		port:     port,
+		scheme:   scheme,
+	}
+}
+
+// NewSRVProvider creates a provider that resolves _service._proto.host SRV
+// records. Targets and ports come from the records; SRV weights become
+// endpoint weights. Empty service and proto look up host directly.
+func NewSRVProvider(resolver *net.Resolver, service, proto, host, scheme string) *DNSProvider {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	if service != "" && proto == "" {
+		proto = "tcp"
+	}
+	return &DNSProvider{
+		resolver: resolver,
+		host:     host,
+		scheme:   scheme,
+		srv:      true,
+		service:  service,
+		proto:    proto,
+	}
+}
+
+// Name returns the provider type
+func (p *DNSProvider) Name() string {
+	if p.srv {
+		return "dns_srv"
+	}
+	return "dns"
+}
+
+// Discover resolves the configured name
+func (p *DNSProvider) Discover(ctx context.Context) ([]Endpoint, error) {
+	if p.srv {
+		return p.discoverSRV(ctx)
+	}
+
+	addrs, err := p.resolver.LookupHost(ctx, p.host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", p.host, err)
+	}
+
+	endpoints := make([]Endpoint, 0, len(addrs))
+	for _, addr := range addrs {
+		endpoints = append(endpoints, Endpoint{
+			URL: p.scheme + "://" + net.JoinHostPort(addr, strconv.Itoa(p.port)),
+		})
+	}
+
+	return sortEndpoints(endpoints), nil
+}
+
+func (p *DNSProvider) discoverSRV(ctx context.Context) ([]Endpoint, error) {
+	_, records, err := p.resolver.LookupSRV(ctx, p.service, p.proto, p.host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve SRV records for %s: %w", p.host, err)
+	}
+
+	endpoints := make([]Endpoint, 0, len(records))
+	for _, record := range records {
+		target := strings.TrimSuffix(record.Target, ".")
+		endpoints = append(endpoints, Endpoint{
+			URL:    p.scheme + "://" + net.JoinHostPort(target, strconv.Itoa(int(record.Port))),
+			Weight: int(record.Weight),
+		})
+	}
+
+	return sortEndpoints(endpoints), nil
+}
@@ -0,0 +1,106 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// FileProvider reads endpoints from a JSON or YAML file. The file is only
+// re-parsed when its modification time or size changes, so it is cheap to
+// poll on every refresh. Expected format:
+//
+//	endpoints:
+//	  - url: "http://10.0.0.12:8080"
+//	    weight: 2
+type FileProvider struct {
+	path string
+
+	mu        sync.Mutex
+	modTime   time.Time
+	size      int64
+	endpoints []Endpoint
+}
+
+// NewFileProvider creates a provider backed by the file at path
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Name returns the provider type
+func (p *FileProvider) Name() string {
+	return "file"
+}
+
+// Discover returns the endpoints listed in the file
+func (p *FileProvider) Discover(ctx context.Context) ([]Endpoint, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat endpoint file '%s': %w", p.path, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.endpoints != nil && info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return copyEndpoints(p.endpoints), nil
+	}
+
+	endpoints, err := readEndpointFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	p.endpoints = endpoints
+
+	return copyEndpoints(endpoints), nil
+}
+
+// readEndpointFile parses the endpoint list using the file extension to pick
+// between JSON and YAML
+func readEndpointFile(path string) ([]Endpoint, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		v.SetConfigType("json")
+	case ".yaml", ".yml", "":
+		v.SetConfigType("yaml")
+	default:
+		return nil, fmt.Errorf("unsupported endpoint file extension: %s", filepath.Ext(path))
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read endpoint file '%s': %w", path, err)
+	}
+
+	var endpoints []Endpoint
+	if err := v.UnmarshalKey("endpoints", &endpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse endpoint file '%s': %w", path, err)
+	}
+
+	valid := make([]Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint.URL == "" {
+			continue
+		}
+		valid = append(valid, endpoint)
+	}
+
+	return sortEndpoints(valid), nil
+}
+
+func copyEndpoints(endpoints []Endpoint) []Endpoint {
+	out := make([]Endpoint, len(endpoints))
+	copy(out, endpoints)
+	return out
+}
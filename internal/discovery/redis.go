@@ -0,0 +1,112 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisProvider reads membership from a Redis sorted set where each member
+// is an endpoint URL scored by the unix time of its last heartbeat. Members
+// whose heartbeat is older than ttl are ignored and pruned.
+type RedisProvider struct {
+	client *redis.Client
+	key    string
+	ttl    time.Duration
+}
+
+// NewRedisProvider creates a provider for the membership set stored at key
+func NewRedisProvider(client *redis.Client, key string, ttl time.Duration) *RedisProvider {
+	return &RedisProvider{
+		client: client,
+		key:    key,
+		ttl:    ttl,
+	}
+}
+
+// Name returns the provider type
+func (p *RedisProvider) Name() string {
+	return "redis"
+}
+
+// Close closes the provider's Redis client
+func (p *RedisProvider) Close() error {
+	return p.client.Close()
+}
+
+// Discover returns every member with a live heartbeat
+func (p *RedisProvider) Discover(ctx context.Context) ([]Endpoint, error) {
+	cutoff := strconv.FormatInt(time.Now().Add(-p.ttl).Unix(), 10)
+
+	// Pruning is best effort; readers filter by score regardless
+	_ = p.client.ZRemRangeByScore(ctx, p.key, "-inf", "("+cutoff).Err()
+
+	members, err := p.client.ZRangeByScore(ctx, p.key, &redis.ZRangeBy{
+		Min: cutoff,
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read membership set %s: %w", p.key, err)
+	}
+
+	endpoints := make([]Endpoint, 0, len(members))
+	for _, member := range members {
+		endpoints = append(endpoints, Endpoint{URL: member})
+	}
+
+	return sortEndpoints(endpoints), nil
+}
+
+// Registrar lets a backend instance announce itself in a Redis membership
+// set read by RedisProvider
+type Registrar struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRegistrar creates a registrar for the membership set stored at key
+func NewRegistrar(client *redis.Client, key string) *Registrar {
+	return &Registrar{
+		client: client,
+		key:    key,
+	}
+}
+
+// Register adds or refreshes endpointURL with the current time as heartbeat
+func (r *Registrar) Register(ctx context.Context, endpointURL string) error {
+	return r.client.ZAdd(ctx, r.key, redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: endpointURL,
+	}).Err()
+}
+
+// Deregister removes endpointURL from the membership set
+func (r *Registrar) Deregister(ctx context.Context, endpointURL string) error {
+	return r.client.ZRem(ctx, r.key, endpointURL).Err()
+}
+
+// Heartbeat registers endpointURL every interval until ctx is cancelled, then
+// deregisters it so the gateway stops routing to the instance immediately
+func (r *Registrar) Heartbeat(ctx context.Context, endpointURL string, interval time.Duration) error {
+	if err := r.Register(ctx, endpointURL); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			deregisterCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return r.Deregister(deregisterCtx, endpointURL)
+		case <-ticker.C:
+			// A missed heartbeat is tolerated until the TTL expires
+			_ = r.Register(ctx, endpointURL)
+		}
+	}
+}
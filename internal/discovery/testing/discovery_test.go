@@ -0,0 +1,134 @@
+package testing
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kalshi/internal/config"
+	"kalshi/internal/discovery"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileProvider_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "endpoints.yaml")
+	content := `endpoints:
+  - url: "http://10.0.0.2:8080"
+    weight: 3
+  - url: "http://10.0.0.1:8080"
+  - url: ""
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	provider := discovery.NewFileProvider(path)
+	endpoints, err := provider.Discover(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []discovery.Endpoint{
+		{URL: "http://10.0.0.1:8080"},
+		{URL: "http://10.0.0.2:8080", Weight: 3},
+	}, endpoints)
+	assert.Equal(t, "file", provider.Name())
+}
+
+func TestFileProvider_JSONReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "endpoints.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"endpoints": [{"url": "http://a:80"}]}`), 0o644))
+
+	provider := discovery.NewFileProvider(path)
+	endpoints, err := provider.Discover(context.Background())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+
+	updated := `{"endpoints": [{"url": "http://a:80"}, {"url": "http://b:80"}]}`
+	require.NoError(t, os.WriteFile(path, []byte(updated), 0o644))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, future, future))
+
+	endpoints, err = provider.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, endpoints, 2)
+}
+
+func TestFileProvider_MissingFile(t *testing.T) {
+	provider := discovery.NewFileProvider(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	_, err := provider.Discover(context.Background())
+	assert.Error(t, err)
+}
+
+func TestDNSProvider_Localhost(t *testing.T) {
+	provider := discovery.NewDNSProvider(nil, "localhost", 8080, "http")
+
+	endpoints, err := provider.Discover(context.Background())
+	if err != nil {
+		t.Skipf("localhost does not resolve in this environment: %v", err)
+	}
+
+	require.NotEmpty(t, endpoints)
+	for _, endpoint := range endpoints {
+		assert.Contains(t, endpoint.URL, ":8080")
+		assert.Contains(t, endpoint.URL, "http://")
+	}
+	assert.Equal(t, "dns", provider.Name())
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          config.DiscoveryConfig
+		expectedName string
+		expectError  bool
+	}{
+		{
+			name:         "dns",
+			cfg:          config.DiscoveryConfig{Type: "dns", Host: "localhost", Port: 80},
+			expectedName: "dns",
+		},
+		{
+			name:         "dns srv",
+			cfg:          config.DiscoveryConfig{Type: "dns_srv", Service: "http", Host: "example.com"},
+			expectedName: "dns_srv",
+		},
+		{
+			name:         "file",
+			cfg:          config.DiscoveryConfig{Type: "file", Path: "/tmp/endpoints.yaml"},
+			expectedName: "file",
+		},
+		{
+			name:         "redis",
+			cfg:          config.DiscoveryConfig{Type: "redis", Key: "discovery:test"},
+			expectedName: "redis",
+		},
+		{
+			name:        "unknown",
+			cfg:         config.DiscoveryConfig{Type: "consul"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := discovery.NewProvider(tt.cfg, config.RedisConfig{Addr: "localhost:6379"})
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedName, provider.Name())
+			if closer, ok := provider.(io.Closer); ok {
+				assert.NoError(t, closer.Close())
+			}
+		})
+	}
+}
+
+func TestRefreshInterval(t *testing.T) {
+	assert.Equal(t, discovery.DefaultRefreshInterval, discovery.RefreshInterval(config.DiscoveryConfig{}))
+	assert.Equal(t, time.Second, discovery.RefreshInterval(config.DiscoveryConfig{RefreshInterval: time.Second}))
+}
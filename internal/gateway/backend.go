@@ -3,7 +3,6 @@ package gateway
 import (
 	"context"
 	"fmt"
-	"net"
 	"net/http"
 	"net/url"
 	"sync"
@@ -16,21 +15,66 @@ type Backend struct {
 	HealthCheck string
 	Weight      int
 	IsHealthy   bool
+	Pool        string // Logical backend this instance was discovered for; empty for static backends
 	mu          sync.RWMutex
+
+	// Active health check state, guarded by mu
+	healthOpts           HealthCheckOptions
+	consecutiveSuccesses int
+	consecutiveFailures  int
+	lastProbe            time.Time
+	probing              bool
+	history              healthHistory
+
+	// Passive health check state, guarded by mu
+	outlierOpts       OutlierOptions
+	consecutiveErrors int
+	ejections         int // Ejections so far; drives exponential back-off
+	ejectedUntil      time.Time
+}
+
+// Healthy reports the current health status
+func (b *Backend) Healthy() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.IsHealthy
+}
+
+// HealthOptions returns the active health check settings of the backend
+func (b *Backend) HealthOptions() HealthCheckOptions {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.healthOpts
 }
 
 type BackendManager struct {
-	backends map[string]*Backend
-	mu       sync.RWMutex
+	backends    map[string]*Backend
+	pools       map[string]*backendPool
+	probeClient *http.Client // Shared by every health probe
+	mu          sync.RWMutex
+	ejectMu     sync.Mutex // Serializes outlier ejection decisions
+
+	// Service discovery loops stop when stopDiscovery is closed
+	stopDiscovery chan struct{}
+	stopOnce      sync.Once
+	discoveries   sync.WaitGroup
 }
 
 func NewBackendManager() *BackendManager {
 	return &BackendManager{
-		backends: make(map[string]*Backend),
+		backends:      make(map[string]*Backend),
+		pools:         make(map[string]*backendPool),
+		probeClient:   newProbeClient(),
+		stopDiscovery: make(chan struct{}),
 	}
 }
 
 func (bm *BackendManager) AddBackend(name, urlStr, healthCheck string, weight int) error {
+	return bm.AddBackendWithOptions(name, urlStr, healthCheck, weight, DefaultHealthCheckOptions())
+}
+
+// AddBackendWithOptions adds a backend with custom active health check settings
+func (bm *BackendManager) AddBackendWithOptions(name, urlStr, healthCheck string, weight int, opts HealthCheckOptions) error {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
 		return fmt.Errorf("failed to parse backend URL: %w", err)
@@ -42,6 +86,7 @@ func (bm *BackendManager) AddBackend(name, urlStr, healthCheck string, weight in
 		HealthCheck: healthCheck,
 		Weight:      weight,
 		IsHealthy:   true,
+		healthOpts:  opts,
 	}
 
 	bm.mu.Lock()
@@ -55,6 +100,15 @@ func (bm *BackendManager) GetBackend(name string) (*Backend, error) {
 	bm.mu.RLock()
 	defer bm.mu.RUnlock()
 
+	// Discovered backends are balanced across their healthy instances
+	if pool, isPool := bm.pools[name]; isPool {
+		backend := pool.next()
+		if backend == nil {
+			return nil, fmt.Errorf("backend %s has no healthy instances", name)
+		}
+		return backend, nil
+	}
+
 	backend, exists := bm.backends[name]
 	if !exists {
 		return nil, fmt.Errorf("backend %s not found", name)
@@ -67,6 +121,10 @@ func (bm *BackendManager) GetBackend(name string) (*Backend, error) {
 		return nil, fmt.Errorf("backend %s is unhealthy", name)
 	}
 
+	if time.Now().Before(backend.ejectedUntil) {
+		return nil, fmt.Errorf("backend %s is ejected", name)
+	}
+
 	return backend, nil
 }
 
@@ -103,16 +161,28 @@ func (bm *BackendManager) GetAllBackends() []*Backend {
 	return bm.GetBackends()
 }
 
-func (bm *BackendManager) StartHealthChecks(interval time.Duration) {
-	ticker := time.NewTicker(interval)
+// StartHealthChecks probes every backend on its own interval. Backends
+// without a configured interval use defaultInterval.
+func (bm *BackendManager) StartHealthChecks(defaultInterval time.Duration) {
+	if defaultInterval <= 0 {
+		defaultInterval = DefaultHealthCheckInterval
+	}
+
+	// Tick often enough to honour per-backend intervals shorter than the default
+	tick := defaultInterval
+	if tick > time.Second {
+		tick = time.Second
+	}
+
+	ticker := time.NewTicker(tick)
 	go func() {
 		for range ticker.C {
-			bm.performHealthChecks()
+			bm.performHealthChecks(defaultInterval)
 		}
 	}()
 }
 
-func (bm *BackendManager) performHealthChecks() {
+func (bm *BackendManager) performHealthChecks(defaultInterval time.Duration) {
 	bm.mu.RLock()
 	backends := make([]*Backend, 0, len(bm.backends))
 	for _, backend := range bm.backends {
@@ -120,58 +190,111 @@ func (bm *BackendManager) performHealthChecks() {
 	}
 	bm.mu.RUnlock()
 
+	now := time.Now()
 	for _, backend := range backends {
-		go bm.checkBackendHealth(backend)
+		if backend.claimProbe(now, defaultInterval) {
+			go bm.checkBackendHealth(backend)
+		}
 	}
 }
 
+// claimProbe reports whether the backend is due for a probe and marks it as
+// in progress so slow probes never overlap
+func (b *Backend) claimProbe(now time.Time, defaultInterval time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	interval := b.healthOpts.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	if b.probing || now.Sub(b.lastProbe) < interval {
+		return false
+	}
+
+	b.probing = true
+	b.lastProbe = now
+	return true
+}
+
 func (bm *BackendManager) checkBackendHealth(backend *Backend) {
+	defer func() {
+		backend.mu.Lock()
+		backend.probing = false
+		backend.mu.Unlock()
+	}()
+
 	if backend.HealthCheck == "" {
 		return
 	}
 
+	bm.recordProbe(backend, bm.probe(backend))
+}
+
+// probe performs a single health check request against the backend
+func (bm *BackendManager) probe(backend *Backend) probeResult {
+	opts := backend.HealthOptions()
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultHealthCheckTimeout
+	}
+	if opts.Method == "" {
+		opts.Method = http.MethodGet
+	}
+
 	healthURL := backend.URL.String() + backend.HealthCheck
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", healthURL, nil)
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, opts.Method, healthURL, nil)
 	if err != nil {
-		bm.updateBackendHealth(backend, false)
-		return
+		return probeResult{reason: fmt.Sprintf("invalid health check request: %v", err)}
 	}
+	opts.applyHeaders(req)
 
-	// Use optimized HTTP client for health checks
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 100,
-			IdleConnTimeout:     30 * time.Second,
-			DisableCompression:  true,
-			DisableKeepAlives:   false,
-			ForceAttemptHTTP2:   true,
-			DialContext: (&net.Dialer{
-				Timeout:   3 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-		},
-	}
-
-	resp, err := client.Do(req)
+	resp, err := bm.probeClient.Do(req)
 	if err != nil {
-		bm.updateBackendHealth(backend, false)
-		return
+		return probeResult{latency: time.Since(start), reason: err.Error()}
 	}
 	defer resp.Body.Close()
 
-	healthy := resp.StatusCode >= 200 && resp.StatusCode < 300
-	bm.updateBackendHealth(backend, healthy)
+	reason := opts.evaluate(resp)
+	return probeResult{
+		healthy: reason == "",
+		latency: time.Since(start),
+		reason:  reason,
+	}
+}
+
+// recordProbe applies a probe result, only flipping health once the
+// configured number of consecutive results agree
+func (bm *BackendManager) recordProbe(backend *Backend, result probeResult) {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+
+	if result.healthy {
+		backend.consecutiveSuccesses++
+		backend.consecutiveFailures = 0
+		if !backend.IsHealthy && backend.consecutiveSuccesses >= max(backend.healthOpts.HealthyThreshold, 1) {
+			backend.recordTransitionLocked(true, TransitionSourceProbe, result)
+		}
+		return
+	}
+
+	backend.consecutiveFailures++
+	backend.consecutiveSuccesses = 0
+	if backend.IsHealthy && backend.consecutiveFailures >= max(backend.healthOpts.UnhealthyThreshold, 1) {
+		backend.recordTransitionLocked(false, TransitionSourceProbe, result)
+	}
 }
 
 func (bm *BackendManager) updateBackendHealth(backend *Backend, healthy bool) {
 	backend.mu.Lock()
-	backend.IsHealthy = healthy
+	backend.recordTransitionLocked(healthy, TransitionSourceManual, probeResult{})
+	backend.consecutiveSuccesses = 0
+	backend.consecutiveFailures = 0
 	backend.mu.Unlock()
 }
 
@@ -195,7 +318,11 @@ func (bm *BackendManager) TriggerHealthCheck(name string) error {
 		return err
 	}
 
-	bm.checkBackendHealth(backend)
+	if backend.HealthCheck == "" {
+		return nil
+	}
+
+	bm.recordProbe(backend, bm.probe(backend))
 	return nil
 }
 
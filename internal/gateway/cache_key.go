@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"kalshi/pkg/utils"
+)
+
+// QueryKeyMode selects how the query string contributes to a cache key
+type QueryKeyMode string
+
+const (
+	// QueryKeyFull uses the query string exactly as sent
+	QueryKeyFull QueryKeyMode = "full"
+	// QueryKeySorted normalizes the encoding and sorts parameters by name
+	QueryKeySorted QueryKeyMode = "sorted"
+	// QueryKeyAllowlist keeps only the listed parameters, sorted
+	QueryKeyAllowlist QueryKeyMode = "allowlist"
+	// QueryKeyNone keys on the path only
+	QueryKeyNone QueryKeyMode = "none"
+)
+
+// anonymousIdentity keys per-user entries for unauthenticated requests
+const anonymousIdentity = "anonymous"
+
+// CacheKeyPolicy selects the request components that identify a cached
+// response. The zero value keys on method and URL as sent.
+type CacheKeyPolicy struct {
+	Namespace   string       // Route the key belongs to, so routes never collide
+	Query       QueryKeyMode // Empty behaves as QueryKeyFull
+	QueryParams []string     // Parameters kept by QueryKeyAllowlist
+	Headers     []string     // Request headers whose values are part of the key
+	PerUser     bool         // Key on the authenticated caller
+	Identity    string       // Authenticated user or API key owner, when known
+}
+
+// cacheKey builds the key a request is cached under. Components are
+// separated by spaces, which cannot appear unescaped in a URL:
+//
+//	route:/api/v1/* GET:/api/v1/markets?a=1 header:Accept=application/json user:42
+func (p *Proxy) cacheKey(r *http.Request, policy CacheKeyPolicy) string {
+	if policy.Namespace == "" && policy.Query == "" && len(policy.Headers) == 0 && !policy.PerUser {
+		return p.generateCacheKey(r)
+	}
+
+	var b strings.Builder
+	if policy.Namespace != "" {
+		b.WriteString("route:")
+		b.WriteString(policy.Namespace)
+		b.WriteString(" ")
+	}
+
+	b.WriteString(r.Method)
+	b.WriteString(":")
+	b.WriteString(r.URL.EscapedPath())
+	if query := keyQuery(r.URL, policy); query != "" {
+		b.WriteString("?")
+		b.WriteString(query)
+	}
+
+	headers := make([]string, len(policy.Headers))
+	for i, name := range policy.Headers {
+		headers[i] = http.CanonicalHeaderKey(name)
+	}
+	sort.Strings(headers)
+	for _, name := range headers {
+		b.WriteString(" header:")
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(url.QueryEscape(strings.Join(r.Header.Values(name), ",")))
+	}
+
+	if policy.PerUser {
+		b.WriteString(" user:")
+		b.WriteString(url.QueryEscape(requestIdentity(r, policy.Identity)))
+	}
+
+	return b.String()
+}
+
+// keyQuery returns the query string component of a cache key
+func keyQuery(u *url.URL, policy CacheKeyPolicy) string {
+	switch policy.Query {
+	case QueryKeyNone:
+		return ""
+	case QueryKeySorted:
+		// Encode sorts by parameter name and normalizes escaping
+		return u.Query().Encode()
+	case QueryKeyAllowlist:
+		query := u.Query()
+		allowed := make(url.Values, len(policy.QueryParams))
+		for _, name := range policy.QueryParams {
+			if values, ok := query[name]; ok {
+				allowed[name] = values
+			}
+		}
+		return allowed.Encode()
+	default:
+		return u.RawQuery
+	}
+}
+
+// requestIdentity names the caller for per-user keys. Without an identity
+// from authentication, credentials are hashed so they never appear in keys.
+func requestIdentity(r *http.Request, identity string) string {
+	if identity != "" {
+		return identity
+	}
+	if credential := r.Header.Get("Authorization"); credential != "" {
+		return "credential:" + utils.HashString(credential)
+	}
+	if credential := r.Header.Get("X-API-Key"); credential != "" {
+		return "credential:" + utils.HashString(credential)
+	}
+	return anonymousIdentity
+}
+
+// parseCacheKey recovers a replayable request from a key built by cacheKey:
+// the route namespace, the path and query, and the headers the key depends
+// on. Keys for individual users and Vary variants cannot be replayed.
+func parseCacheKey(key string) (namespace, target string, header http.Header, ok bool) {
+	if strings.Contains(key, variantSeparator) {
+		return "", "", nil, false
+	}
+
+	header = make(http.Header)
+	for i, field := range strings.Split(key, " ") {
+		name, value, found := strings.Cut(field, ":")
+		if !found {
+			return "", "", nil, false
+		}
+
+		switch {
+		case i == 0 && name == "route":
+			namespace = value
+		case target == "" && name == http.MethodGet:
+			target = value
+		case target != "" && name == "header":
+			headerName, escaped, _ := strings.Cut(value, "=")
+			headerValue, err := url.QueryUnescape(escaped)
+			if err != nil {
+				return "", "", nil, false
+			}
+			if headerValue != "" {
+				header.Set(headerName, headerValue)
+			}
+		default:
+			return "", "", nil, false
+		}
+	}
+
+	return namespace, target, header, target != ""
+}
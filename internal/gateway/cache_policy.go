@@ -0,0 +1,284 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kalshi/internal/config"
+)
+
+// DefaultRouteCacheTTL is the cache TTL of routes that do not set one
+const DefaultRouteCacheTTL = 5 * time.Minute
+
+// CacheMode selects how cacheability and freshness are decided
+type CacheMode string
+
+const (
+	// CacheModeTTL caches every 200 GET response for the route TTL
+	CacheModeTTL CacheMode = "ttl"
+	// CacheModeHeaders lets the backend's Cache-Control and Expires headers
+	// decide, using the route TTL when the response gives no freshness
+	CacheModeHeaders CacheMode = "headers"
+	// CacheModeOverride applies the backend's cacheability rules but always
+	// uses the route TTL as the freshness lifetime
+	CacheModeOverride CacheMode = "override"
+)
+
+// CachePolicy controls how the proxy caches responses for a route
+type CachePolicy struct {
+	TTL  time.Duration
+	Mode CacheMode // Empty behaves as CacheModeTTL
+
+	// StaleWhileRevalidate serves expired entries while they are refreshed
+	// in the background; StaleIfError serves them while the backend fails
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+
+	// GenerateETag derives a strong ETag from the body of cacheable
+	// responses that arrive without one
+	GenerateETag bool
+
+	// Key selects the request components responses are cached under
+	Key CacheKeyPolicy
+
+	// NegativeTTL caches NegativeStatuses error responses briefly so repeated
+	// lookups of missing resources stop reaching the backend
+	NegativeTTL      time.Duration
+	NegativeStatuses []int // Defaults to 404 when NegativeTTL is set
+}
+
+// RouteCachePolicy builds the cache policy configured for a route. Keys are
+// namespaced by the route path; per-user routes still need the caller's
+// identity set on Key.
+func RouteCachePolicy(route *config.RouteConfig) CachePolicy {
+	ttl := route.CacheTTL
+	if ttl == 0 {
+		ttl = DefaultRouteCacheTTL
+	}
+
+	return CachePolicy{
+		TTL:                  ttl,
+		Mode:                 CacheMode(route.CacheMode),
+		StaleWhileRevalidate: route.StaleWhileRevalidate,
+		StaleIfError:         route.StaleIfError,
+		GenerateETag:         route.GenerateETag,
+		Key: CacheKeyPolicy{
+			Namespace:   route.Path,
+			Query:       QueryKeyMode(route.CacheKey.Query),
+			QueryParams: route.CacheKey.QueryParams,
+			Headers:     route.CacheKey.Headers,
+			PerUser:     route.CacheKey.User,
+		},
+		NegativeTTL:      route.NegativeCacheTTL,
+		NegativeStatuses: route.NegativeCacheStatuses,
+	}
+}
+
+// negative reports whether responses with status are negatively cached
+func (p CachePolicy) negative(status int) bool {
+	if p.NegativeTTL <= 0 {
+		return false
+	}
+	if len(p.NegativeStatuses) == 0 {
+		return status == http.StatusNotFound
+	}
+	for _, negative := range p.NegativeStatuses {
+		if status == negative {
+			return true
+		}
+	}
+	return false
+}
+
+// heuristicallyCacheable lists the status codes that may be cached without
+// explicit freshness information (RFC 9110 section 15.1)
+var heuristicallyCacheable = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// cacheControl holds the parsed directives of a Cache-Control header
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+			name, arg, _ := strings.Cut(directive, "=")
+			cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// seconds returns the value of a delta-seconds directive
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	value, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// usesHeaders reports whether the backend's caching headers are honoured
+func (p CachePolicy) usesHeaders() bool {
+	return p.Mode == CacheModeHeaders || p.Mode == CacheModeOverride
+}
+
+// bypassLookup reports whether the request asks not to be served from cache
+func (p CachePolicy) bypassLookup(r *http.Request) bool {
+	if !p.usesHeaders() {
+		return false
+	}
+	cc := parseCacheControl(r.Header)
+	return cc.has("no-cache") || cc.has("no-store") || r.Header.Get("Pragma") == "no-cache"
+}
+
+// hasCredentials reports whether the request identifies a user. Responses to
+// such requests are shared only when the backend explicitly allows it or
+// the cache key includes the caller.
+func hasCredentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get("X-API-Key") != ""
+}
+
+// storeTTL decides whether a response may be stored and for how long. A zero
+// duration means the response must not be cached. In every mode responses
+// marked no-store or private are never stored, and neither are responses to
+// authenticated requests unless they are shareable.
+func (p CachePolicy) storeTTL(r *http.Request, resp *http.Response) time.Duration {
+	if r.Method != http.MethodGet || p.TTL <= 0 {
+		return 0
+	}
+
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") || cc.has("private") {
+		return 0
+	}
+
+	// A shared cache may only store authenticated responses that are
+	// explicitly marked shareable (RFC 9111 section 3.5). Per-user keys
+	// keep each caller's responses apart.
+	if hasCredentials(r) && !p.Key.PerUser && !cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate") {
+		return 0
+	}
+
+	negative := p.negative(resp.StatusCode)
+
+	if !p.usesHeaders() {
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return p.TTL
+		case negative:
+			return p.NegativeTTL
+		}
+		return 0
+	}
+
+	if parseCacheControl(r.Header).has("no-store") || cc.has("no-cache") {
+		return 0
+	}
+
+	lifetime, explicit := freshnessLifetime(resp.Header, cc)
+	if !explicit && !negative && !cc.has("public") && !heuristicallyCacheable[resp.StatusCode] {
+		return 0
+	}
+
+	switch {
+	case negative && (p.Mode == CacheModeOverride || !explicit):
+		lifetime = p.NegativeTTL
+	case p.Mode == CacheModeOverride || !explicit:
+		lifetime = p.TTL
+	}
+
+	lifetime -= currentAge(resp.Header)
+	if lifetime <= 0 {
+		return 0
+	}
+	return lifetime
+}
+
+// staleWindows returns how long a response may be served stale. In the
+// header-driven modes the response's own directives take precedence.
+func (p CachePolicy) staleWindows(resp *http.Response) (staleWhileRevalidate, staleIfError time.Duration) {
+	// A negatively cached error is never worth serving past its short TTL
+	if p.negative(resp.StatusCode) {
+		return 0, 0
+	}
+
+	staleWhileRevalidate, staleIfError = p.StaleWhileRevalidate, p.StaleIfError
+	if !p.usesHeaders() {
+		return staleWhileRevalidate, staleIfError
+	}
+
+	cc := parseCacheControl(resp.Header)
+	if cc.has("must-revalidate") || cc.has("proxy-revalidate") {
+		return 0, 0
+	}
+	if window, ok := cc.seconds("stale-while-revalidate"); ok {
+		staleWhileRevalidate = window
+	}
+	if window, ok := cc.seconds("stale-if-error"); ok {
+		staleIfError = window
+	}
+	return staleWhileRevalidate, staleIfError
+}
+
+// freshnessLifetime computes the lifetime a shared cache assigns to a
+// response: s-maxage, then max-age, then Expires relative to Date
+func freshnessLifetime(header http.Header, cc cacheControl) (time.Duration, bool) {
+	if lifetime, ok := cc.seconds("s-maxage"); ok {
+		return lifetime, true
+	}
+	if lifetime, ok := cc.seconds("max-age"); ok {
+		return lifetime, true
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			// Invalid Expires values mean already expired
+			return 0, true
+		}
+
+		date := time.Now()
+		if parsed, err := http.ParseTime(header.Get("Date")); err == nil {
+			date = parsed
+		}
+		return expiresAt.Sub(date), true
+	}
+
+	return 0, false
+}
+
+// currentAge returns the age the upstream reported for a response
+func currentAge(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get("Age"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCoalesceMaxWaiters caps how many requests wait on one upstream fetch
+	DefaultCoalesceMaxWaiters = 1000
+	// DefaultCoalesceMaxWait bounds how long a request waits before fetching itself
+	DefaultCoalesceMaxWait = 5 * time.Second
+
+	// coalesceTimeout bounds a shared upstream fetch, which outlives the
+	// client of the request leading it
+	coalesceTimeout = 30 * time.Second
+)
+
+// upstreamResult is a fully read upstream response, or the error that
+// prevented one
+type upstreamResult struct {
+	statusCode  int
+	header      http.Header
+	body        []byte
+	err         error
+	revalidated bool // The backend confirmed a cached entry with a 304
+}
+
+// response exposes the result as an *http.Response for cache decisions
+func (u *upstreamResult) response() *http.Response {
+	return &http.Response{StatusCode: u.statusCode, Header: u.header}
+}
+
+// flight is an upstream fetch that concurrent requests for the same cache
+// key can wait on instead of fetching themselves
+type flight struct {
+	done    chan struct{}
+	req     *http.Request // The request that performs the fetch
+	result  *upstreamResult
+	waiters int // Guarded by coalescer.mu
+}
+
+// coalescer collapses concurrent cache misses for the same key into a
+// single upstream fetch
+type coalescer struct {
+	mu         sync.Mutex
+	flights    map[string]*flight
+	maxWaiters int
+	maxWait    time.Duration
+}
+
+func newCoalescer(maxWaiters int, maxWait time.Duration) *coalescer {
+	if maxWaiters <= 0 {
+		maxWaiters = DefaultCoalesceMaxWaiters
+	}
+	if maxWait <= 0 {
+		maxWait = DefaultCoalesceMaxWait
+	}
+
+	return &coalescer{
+		flights:    make(map[string]*flight),
+		maxWaiters: maxWaiters,
+		maxWait:    maxWait,
+	}
+}
+
+// join returns the flight for key and whether the caller leads it. A nil
+// flight means the waiter cap is reached and the caller should fetch alone.
+func (c *coalescer) join(key string, r *http.Request) (*flight, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f, exists := c.flights[key]; exists {
+		if f.waiters >= c.maxWaiters {
+			return nil, false
+		}
+		f.waiters++
+		return f, false
+	}
+
+	f := &flight{done: make(chan struct{}), req: r}
+	c.flights[key] = f
+	return f, true
+}
+
+// finish publishes the leader's result and releases every waiter
+func (c *coalescer) finish(key string, f *flight, result *upstreamResult) {
+	c.mu.Lock()
+	delete(c.flights, key)
+	c.mu.Unlock()
+
+	f.result = result
+	close(f.done)
+}
+
+// wait blocks until the flight completes, the wait cap expires or the
+// request is cancelled. ok is false when no result is available.
+func (c *coalescer) wait(ctx context.Context, f *flight) (*upstreamResult, bool) {
+	timer := time.NewTimer(c.maxWait)
+	defer timer.Stop()
+
+	select {
+	case <-f.done:
+		return f.result, true
+	case <-timer.C:
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// fetchResult fetches from the backend and reads the whole response. When
+// cached is set, a 304 is expanded into the cached response it confirms.
+func (p *Proxy) fetchResult(r *http.Request, backendName string, policy CachePolicy, cached *CachedResponse) *upstreamResult {
+	resp, err := p.fetch(r, backendName)
+	if err != nil {
+		return &upstreamResult{err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &upstreamResult{err: fmt.Errorf("failed to read response body: %w", err)}
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		return cached.refreshed(resp.Header)
+	}
+
+	if policy.GenerateETag && resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") == "" {
+		resp.Header.Set("ETag", generateETag(body))
+	}
+
+	return &upstreamResult{
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+		body:       body,
+	}
+}
+
+// fetchCoalesced fetches a cacheable request, sharing one upstream fetch
+// among concurrent requests for the same cache key. cached is a stale entry
+// to revalidate, if any. leader reports whether this request performed the
+// fetch itself.
+func (p *Proxy) fetchCoalesced(r *http.Request, backendName string, policy CachePolicy, cached *CachedResponse) (result *upstreamResult, leader bool) {
+	req := upstreamRequest(r, cached)
+	if p.coalescer == nil {
+		return p.fetchResult(req, backendName, policy, cached), true
+	}
+
+	cacheKey := p.cacheKey(r, policy.Key)
+	for {
+		f, leader := p.coalescer.join(cacheKey, r)
+		if f == nil {
+			return p.fetchResult(req, backendName, policy, cached), true
+		}
+
+		if leader {
+			// Waiters depend on the fetch, so the leader's client going away
+			// must not cancel it
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), coalesceTimeout)
+			result := p.fetchResult(upstreamRequest(r.WithContext(ctx), cached), backendName, policy, cached)
+			cancel()
+			p.coalescer.finish(cacheKey, f, result)
+			return result, true
+		}
+
+		result, ok := p.coalescer.wait(r.Context(), f)
+		if ok && interrupted(result) {
+			// The leader's fetch was cut short rather than failed by the
+			// backend; wait on another fetch, or lead one
+			continue
+		}
+		if ok && canShare(f.req, r, result, policy) {
+			return result, false
+		}
+		return p.fetchResult(req, backendName, policy, cached), true
+	}
+}
+
+// interrupted reports whether a fetch ended by cancellation or timeout
+// rather than with the backend's answer
+func interrupted(result *upstreamResult) bool {
+	return errors.Is(result.err, context.Canceled) || errors.Is(result.err, context.DeadlineExceeded)
+}
+
+// canShare reports whether a result fetched for leader may be returned to
+// another request. Backend errors are shared so a failing backend is not
+// hit once per waiter, but not cancellations of the leader's fetch;
+// responses only when the cache could have served them.
+func canShare(leader, r *http.Request, result *upstreamResult, policy CachePolicy) bool {
+	if result.err != nil {
+		return !interrupted(result)
+	}
+
+	resp := result.response()
+	if policy.storeTTL(r, resp) <= 0 {
+		return false
+	}
+
+	vary, ok := varyHeaders(resp.Header)
+	if !ok {
+		return false
+	}
+	return variantKey("", vary, leader) == variantKey("", vary, r)
+}
@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"kalshi/pkg/utils"
+)
+
+// CacheStatusRevalidated reports a stale entry the backend confirmed unchanged
+const CacheStatusRevalidated = "REVALIDATED"
+
+// conditionalHeaders are the request validators a cache answers itself
+// rather than forwarding (RFC 9111 section 4.3.2)
+var conditionalHeaders = []string{"If-None-Match", "If-Modified-Since"}
+
+// notModifiedHeaders are the response headers a 304 must repeat from the
+// full response (RFC 9110 section 15.4.5)
+var notModifiedHeaders = []string{
+	"Cache-Control",
+	"Content-Location",
+	"Date",
+	"ETag",
+	"Expires",
+	"Last-Modified",
+	"Vary",
+}
+
+// hasValidators reports whether the response can be revalidated upstream
+func (c *CachedResponse) hasValidators() bool {
+	return c.Headers.Get("ETag") != "" || c.Headers.Get("Last-Modified") != ""
+}
+
+// notModified evaluates the request's preconditions against a response.
+// If-None-Match takes precedence over If-Modified-Since (RFC 9110 section 13.2.2).
+func notModified(r *http.Request, statusCode int, header http.Header) bool {
+	if statusCode != http.StatusOK || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := header.Get("ETag")
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakETagMatch(candidate, etag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(since)
+}
+
+// weakETagMatch compares entity tags ignoring the weak indicator
+func weakETagMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// writeNotModified answers a conditional request with 304 Not Modified
+func writeNotModified(w http.ResponseWriter, header http.Header) {
+	for _, key := range notModifiedHeaders {
+		for _, value := range header.Values(key) {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// upstreamRequest prepares a cacheable request for the backend. The client's
+// own validators are dropped because the gateway answers them from the full
+// response; a cached entry's validators are sent instead so an unchanged
+// resource costs a 304 rather than a full body.
+func upstreamRequest(r *http.Request, cached *CachedResponse) *http.Request {
+	req := r.Clone(r.Context())
+	for _, key := range conditionalHeaders {
+		req.Header.Del(key)
+	}
+
+	if cached == nil {
+		return req
+	}
+	if etag := cached.Headers.Get("ETag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := cached.Headers.Get("Last-Modified"); lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+	return req
+}
+
+// refreshed turns a 304 from the backend into the cached response it
+// confirms, updated with the headers the 304 carried (RFC 9111 section 4.3.4)
+func (c *CachedResponse) refreshed(header http.Header) *upstreamResult {
+	merged := filterCacheHeaders(c.Headers)
+	for key, values := range filterCacheHeaders(header) {
+		if http.CanonicalHeaderKey(key) == "Content-Length" {
+			continue
+		}
+		merged[key] = values
+	}
+
+	return &upstreamResult{
+		statusCode:  c.StatusCode,
+		header:      merged,
+		body:        c.Body,
+		revalidated: true,
+	}
+}
+
+// generateETag returns a strong ETag derived from the body
+func generateETag(body []byte) string {
+	return `"` + utils.HashBytes(body) + `"`
+}
@@ -1,11 +1,10 @@
 package gateway
 
 import (
-	"time"
-
 	"kalshi/internal/cache"
 	"kalshi/internal/circuit"
 	"kalshi/internal/config"
+	"kalshi/internal/discovery"
 	"kalshi/pkg/logger"
 )
 
@@ -36,24 +35,70 @@ func New(cfg *config.Config, cacheManager cache.Cache, logger *logger.Logger) *G
 	// Initialize backends
 	gateway.initializeBackends()
 
+	// Start service discovery for dynamic backends
+	gateway.initializeDiscovery()
+
 	// Start health checks
-	gateway.backendManager.StartHealthChecks(30 * time.Second)
+	gateway.backendManager.StartHealthChecks(DefaultHealthCheckInterval)
 
 	return gateway
 }
 
 func (g *Gateway) initializeBackends() {
 	for _, backend := range g.config.Backend {
-		err := g.backendManager.AddBackend(
+		healthOpts := HealthCheckOptionsFromConfig(backend.Health)
+
+		if backend.Discovery.Enabled() {
+			g.backendManager.AddPool(backend.Name, backend.HealthCheck, backend.Weight, healthOpts)
+			continue
+		}
+
+		err := g.backendManager.AddBackendWithOptions(
 			backend.Name,
 			backend.URL,
 			backend.HealthCheck,
 			backend.Weight,
+			healthOpts,
 		)
 		if err != nil {
 			g.logger.Error("Failed to add backend", "backend", backend.Name, "error", err)
 		}
 	}
+
+	for _, backend := range g.config.Backend {
+		if !backend.Outlier.Enabled() {
+			continue
+		}
+		if err := g.backendManager.SetOutlierOptions(backend.Name, OutlierOptionsFromConfig(backend.Outlier)); err != nil {
+			g.logger.Error("Failed to configure outlier detection", "backend", backend.Name, "error", err)
+		}
+	}
+}
+
+func (g *Gateway) initializeDiscovery() {
+	for _, backend := range g.config.Backend {
+		if !backend.Discovery.Enabled() {
+			continue
+		}
+
+		provider, err := discovery.NewProvider(backend.Discovery, g.config.Cache.Redis)
+		if err != nil {
+			g.logger.Error("Failed to create discovery provider", "backend", backend.Name, "error", err)
+			continue
+		}
+
+		g.backendManager.StartDiscovery(
+			backend.Name,
+			provider,
+			discovery.RefreshInterval(backend.Discovery),
+			g.logger,
+		)
+	}
+}
+
+// Close stops service discovery and releases the providers' connections
+func (g *Gateway) Close() {
+	g.backendManager.StopDiscovery()
 }
 
 func (g *Gateway) GetProxy() *Proxy {
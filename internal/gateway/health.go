@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"kalshi/internal/config"
+	"kalshi/pkg/utils"
+)
+
+const (
+	// DefaultHealthCheckInterval is used when neither the backend nor
+	// StartHealthChecks provide an interval
+	DefaultHealthCheckInterval = 30 * time.Second
+	// DefaultHealthCheckTimeout bounds a single probe
+	DefaultHealthCheckTimeout = 5 * time.Second
+
+	// maxProbeBodySize caps how much of a probe response is read when the
+	// body has to be inspected
+	maxProbeBodySize = 64 * 1024
+)
+
+// HealthCheckOptions controls how a backend is actively probed
+type HealthCheckOptions struct {
+	Interval           time.Duration // 0 uses the interval passed to StartHealthChecks
+	Timeout            time.Duration
+	HealthyThreshold   int
+	UnhealthyThreshold int
+	ExpectedStatuses   []int // Empty accepts any 2xx
+	ExpectedBody       string
+	ExpectedJSONPath   string
+	ExpectedJSONValue  string
+	Method             string
+	Headers            map[string]string
+}
+
+// DefaultHealthCheckOptions returns options that flip health on a single
+// probe result and accept any 2xx response to a GET
+func DefaultHealthCheckOptions() HealthCheckOptions {
+	return HealthCheckOptions{
+		Timeout:            DefaultHealthCheckTimeout,
+		HealthyThreshold:   1,
+		UnhealthyThreshold: 1,
+		Method:             http.MethodGet,
+	}
+}
+
+// HealthCheckOptionsFromConfig merges backend health settings over the defaults
+func HealthCheckOptionsFromConfig(cfg config.HealthCheckConfig) HealthCheckOptions {
+	opts := DefaultHealthCheckOptions()
+
+	opts.Interval = cfg.Interval
+	if cfg.Timeout > 0 {
+		opts.Timeout = cfg.Timeout
+	}
+	if cfg.HealthyThreshold > 0 {
+		opts.HealthyThreshold = cfg.HealthyThreshold
+	}
+	if cfg.UnhealthyThreshold > 0 {
+		opts.UnhealthyThreshold = cfg.UnhealthyThreshold
+	}
+	if cfg.Method != "" {
+		opts.Method = strings.ToUpper(cfg.Method)
+	}
+
+	opts.ExpectedStatuses = cfg.ExpectedStatuses
+	opts.ExpectedBody = cfg.ExpectedBody
+	opts.ExpectedJSONPath = cfg.ExpectedJSONPath
+	opts.ExpectedJSONValue = cfg.ExpectedJSONValue
+	opts.Headers = cfg.Headers
+
+	return opts
+}
+
+// probeResult describes the outcome of a single health probe
+type probeResult struct {
+	healthy bool
+	latency time.Duration
+	reason  string // Why the probe failed; empty on success
+}
+
+// newProbeClient creates the HTTP client shared by every health probe.
+// Per-probe deadlines come from the request context.
+func newProbeClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     90 * time.Second,
+			DisableCompression:  true,
+			ForceAttemptHTTP2:   true,
+			DialContext: (&net.Dialer{
+				Timeout:   3 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+		},
+	}
+}
+
+// applyHeaders sets the configured probe headers, treating Host specially
+func (o HealthCheckOptions) applyHeaders(req *http.Request) {
+	for key, value := range o.Headers {
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+}
+
+// evaluate checks a probe response against the configured expectations and
+// returns an empty reason when it passes
+func (o HealthCheckOptions) evaluate(resp *http.Response) string {
+	if !o.statusExpected(resp.StatusCode) {
+		return fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+
+	if o.ExpectedBody == "" && o.ExpectedJSONPath == "" {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+	if err != nil {
+		return fmt.Sprintf("failed to read body: %v", err)
+	}
+
+	if o.ExpectedBody != "" && !strings.Contains(string(body), o.ExpectedBody) {
+		return fmt.Sprintf("body does not contain %q", o.ExpectedBody)
+	}
+
+	if o.ExpectedJSONPath != "" {
+		value, err := utils.ExtractJSONField(string(body), o.ExpectedJSONPath)
+		if err != nil {
+			return fmt.Sprintf("json path %s: %v", o.ExpectedJSONPath, err)
+		}
+		if o.ExpectedJSONValue != "" && utils.ToString(value) != o.ExpectedJSONValue {
+			return fmt.Sprintf("json path %s is %q, expected %q", o.ExpectedJSONPath, utils.ToString(value), o.ExpectedJSONValue)
+		}
+	}
+
+	return ""
+}
+
+func (o HealthCheckOptions) statusExpected(status int) bool {
+	if len(o.ExpectedStatuses) == 0 {
+		return status >= 200 && status < 300
+	}
+	for _, expected := range o.ExpectedStatuses {
+		if status == expected {
+			return true
+		}
+	}
+	return false
+}
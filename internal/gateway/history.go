@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"time"
+)
+
+const (
+	// DefaultHealthHistorySize is the number of transitions kept per backend
+	DefaultHealthHistorySize = 64
+	// DefaultFlapWindow is the window in which transitions are counted
+	DefaultFlapWindow = 5 * time.Minute
+	// DefaultFlapThreshold is the number of transitions within the window
+	// at which a backend is considered flapping
+	DefaultFlapThreshold = 4
+)
+
+// Health transition sources
+const (
+	TransitionSourceProbe  = "probe"
+	TransitionSourceManual = "manual"
+)
+
+// HealthTransition records a change of a backend's health status
+type HealthTransition struct {
+	Time    time.Time     `json:"time"`
+	Healthy bool          `json:"healthy"`
+	Source  string        `json:"source"`            // What caused the transition: probe or manual
+	Latency time.Duration `json:"latency,omitempty"` // Latency of the probe that caused it
+	Reason  string        `json:"reason,omitempty"`  // Why the probe failed
+}
+
+// FlapStatus summarizes how often a backend changed health recently
+type FlapStatus struct {
+	Flapping    bool          `json:"flapping"`
+	Transitions int           `json:"transitions"`
+	Window      time.Duration `json:"window"`
+	Threshold   int           `json:"threshold"`
+}
+
+// healthHistory is a fixed-size ring buffer of health transitions
+type healthHistory struct {
+	entries []HealthTransition
+	next    int
+	full    bool
+}
+
+func (h *healthHistory) add(transition HealthTransition) {
+	if h.entries == nil {
+		h.entries = make([]HealthTransition, DefaultHealthHistorySize)
+	}
+
+	h.entries[h.next] = transition
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns the transitions oldest first
+func (h *healthHistory) list() []HealthTransition {
+	if !h.full {
+		transitions := make([]HealthTransition, h.next)
+		copy(transitions, h.entries[:h.next])
+		return transitions
+	}
+
+	transitions := make([]HealthTransition, 0, len(h.entries))
+	transitions = append(transitions, h.entries[h.next:]...)
+	transitions = append(transitions, h.entries[:h.next]...)
+	return transitions
+}
+
+// recordTransitionLocked sets the health status and records the change in
+// the history. The caller must hold b.mu.
+func (b *Backend) recordTransitionLocked(healthy bool, source string, result probeResult) {
+	if b.IsHealthy == healthy {
+		return
+	}
+
+	b.IsHealthy = healthy
+	b.history.add(HealthTransition{
+		Time:    time.Now(),
+		Healthy: healthy,
+		Source:  source,
+		Latency: result.latency,
+		Reason:  result.reason,
+	})
+}
+
+// History returns the recorded health transitions, oldest first
+func (b *Backend) History() []HealthTransition {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.history.list()
+}
+
+// FlapStatus reports whether the backend changed health at least threshold
+// times within the window
+func (b *Backend) FlapStatus(window time.Duration, threshold int) FlapStatus {
+	if window <= 0 {
+		window = DefaultFlapWindow
+	}
+	if threshold <= 0 {
+		threshold = DefaultFlapThreshold
+	}
+
+	cutoff := time.Now().Add(-window)
+	transitions := 0
+	for _, transition := range b.History() {
+		if transition.Time.After(cutoff) {
+			transitions++
+		}
+	}
+
+	return FlapStatus{
+		Flapping:    transitions >= threshold,
+		Transitions: transitions,
+		Window:      window,
+		Threshold:   threshold,
+	}
+}
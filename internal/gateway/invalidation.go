@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"kalshi/internal/cache"
+)
+
+// ErrInvalidationUnsupported is returned when the cache cannot bulk delete
+var ErrInvalidationUnsupported = errors.New("cache does not support invalidation")
+
+// variantSeparator joins a base cache key and the request headers that
+// select a variant (see variantKey)
+const variantSeparator = "|"
+
+// PurgeRequest selects cached responses to invalidate. Keys remove a cache
+// key and all of its Vary variants; prefixes match raw cache keys, which
+// have the form "route:/api/* GET:/path?query" for routed requests (see
+// cacheKey); tags match Surrogate-Key values.
+type PurgeRequest struct {
+	Keys     []string `json:"keys,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	All      bool     `json:"all,omitempty"`
+}
+
+// Empty reports whether the request selects nothing
+func (r PurgeRequest) Empty() bool {
+	return !r.All && len(r.Keys) == 0 && len(r.Prefixes) == 0 && len(r.Tags) == 0
+}
+
+// surrogateKeys returns the space separated tags a backend attached to a
+// response with the Surrogate-Key header
+func surrogateKeys(header http.Header) []string {
+	var tags []string
+	for _, value := range header.Values("Surrogate-Key") {
+		tags = append(tags, strings.Fields(value)...)
+	}
+	return tags
+}
+
+// tagCachedKeys records the response's surrogate keys against the cache keys
+// it was stored under
+func (p *Proxy) tagCachedKeys(ctx context.Context, cached *CachedResponse, ttl time.Duration, keys ...string) error {
+	tags := surrogateKeys(cached.Headers)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	invalidator, ok := p.cacheManager.(cache.Invalidator)
+	if !ok {
+		return nil
+	}
+
+	for _, key := range keys {
+		if err := invalidator.Tag(ctx, key, tags, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Purge invalidates the cached responses selected by req and returns how
+// many cache entries were removed
+func (p *Proxy) Purge(ctx context.Context, req PurgeRequest) (int, error) {
+	invalidator, ok := p.cacheManager.(cache.Invalidator)
+
+	if req.All {
+		if !ok {
+			return 0, ErrInvalidationUnsupported
+		}
+		return 0, invalidator.Clear(ctx)
+	}
+
+	if !ok && (len(req.Prefixes) > 0 || len(req.Tags) > 0) {
+		return 0, ErrInvalidationUnsupported
+	}
+
+	purged := 0
+	for _, key := range req.Keys {
+		if exists, err := p.cacheManager.Exists(ctx, key); err == nil && exists {
+			purged++
+		}
+		if err := p.cacheManager.Delete(ctx, key); err != nil {
+			return purged, err
+		}
+
+		if ok {
+			n, err := invalidator.DeletePrefix(ctx, key+variantSeparator)
+			if err != nil {
+				return purged, err
+			}
+			purged += n
+		}
+	}
+
+	for _, prefix := range req.Prefixes {
+		n, err := invalidator.DeletePrefix(ctx, prefix)
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+
+	if len(req.Tags) > 0 {
+		keys, err := invalidator.PurgeTags(ctx, req.Tags...)
+		if err != nil {
+			return purged, err
+		}
+		purged += len(keys)
+	}
+
+	return purged, nil
+}
+
+// CacheKeys lists cached keys starting with prefix
+func (p *Proxy) CacheKeys(ctx context.Context, prefix string) ([]string, error) {
+	invalidator, ok := p.cacheManager.(cache.Invalidator)
+	if !ok {
+		return nil, ErrInvalidationUnsupported
+	}
+	return invalidator.Keys(ctx, prefix)
+}
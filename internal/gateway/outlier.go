@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+
+	"kalshi/internal/config"
+)
+
+const (
+	// DefaultBaseEjectionTime is the length of a first ejection
+	DefaultBaseEjectionTime = 30 * time.Second
+	// DefaultMaxEjectionTime caps exponentially growing ejections
+	DefaultMaxEjectionTime = 5 * time.Minute
+	// DefaultMaxEjectionPercent is the share of a pool that may be ejected at once
+	DefaultMaxEjectionPercent = 50
+)
+
+// OutlierOptions controls passive health checking based on live traffic.
+// Detection is disabled when ConsecutiveErrors is zero.
+type OutlierOptions struct {
+	ConsecutiveErrors  int
+	BaseEjectionTime   time.Duration
+	MaxEjectionTime    time.Duration
+	MaxEjectionPercent int
+}
+
+// OutlierOptionsFromConfig merges outlier detection settings over the defaults
+func OutlierOptionsFromConfig(cfg config.OutlierConfig) OutlierOptions {
+	opts := OutlierOptions{
+		ConsecutiveErrors:  cfg.ConsecutiveErrors,
+		BaseEjectionTime:   DefaultBaseEjectionTime,
+		MaxEjectionTime:    DefaultMaxEjectionTime,
+		MaxEjectionPercent: DefaultMaxEjectionPercent,
+	}
+
+	if cfg.BaseEjectionTime > 0 {
+		opts.BaseEjectionTime = cfg.BaseEjectionTime
+	}
+	if cfg.MaxEjectionTime > 0 {
+		opts.MaxEjectionTime = cfg.MaxEjectionTime
+	}
+	if cfg.MaxEjectionPercent > 0 {
+		opts.MaxEjectionPercent = cfg.MaxEjectionPercent
+	}
+
+	return opts
+}
+
+// ejectionDuration doubles the base ejection time for every previous
+// ejection, capped at the maximum
+func (o OutlierOptions) ejectionDuration(previousEjections int) time.Duration {
+	duration := o.BaseEjectionTime
+	for i := 0; i < previousEjections && duration < o.MaxEjectionTime; i++ {
+		duration *= 2
+	}
+	if o.MaxEjectionTime > 0 && duration > o.MaxEjectionTime {
+		duration = o.MaxEjectionTime
+	}
+	return duration
+}
+
+// Ejected reports whether the backend is currently ejected by outlier detection
+func (b *Backend) Ejected() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return time.Now().Before(b.ejectedUntil)
+}
+
+// EjectedUntil returns when the current ejection ends; zero if never ejected
+func (b *Backend) EjectedUntil() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.ejectedUntil
+}
+
+// Available reports whether the backend may receive traffic: it passes
+// active health checks and is not ejected
+func (b *Backend) Available() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.IsHealthy && !time.Now().Before(b.ejectedUntil)
+}
+
+// SetOutlierOptions configures passive health checking for a backend. For a
+// pool the options apply to current and future instances.
+func (bm *BackendManager) SetOutlierOptions(name string, opts OutlierOptions) error {
+	bm.mu.RLock()
+	pool, isPool := bm.pools[name]
+	bm.mu.RUnlock()
+
+	if isPool {
+		pool.mu.Lock()
+		pool.outlierOpts = opts
+		members := make([]*Backend, len(pool.members))
+		copy(members, pool.members)
+		pool.mu.Unlock()
+
+		for _, member := range members {
+			member.mu.Lock()
+			member.outlierOpts = opts
+			member.mu.Unlock()
+		}
+		return nil
+	}
+
+	backend, err := bm.GetBackendByName(name)
+	if err != nil {
+		return err
+	}
+
+	backend.mu.Lock()
+	backend.outlierOpts = opts
+	backend.mu.Unlock()
+	return nil
+}
+
+// ReportResult records the outcome of a live request to a backend. A
+// connection failure or 5xx response counts as an error; once the configured
+// number of consecutive errors is reached the backend is ejected, unless that
+// would eject more than the allowed share of its pool. One member of any
+// pool may always be ejected.
+func (bm *BackendManager) ReportResult(backend *Backend, statusCode int, err error) {
+	failed := err != nil || statusCode >= http.StatusInternalServerError
+
+	backend.mu.Lock()
+	opts := backend.outlierOpts
+	if opts.ConsecutiveErrors <= 0 {
+		backend.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	if !failed {
+		backend.consecutiveErrors = 0
+		// Forgive earlier offences once the backend has stayed in service
+		// for a full maximum ejection period
+		if backend.ejections > 0 && now.Sub(backend.ejectedUntil) >= opts.MaxEjectionTime {
+			backend.ejections = 0
+		}
+		backend.mu.Unlock()
+		return
+	}
+
+	backend.consecutiveErrors++
+	due := backend.consecutiveErrors >= opts.ConsecutiveErrors && !now.Before(backend.ejectedUntil)
+	backend.mu.Unlock()
+
+	if due {
+		bm.tryEject(backend, opts, now)
+	}
+}
+
+// tryEject ejects the backend if the pool's ejection cap allows it
+func (bm *BackendManager) tryEject(backend *Backend, opts OutlierOptions, now time.Time) {
+	// Serialize ejection decisions so concurrent failures cannot exceed the cap
+	bm.ejectMu.Lock()
+	defer bm.ejectMu.Unlock()
+
+	peers := []*Backend{backend}
+	if backend.Pool != "" {
+		bm.mu.RLock()
+		if pool, exists := bm.pools[backend.Pool]; exists {
+			peers = pool.snapshot()
+		}
+		bm.mu.RUnlock()
+	}
+
+	ejected := 0
+	for _, peer := range peers {
+		if peer != backend && peer.Ejected() {
+			ejected++
+		}
+	}
+
+	// The cap rounds down, but one ejection is always allowed so that small
+	// pools and standalone backends are ejected at the default percentage
+	allowed := max(len(peers)*opts.MaxEjectionPercent/100, 1)
+	if ejected+1 > allowed {
+		return
+	}
+
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+
+	if now.Before(backend.ejectedUntil) {
+		return
+	}
+	backend.ejectedUntil = now.Add(opts.ejectionDuration(backend.ejections))
+	backend.ejections++
+	backend.consecutiveErrors = 0
+}
+
+// ClearEjection returns an ejected backend to service immediately
+func (bm *BackendManager) ClearEjection(name string) error {
+	backend, err := bm.GetBackendByName(name)
+	if err != nil {
+		return err
+	}
+
+	backend.mu.Lock()
+	backend.ejectedUntil = time.Time{}
+	backend.consecutiveErrors = 0
+	backend.mu.Unlock()
+	return nil
+}
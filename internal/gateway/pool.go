@@ -0,0 +1,257 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"kalshi/internal/discovery"
+	"kalshi/pkg/logger"
+)
+
+// backendPool holds the discovered instances of a logical backend and
+// balances requests across the available ones with smooth weighted round-robin
+type backendPool struct {
+	name        string
+	healthCheck string
+	weight      int // Default weight for endpoints that do not report one
+	healthOpts  HealthCheckOptions
+	outlierOpts OutlierOptions
+
+	mu      sync.Mutex
+	members []*Backend
+	current map[string]int // Smooth weighted round-robin state, keyed by member name
+}
+
+func newBackendPool(name, healthCheck string, weight int, opts HealthCheckOptions) *backendPool {
+	return &backendPool{
+		name:        name,
+		healthCheck: healthCheck,
+		weight:      weight,
+		healthOpts:  opts,
+		current:     make(map[string]int),
+	}
+}
+
+// balancingWeight returns the member's weight, at least one. SyncPool updates
+// the weight of members that are rediscovered, so it is read under the lock.
+func (b *Backend) balancingWeight() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return max(b.Weight, 1)
+}
+
+// next picks the available member with the highest current weight
+func (p *backendPool) next() *Backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var selected *Backend
+	total := 0
+	for _, member := range p.members {
+		if !member.Available() {
+			continue
+		}
+
+		weight := member.balancingWeight()
+		p.current[member.Name] += weight
+		total += weight
+
+		if selected == nil || p.current[member.Name] > p.current[selected.Name] {
+			selected = member
+		}
+	}
+
+	if selected != nil {
+		p.current[selected.Name] -= total
+	}
+	return selected
+}
+
+func (p *backendPool) setMembers(members []*Backend) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := make(map[string]int, len(members))
+	for _, member := range members {
+		current[member.Name] = p.current[member.Name]
+	}
+
+	p.members = members
+	p.current = current
+}
+
+func (p *backendPool) snapshot() []*Backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	members := make([]*Backend, len(p.members))
+	copy(members, p.members)
+	return members
+}
+
+// poolMemberName derives a stable, unique backend name for an instance
+func poolMemberName(pool string, u *url.URL) string {
+	return pool + "@" + u.Host
+}
+
+// AddPool registers a logical backend whose instances are supplied by
+// service discovery. Until SyncPool is called the pool has no instances.
+// Every instance is probed with the given health check options.
+func (bm *BackendManager) AddPool(name, healthCheck string, weight int, opts HealthCheckOptions) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	if _, exists := bm.pools[name]; exists {
+		return
+	}
+	bm.pools[name] = newBackendPool(name, healthCheck, weight, opts)
+}
+
+// SyncPool reconciles a pool's instances with the discovered endpoints.
+// Instances that are still present keep their health state; new ones start
+// healthy and are picked up by the next health check round. Invalid
+// endpoints are skipped and reported in the returned error while the valid
+// ones are still applied.
+func (bm *BackendManager) SyncPool(name string, endpoints []discovery.Endpoint) error {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	pool, exists := bm.pools[name]
+	if !exists {
+		return fmt.Errorf("backend pool %s not found", name)
+	}
+
+	var errs []error
+	desired := make(map[string]*Backend, len(endpoints))
+	for _, endpoint := range endpoints {
+		parsedURL, err := url.Parse(endpoint.URL)
+		if err != nil || parsedURL.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid endpoint %q", endpoint.URL))
+			continue
+		}
+
+		weight := endpoint.Weight
+		if weight <= 0 {
+			weight = pool.weight
+		}
+
+		memberName := poolMemberName(name, parsedURL)
+		if existing, ok := bm.backends[memberName]; ok && existing.Pool == name {
+			existing.mu.Lock()
+			existing.Weight = weight
+			existing.mu.Unlock()
+			desired[memberName] = existing
+			continue
+		}
+
+		desired[memberName] = &Backend{
+			Name:        memberName,
+			URL:         parsedURL,
+			HealthCheck: pool.healthCheck,
+			Weight:      weight,
+			IsHealthy:   true,
+			Pool:        name,
+			healthOpts:  pool.healthOpts,
+			outlierOpts: pool.outlierOpts,
+		}
+	}
+
+	for _, member := range pool.snapshot() {
+		if _, keep := desired[member.Name]; !keep {
+			delete(bm.backends, member.Name)
+		}
+	}
+
+	members := make([]*Backend, 0, len(desired))
+	for memberName, backend := range desired {
+		bm.backends[memberName] = backend
+		members = append(members, backend)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	pool.setMembers(members)
+
+	return errors.Join(errs...)
+}
+
+// GetPoolMembers returns every instance (healthy and unhealthy) of a pool
+func (bm *BackendManager) GetPoolMembers(name string) ([]*Backend, error) {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+
+	pool, exists := bm.pools[name]
+	if !exists {
+		return nil, fmt.Errorf("backend pool %s not found", name)
+	}
+	return pool.snapshot(), nil
+}
+
+// StartDiscovery resolves the pool once synchronously so it is populated
+// before traffic arrives, then keeps re-resolving it every interval until
+// StopDiscovery. When the provider fails the previous membership is kept.
+// Providers that hold connections are closed when discovery stops.
+func (bm *BackendManager) StartDiscovery(name string, provider discovery.Provider, interval time.Duration, log *logger.Logger) {
+	refresh := func() {
+		timeout := interval
+		if timeout > 5*time.Second {
+			timeout = 5 * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		endpoints, err := provider.Discover(ctx)
+		if err != nil {
+			if log != nil {
+				log.Warn("Backend discovery failed, keeping previous instances",
+					"backend", name,
+					"provider", provider.Name(),
+					"error", err,
+				)
+			}
+			return
+		}
+
+		if err := bm.SyncPool(name, endpoints); err != nil && log != nil {
+			log.Warn("Skipped invalid discovered endpoints", "backend", name, "error", err)
+		}
+	}
+
+	refresh()
+
+	ticker := time.NewTicker(interval)
+	bm.discoveries.Add(1)
+	go func() {
+		defer bm.discoveries.Done()
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				refresh()
+			case <-bm.stopDiscovery:
+				if closer, ok := provider.(io.Closer); ok {
+					if err := closer.Close(); err != nil && log != nil {
+						log.Warn("Failed to close discovery provider", "backend", name, "error", err)
+					}
+				}
+				return
+			}
+		}
+	}()
+}
+
+// StopDiscovery stops every discovery loop and waits for them to exit.
+// Pools keep their last membership. It is safe to call more than once.
+func (bm *BackendManager) StopDiscovery() {
+	bm.stopOnce.Do(func() {
+		close(bm.stopDiscovery)
+	})
+	bm.discoveries.Wait()
+}
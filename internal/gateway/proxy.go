@@ -1,11 +1,13 @@
 package gateway
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
@@ -15,12 +17,6 @@ import (
 	"kalshi/pkg/logger"
 )
 
-type CachedResponse struct {
-	StatusCode int
-	Headers    http.Header
-	Body       []byte
-}
-
 type Proxy struct {
 	backendManager *BackendManager
 	cacheManager   cache.Cache
@@ -30,16 +26,24 @@ type Proxy struct {
 	optimizedClient *http.Client
 	clientOnce      sync.Once
 	config          *config.Config
+	revalidating    sync.Map   // Cache keys with a background refresh in flight
+	coalescer       *coalescer // Collapses concurrent cache misses; nil when disabled
 }
 
 func NewProxy(bm *BackendManager, cm cache.Cache, circuitManager *circuit.Manager, logger *logger.Logger, cfg *config.Config) *Proxy {
-	return &Proxy{
+	proxy := &Proxy{
 		backendManager: bm,
 		cacheManager:   cm,
 		circuitManager: circuitManager,
 		logger:         logger,
 		config:         cfg,
 	}
+
+	if cfg != nil && cfg.Cache.Coalescing.Enabled {
+		proxy.coalescer = newCoalescer(cfg.Cache.Coalescing.MaxWaiters, cfg.Cache.Coalescing.MaxWait)
+	}
+
+	return proxy
 }
 
 // getOptimizedClient creates and returns an optimized HTTP client with connection pooling
@@ -96,20 +100,109 @@ func (p *Proxy) getOptimizedClient() *http.Client {
 	return p.optimizedClient
 }
 
+// ServeHTTP proxies a request, caching 200 GET responses for cacheTTL
 func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request, backendName string, cacheTTL time.Duration) {
+	p.ServeHTTPWithPolicy(w, r, backendName, CachePolicy{TTL: cacheTTL})
+}
+
+// ServeHTTPWithPolicy proxies a request, caching responses according to policy
+func (p *Proxy) ServeHTTPWithPolicy(w http.ResponseWriter, r *http.Request, backendName string, policy CachePolicy) {
+	cacheable := r.Method == "GET" && policy.TTL > 0
+	cacheKey := p.cacheKey(r, policy.Key)
+
 	// Check cache first for GET requests
-	if r.Method == "GET" && cacheTTL > 0 {
-		if cached, err := p.getCachedResponse(r); err == nil {
-			p.writeCachedResponse(w, cached)
+	var stale, revalidating *CachedResponse
+	if cacheable && !policy.bypassLookup(r) {
+		if recorder, ok := p.cacheManager.(lookupRecorder); ok {
+			recorder.RecordLookup(cacheKey)
+		}
+		if cached, err := p.lookupCachedResponse(r.Context(), cacheKey, r); err == nil {
+			now := time.Now()
+			switch {
+			case cached.fresh(now):
+				p.serveCached(w, r, cached, CacheStatusHit)
+				return
+			case cached.staleFor(now) <= cached.StaleWhileRevalidate:
+				p.revalidate(r, backendName, policy, cached)
+				p.serveCached(w, r, cached, CacheStatusStale)
+				return
+			}
+
+			if cached.staleFor(now) <= cached.StaleIfError {
+				stale = cached
+			}
+			if cached.hasValidators() {
+				revalidating = cached
+			}
+		}
+	}
+
+	var result *upstreamResult
+	leader := true
+	if cacheable {
+		result, leader = p.fetchCoalesced(r, backendName, policy, revalidating)
+	} else {
+		result = p.fetchResult(r, backendName, CachePolicy{}, nil)
+	}
+
+	if err := result.err; err != nil {
+		// Keep serving the last good response while the backend is failing
+		if stale != nil {
+			p.serveCached(w, r, stale, CacheStatusStale)
 			return
 		}
+
+		switch {
+		case errors.Is(err, errBackendUnavailable):
+			http.Error(w, "Backend not found", http.StatusBadGateway)
+		case errors.Is(err, circuit.ErrCircuitBreakerOpen):
+			http.Error(w, "Service temporarily unavailable", http.StatusServiceUnavailable)
+		default:
+			http.Error(w, "Backend error", http.StatusBadGateway)
+		}
+		return
+	}
+
+	cacheStatus := CacheStatusMiss
+	if result.revalidated {
+		cacheStatus = CacheStatusRevalidated
 	}
 
-	// Get Backend
+	// Cache responses the policy allows; coalesced waiters share the
+	// leader's entry
+	if cacheable && leader {
+		p.storeResponse(r, result.response(), result.body, policy)
+	}
+
+	// The client's validators were not forwarded, so answer them here
+	if cacheable && notModified(r, result.statusCode, result.header) {
+		w.Header().Set("X-Cache", cacheStatus)
+		writeNotModified(w, result.header)
+		return
+	}
+
+	// Copy response headers
+	for key, values := range result.header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	if cacheable {
+		w.Header().Set("X-Cache", cacheStatus)
+	}
+
+	w.WriteHeader(result.statusCode)
+
+	// Write response
+	w.Write(result.body)
+}
+
+// fetch sends the request to a healthy instance of the backend through its
+// circuit breaker. 5xx responses are returned as errors with the body closed.
+func (p *Proxy) fetch(r *http.Request, backendName string) (*http.Response, error) {
 	backend, err := p.backendManager.GetBackend(backendName)
 	if err != nil {
-		http.Error(w, "Backend not found", http.StatusBadGateway)
-		return
+		return nil, fmt.Errorf("%w: %v", errBackendUnavailable, err)
 	}
 
 	// Get circuit breaker
@@ -126,8 +219,10 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request, backendName st
 		var callErr error
 		resp, callErr = p.forwardRequest(r, backend)
 		if callErr != nil {
+			p.backendManager.ReportResult(backend, 0, callErr)
 			return callErr
 		}
+		p.backendManager.ReportResult(backend, resp.StatusCode, nil)
 
 		// Consider only 5xx HTTP error status codes as failures for circuit breaker
 		// 4xx responses are client errors and should be forwarded
@@ -139,39 +234,37 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request, backendName st
 	})
 
 	if err != nil {
-		if err == circuit.ErrCircuitBreakerOpen {
-			http.Error(w, "Service temporarily unavailable", http.StatusServiceUnavailable)
-		} else {
-			http.Error(w, "Backend error", http.StatusBadGateway)
-		}
-		return
-	}
-
-	defer resp.Body.Close()
-
-	// Copy response headers
-	for key, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(key, value)
+		if resp != nil {
+			resp.Body.Close()
 		}
+		return nil, err
 	}
 
-	w.WriteHeader(resp.StatusCode)
+	return resp, nil
+}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		p.logger.Error("Failed to read response body", "error", err)
+// revalidate refreshes a stale cache entry in the background, conditionally
+// when the entry has validators. Only one refresh per cache key runs at a time.
+func (p *Proxy) revalidate(r *http.Request, backendName string, policy CachePolicy, cached *CachedResponse) {
+	cacheKey := p.cacheKey(r, policy.Key)
+	if _, busy := p.revalidating.LoadOrStore(cacheKey, struct{}{}); busy {
 		return
 	}
 
-	// Cache successful GET responses
-	if r.Method == "GET" && resp.StatusCode == 200 && cacheTTL > 0 {
-		p.cacheResponse(r, resp, body, cacheTTL)
-	}
+	// The refresh outlives the client request
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), revalidateTimeout)
+	req := upstreamRequest(r.WithContext(ctx), cached)
 
-	// Write response
-	w.Write(body)
+	go func() {
+		defer p.revalidating.Delete(cacheKey)
+		defer cancel()
+
+		result := p.fetchResult(req, backendName, policy, cached)
+		if result.err != nil {
+			return
+		}
+		p.storeResponse(req, result.response(), result.body, policy)
+	}()
 }
 
 func (p *Proxy) generateCacheKey(r *http.Request) string {
@@ -183,21 +276,13 @@ func (p *Proxy) GenerateCacheKey(r *http.Request) string {
 	return p.generateCacheKey(r)
 }
 
-func (p *Proxy) getCachedResponse(r *http.Request) (*CachedResponse, error) {
-	cacheKey := p.generateCacheKey(r)
-
-	data, err := p.cacheManager.Get(r.Context(), cacheKey)
-	if err != nil {
-		return nil, err
-	}
+// CacheKey is a public wrapper for cacheKey for testing
+func (p *Proxy) CacheKey(r *http.Request, policy CacheKeyPolicy) string {
+	return p.cacheKey(r, policy)
+}
 
-	// In a real implementation, you would properly serialize/deserialize the response
-	// For simplicity, well just cache the body
-	return &CachedResponse{
-		StatusCode: 200,
-		Headers:    make(http.Header),
-		Body:       data,
-	}, nil
+func (p *Proxy) getCachedResponse(r *http.Request) (*CachedResponse, error) {
+	return p.lookupCachedResponse(r.Context(), p.generateCacheKey(r), r)
 }
 
 // GetCachedResponse is a public wrapper for getCachedResponse for testing
@@ -206,11 +291,31 @@ func (p *Proxy) GetCachedResponse(r *http.Request) (*CachedResponse, error) {
 }
 
 func (p *Proxy) writeCachedResponse(w http.ResponseWriter, cached *CachedResponse) {
+	p.writeCached(w, cached, CacheStatusHit)
+}
+
+// serveCached answers a request from a cached response, with 304 Not
+// Modified when the request's validators still match
+func (p *Proxy) serveCached(w http.ResponseWriter, r *http.Request, cached *CachedResponse, cacheStatus string) {
+	if !notModified(r, cached.StatusCode, cached.Headers) {
+		p.writeCached(w, cached, cacheStatus)
+		return
+	}
+
+	w.Header().Set("X-Cache", cacheStatus)
+	w.Header().Set("Age", strconv.Itoa(int(cached.Age().Seconds())))
+	writeNotModified(w, cached.Headers)
+}
+
+// writeCached replays a cached response, reporting cacheStatus in X-Cache
+func (p *Proxy) writeCached(w http.ResponseWriter, cached *CachedResponse, cacheStatus string) {
 	for key, values := range cached.Headers {
 		for _, value := range values {
 			w.Header().Add(key, value)
 		}
 	}
+	w.Header().Set("X-Cache", cacheStatus)
+	w.Header().Set("Age", strconv.Itoa(int(cached.Age().Seconds())))
 	w.WriteHeader(cached.StatusCode)
 	w.Write(cached.Body)
 }
@@ -220,9 +325,41 @@ func (p *Proxy) WriteCachedResponse(w http.ResponseWriter, cached *CachedRespons
 	p.writeCachedResponse(w, cached)
 }
 
-func (p *Proxy) cacheResponse(r *http.Request, _ *http.Response, body []byte, ttl time.Duration) {
-	cacheKey := p.generateCacheKey(r)
-	p.cacheManager.Set(r.Context(), cacheKey, body, ttl)
+func (p *Proxy) cacheResponse(r *http.Request, resp *http.Response, body []byte, ttl time.Duration) {
+	p.cacheResponseFor(p.generateCacheKey(r), r, resp, body, ttl, 0, 0)
+}
+
+// storeResponse caches a response if the policy allows it
+func (p *Proxy) storeResponse(r *http.Request, resp *http.Response, body []byte, policy CachePolicy) {
+	freshness := policy.storeTTL(r, resp)
+	if freshness <= 0 {
+		return
+	}
+
+	staleWhileRevalidate, staleIfError := policy.staleWindows(resp)
+	p.cacheResponseFor(p.cacheKey(r, policy.Key), r, resp, body, freshness, staleWhileRevalidate, staleIfError)
+}
+
+// cacheResponseFor stores a response under cacheKey that is fresh for
+// freshness and kept around afterwards for as long as it may still be served
+// stale
+func (p *Proxy) cacheResponseFor(cacheKey string, r *http.Request, resp *http.Response, body []byte, freshness, staleWhileRevalidate, staleIfError time.Duration) {
+	cached := newCachedResponse(resp, body)
+	cached.ExpiresAt = time.Now().Add(freshness)
+	cached.StaleWhileRevalidate = staleWhileRevalidate
+	cached.StaleIfError = staleIfError
+
+	// Entries with validators are kept an extra freshness lifetime so they
+	// can be revalidated with a conditional request once stale
+	retention := max(staleWhileRevalidate, staleIfError)
+	if cached.hasValidators() {
+		retention = max(retention, freshness)
+	}
+
+	ttl := freshness + retention
+	if err := p.storeCachedResponse(r.Context(), cacheKey, r, cached, ttl); err != nil {
+		p.logger.Warn("Failed to cache response", "key", cacheKey, "error", err)
+	}
 }
 
 // CacheResponse is a public wrapper for cacheResponse for testing
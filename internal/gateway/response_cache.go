@@ -0,0 +1,237 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cacheEntryVersion identifies the serialized cache entry format. Entries
+// without it were written by older gateways that cached only the body.
+const cacheEntryVersion = 1
+
+// revalidateTimeout bounds a background refresh of a stale entry
+const revalidateTimeout = 30 * time.Second
+
+var (
+	// errInvalidCacheEntry is returned when a cached variant cannot be decoded
+	errInvalidCacheEntry = errors.New("invalid cache entry")
+	// errBackendUnavailable is returned when no backend instance can serve a request
+	errBackendUnavailable = errors.New("backend unavailable")
+)
+
+// Cache status values reported in the X-Cache header
+const (
+	CacheStatusHit   = "HIT"
+	CacheStatusMiss  = "MISS"
+	CacheStatusStale = "STALE"
+)
+
+// CachedResponse is a complete upstream response as stored in the cache
+type CachedResponse struct {
+	StatusCode int         `json:"status"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+	StoredAt   time.Time   `json:"stored_at"`
+	ExpiresAt  time.Time   `json:"expires_at"` // End of freshness; zero means fresh for as long as it is cached
+
+	// How long after ExpiresAt the response may still be served stale
+	StaleWhileRevalidate time.Duration `json:"stale_while_revalidate,omitempty"`
+	StaleIfError         time.Duration `json:"stale_if_error,omitempty"`
+}
+
+// fresh reports whether the response can be served without revalidation
+func (c *CachedResponse) fresh(now time.Time) bool {
+	return c.ExpiresAt.IsZero() || now.Before(c.ExpiresAt)
+}
+
+// staleFor returns how long the response has been stale
+func (c *CachedResponse) staleFor(now time.Time) time.Duration {
+	return now.Sub(c.ExpiresAt)
+}
+
+// Age returns how long the response has been cached
+func (c *CachedResponse) Age() time.Duration {
+	if c.StoredAt.IsZero() {
+		return 0
+	}
+	age := time.Since(c.StoredAt)
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+// cacheEntry is the serialized form stored under a cache key. When the
+// upstream response varies on request headers, the entry stored under the
+// base key only lists those headers and each variant is stored under its
+// own key.
+type cacheEntry struct {
+	Version  int             `json:"v"`
+	Vary     []string        `json:"vary,omitempty"`
+	Response *CachedResponse `json:"response,omitempty"`
+}
+
+func decodeCacheEntry(data []byte) (*cacheEntry, bool) {
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Version != cacheEntryVersion {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func encodeCacheEntry(entry cacheEntry) ([]byte, error) {
+	entry.Version = cacheEntryVersion
+	return json.Marshal(entry)
+}
+
+// uncachedHeaders are never stored: hop-by-hop headers, per-client cookies
+// and headers the gateway recomputes on every hit
+var uncachedHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Set-Cookie":          true,
+	"Age":                 true,
+	"X-Cache":             true,
+}
+
+// filterCacheHeaders copies the headers that are safe to replay from cache
+func filterCacheHeaders(header http.Header) http.Header {
+	filtered := make(http.Header, len(header))
+	for key, values := range header {
+		if uncachedHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		filtered[key] = append([]string(nil), values...)
+	}
+	return filtered
+}
+
+// varyHeaders returns the canonical request header names a response varies
+// on. ok is false for Vary: *, which can never be served from cache.
+func varyHeaders(header http.Header) (names []string, ok bool) {
+	seen := make(map[string]bool)
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if name == "*" {
+				return nil, false
+			}
+			name = http.CanonicalHeaderKey(name)
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+// variantKey derives the cache key of the variant selected by the request
+func variantKey(baseKey string, vary []string, r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(baseKey)
+	for _, name := range vary {
+		b.WriteString(variantSeparator)
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// newCachedResponse captures an upstream response for storage. The upstream
+// Age is folded into StoredAt so the age reported on hits stays accurate.
+func newCachedResponse(resp *http.Response, body []byte) *CachedResponse {
+	storedAt := time.Now().Add(-currentAge(resp.Header))
+
+	return &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    filterCacheHeaders(resp.Header),
+		Body:       body,
+		StoredAt:   storedAt,
+	}
+}
+
+// lookupCachedResponse resolves the stored response for a request, following
+// the vary index when the response has variants
+func (p *Proxy) lookupCachedResponse(ctx context.Context, baseKey string, r *http.Request) (*CachedResponse, error) {
+	data, err := p.cacheManager.Get(ctx, baseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := decodeCacheEntry(data)
+	if !ok {
+		// Entries written before full responses were cached hold only the body
+		return &CachedResponse{
+			StatusCode: http.StatusOK,
+			Headers:    make(http.Header),
+			Body:       data,
+		}, nil
+	}
+
+	if entry.Response != nil {
+		return entry.Response, nil
+	}
+
+	data, err = p.cacheManager.Get(ctx, variantKey(baseKey, entry.Vary, r))
+	if err != nil {
+		return nil, err
+	}
+
+	variant, ok := decodeCacheEntry(data)
+	if !ok || variant.Response == nil {
+		return nil, errInvalidCacheEntry
+	}
+	return variant.Response, nil
+}
+
+// storeCachedResponse writes a response and, when it varies, the vary index
+func (p *Proxy) storeCachedResponse(ctx context.Context, baseKey string, r *http.Request, cached *CachedResponse, ttl time.Duration) error {
+	vary, ok := varyHeaders(cached.Headers)
+	if !ok {
+		return nil
+	}
+
+	data, err := encodeCacheEntry(cacheEntry{Response: cached})
+	if err != nil {
+		return err
+	}
+
+	if len(vary) == 0 {
+		if err := p.cacheManager.Set(ctx, baseKey, data, ttl); err != nil {
+			return err
+		}
+		return p.tagCachedKeys(ctx, cached, ttl, baseKey)
+	}
+
+	key := variantKey(baseKey, vary, r)
+	if err := p.cacheManager.Set(ctx, key, data, ttl); err != nil {
+		return err
+	}
+
+	index, err := encodeCacheEntry(cacheEntry{Vary: vary})
+	if err != nil {
+		return err
+	}
+	if err := p.cacheManager.Set(ctx, baseKey, index, ttl); err != nil {
+		return err
+	}
+	return p.tagCachedKeys(ctx, cached, ttl, key, baseKey)
+}
@@ -0,0 +1,156 @@
+package testing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/gateway"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxy_CacheKey(t *testing.T) {
+	proxy := newInvalidatingProxy(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	request := func(target string, header http.Header) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		policy gateway.CacheKeyPolicy
+		a, b   *http.Request
+		same   bool
+	}{
+		{
+			name:   "zero policy keys on the URL as sent",
+			policy: gateway.CacheKeyPolicy{},
+			a:      request("/markets?a=1&b=2", nil),
+			b:      request("/markets?b=2&a=1", nil),
+			same:   false,
+		},
+		{
+			name:   "sorted query ignores parameter order",
+			policy: gateway.CacheKeyPolicy{Namespace: "/markets", Query: gateway.QueryKeySorted},
+			a:      request("/markets?a=1&b=2", nil),
+			b:      request("/markets?b=2&a=1", nil),
+			same:   true,
+		},
+		{
+			name:   "allowlist drops other parameters",
+			policy: gateway.CacheKeyPolicy{Namespace: "/markets", Query: gateway.QueryKeyAllowlist, QueryParams: []string{"page"}},
+			a:      request("/markets?page=2&utm_source=mail", nil),
+			b:      request("/markets?page=2", nil),
+			same:   true,
+		},
+		{
+			name:   "allowlist keeps listed parameters",
+			policy: gateway.CacheKeyPolicy{Namespace: "/markets", Query: gateway.QueryKeyAllowlist, QueryParams: []string{"page"}},
+			a:      request("/markets?page=1", nil),
+			b:      request("/markets?page=2", nil),
+			same:   false,
+		},
+		{
+			name:   "none keys on the path only",
+			policy: gateway.CacheKeyPolicy{Namespace: "/markets", Query: gateway.QueryKeyNone},
+			a:      request("/markets?page=1", nil),
+			b:      request("/markets?page=2", nil),
+			same:   true,
+		},
+		{
+			name:   "selected headers separate entries",
+			policy: gateway.CacheKeyPolicy{Namespace: "/markets", Headers: []string{"accept-language"}},
+			a:      request("/markets", http.Header{"Accept-Language": {"en"}}),
+			b:      request("/markets", http.Header{"Accept-Language": {"fr"}}),
+			same:   false,
+		},
+		{
+			name:   "other headers are ignored",
+			policy: gateway.CacheKeyPolicy{Namespace: "/markets", Headers: []string{"Accept-Language"}},
+			a:      request("/markets", http.Header{"User-Agent": {"a"}}),
+			b:      request("/markets", http.Header{"User-Agent": {"b"}}),
+			same:   true,
+		},
+		{
+			name:   "per user separates credentials",
+			policy: gateway.CacheKeyPolicy{Namespace: "/markets", PerUser: true},
+			a:      request("/markets", http.Header{"X-Api-Key": {"key-a"}}),
+			b:      request("/markets", http.Header{"X-Api-Key": {"key-b"}}),
+			same:   false,
+		},
+		{
+			name:   "per user shares anonymous entries",
+			policy: gateway.CacheKeyPolicy{Namespace: "/markets", PerUser: true},
+			a:      request("/markets", nil),
+			b:      request("/markets", nil),
+			same:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := proxy.CacheKey(tt.a, tt.policy)
+			b := proxy.CacheKey(tt.b, tt.policy)
+			if tt.same {
+				assert.Equal(t, a, b)
+			} else {
+				assert.NotEqual(t, a, b)
+			}
+		})
+	}
+}
+
+func TestProxy_CacheKey_Namespaced(t *testing.T) {
+	proxy := newInvalidatingProxy(t, func(w http.ResponseWriter, r *http.Request) {})
+	r := httptest.NewRequest(http.MethodGet, "/markets?page=1", nil)
+
+	a := proxy.CacheKey(r, gateway.CacheKeyPolicy{Namespace: "/markets"})
+	b := proxy.CacheKey(r, gateway.CacheKeyPolicy{Namespace: "/*"})
+
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, "route:/markets GET:/markets?page=1", a)
+}
+
+func TestProxy_CacheKey_HidesCredentials(t *testing.T) {
+	proxy := newInvalidatingProxy(t, func(w http.ResponseWriter, r *http.Request) {})
+	r := httptest.NewRequest(http.MethodGet, "/markets", nil)
+	r.Header.Set("Authorization", "Bearer secret-token")
+
+	key := proxy.CacheKey(r, gateway.CacheKeyPolicy{Namespace: "/markets", PerUser: true})
+	assert.NotContains(t, key, "secret-token")
+
+	key = proxy.CacheKey(r, gateway.CacheKeyPolicy{Namespace: "/markets", PerUser: true, Identity: "user-1"})
+	assert.Equal(t, "route:/markets GET:/markets user:user-1", key)
+}
+
+func TestProxy_CacheKeyPolicy_PerUser(t *testing.T) {
+	var calls int32
+	proxy := newInvalidatingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte("portfolio of " + r.Header.Get("X-API-Key")))
+	})
+
+	policy := gateway.CachePolicy{
+		TTL: time.Minute,
+		Key: gateway.CacheKeyPolicy{Namespace: "/portfolio", PerUser: true},
+	}
+	serve := func(apiKey string) string {
+		r := httptest.NewRequest(http.MethodGet, "/portfolio", nil)
+		r.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		proxy.ServeHTTPWithPolicy(w, r, "test-backend", policy)
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "portfolio of key-a", serve("key-a"))
+	assert.Equal(t, "portfolio of key-b", serve("key-b"))
+	assert.Equal(t, "portfolio of key-a", serve("key-a"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
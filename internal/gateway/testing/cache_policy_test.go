@@ -0,0 +1,217 @@
+package testing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/gateway"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxy_CachePolicyHeaders(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          gateway.CacheMode
+		status        int
+		headers       map[string]string
+		requestHeader map[string]string
+		expectCached  bool
+		expectedTTL   time.Duration
+	}{
+		{
+			name:         "max-age sets freshness",
+			mode:         gateway.CacheModeHeaders,
+			status:       http.StatusOK,
+			headers:      map[string]string{"Cache-Control": "max-age=30"},
+			expectCached: true,
+			expectedTTL:  30 * time.Second,
+		},
+		{
+			name:         "s-maxage wins over max-age",
+			mode:         gateway.CacheModeHeaders,
+			status:       http.StatusOK,
+			headers:      map[string]string{"Cache-Control": "max-age=30, s-maxage=90"},
+			expectCached: true,
+			expectedTTL:  90 * time.Second,
+		},
+		{
+			name:         "age is subtracted",
+			mode:         gateway.CacheModeHeaders,
+			status:       http.StatusOK,
+			headers:      map[string]string{"Cache-Control": "max-age=30", "Age": "10"},
+			expectCached: true,
+			expectedTTL:  20 * time.Second,
+		},
+		{
+			name:   "expires relative to date",
+			mode:   gateway.CacheModeHeaders,
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Date":    "Mon, 02 Jan 2006 15:04:05 GMT",
+				"Expires": "Mon, 02 Jan 2006 15:05:05 GMT",
+			},
+			expectCached: true,
+			expectedTTL:  time.Minute,
+		},
+		{
+			name:         "route ttl is the fallback",
+			mode:         gateway.CacheModeHeaders,
+			status:       http.StatusOK,
+			expectCached: true,
+			expectedTTL:  time.Minute,
+		},
+		{
+			name:         "override ignores max-age",
+			mode:         gateway.CacheModeOverride,
+			status:       http.StatusOK,
+			headers:      map[string]string{"Cache-Control": "max-age=5"},
+			expectCached: true,
+			expectedTTL:  time.Minute,
+		},
+		{
+			name:         "404 is heuristically cacheable",
+			mode:         gateway.CacheModeHeaders,
+			status:       http.StatusNotFound,
+			expectCached: true,
+			expectedTTL:  time.Minute,
+		},
+		{
+			name:    "no-store",
+			mode:    gateway.CacheModeHeaders,
+			status:  http.StatusOK,
+			headers: map[string]string{"Cache-Control": "no-store"},
+		},
+		{
+			name:    "private",
+			mode:    gateway.CacheModeOverride,
+			status:  http.StatusOK,
+			headers: map[string]string{"Cache-Control": "private, max-age=60"},
+		},
+		{
+			name:    "expired",
+			mode:    gateway.CacheModeHeaders,
+			status:  http.StatusOK,
+			headers: map[string]string{"Cache-Control": "max-age=0"},
+		},
+		{
+			name:          "authorized request is not shared",
+			mode:          gateway.CacheModeHeaders,
+			status:        http.StatusOK,
+			headers:       map[string]string{"Cache-Control": "max-age=60"},
+			requestHeader: map[string]string{"Authorization": "Bearer token"},
+		},
+		{
+			name:          "authorized request marked public",
+			mode:          gateway.CacheModeHeaders,
+			status:        http.StatusOK,
+			headers:       map[string]string{"Cache-Control": "public, max-age=60"},
+			requestHeader: map[string]string{"X-API-Key": "key"},
+			expectCached:  true,
+			expectedTTL:   time.Minute,
+		},
+		{
+			name:          "request no-store",
+			mode:          gateway.CacheModeHeaders,
+			status:        http.StatusOK,
+			requestHeader: map[string]string{"Cache-Control": "no-store"},
+		},
+		{
+			name:         "ttl mode ignores freshness headers",
+			mode:         gateway.CacheModeTTL,
+			status:       http.StatusOK,
+			headers:      map[string]string{"Cache-Control": "max-age=5"},
+			expectCached: true,
+			expectedTTL:  time.Minute,
+		},
+		{
+			name:    "ttl mode honours no-store",
+			mode:    gateway.CacheModeTTL,
+			status:  http.StatusOK,
+			headers: map[string]string{"Cache-Control": "no-store"},
+		},
+		{
+			name:    "ttl mode honours private",
+			mode:    gateway.CacheModeTTL,
+			status:  http.StatusOK,
+			headers: map[string]string{"Cache-Control": "private, max-age=60"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy, mockCache := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+				for key, value := range tt.headers {
+					w.Header().Set(key, value)
+				}
+				w.WriteHeader(tt.status)
+			})
+
+			req := httptest.NewRequest("GET", "/markets", nil)
+			for key, value := range tt.requestHeader {
+				req.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			proxy.ServeHTTPWithPolicy(w, req, "test-backend", gateway.CachePolicy{TTL: time.Minute, Mode: tt.mode})
+			require.Equal(t, tt.status, w.Code)
+
+			ttl, cached := mockCache.ttl[proxy.GenerateCacheKey(req)]
+			assert.Equal(t, tt.expectCached, cached)
+			if tt.expectCached {
+				assert.InDelta(t, tt.expectedTTL.Seconds(), ttl.Seconds(), 1)
+			}
+		})
+	}
+}
+
+func TestProxy_CachePolicyRequestNoCache(t *testing.T) {
+	var calls atomic.Int32
+	proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.WriteHeader(http.StatusOK)
+	})
+	policy := gateway.CachePolicy{TTL: time.Minute, Mode: gateway.CacheModeHeaders}
+
+	serve := func(header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/markets", nil)
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		proxy.ServeHTTPWithPolicy(w, req, "test-backend", policy)
+		return w
+	}
+
+	serve(nil)
+	assert.Equal(t, gateway.CacheStatusHit, serve(nil).Header().Get("X-Cache"))
+
+	w := serve(map[string]string{"Cache-Control": "no-cache"})
+	assert.Equal(t, gateway.CacheStatusMiss, w.Header().Get("X-Cache"))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestProxy_CachePolicyTTLModeKeepsUsersApart(t *testing.T) {
+	proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("portfolio of " + r.Header.Get("Authorization")))
+	})
+	policy := gateway.CachePolicy{TTL: time.Minute, Mode: gateway.CacheModeTTL}
+
+	serve := func(credential string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/portfolio", nil)
+		req.Header.Set("Authorization", credential)
+		w := httptest.NewRecorder()
+		proxy.ServeHTTPWithPolicy(w, req, "test-backend", policy)
+		return w
+	}
+
+	serve("Bearer user-a")
+	w := serve("Bearer user-b")
+	assert.Equal(t, "portfolio of Bearer user-b", w.Body.String(), "another user's response is not served")
+	assert.Equal(t, gateway.CacheStatusMiss, w.Header().Get("X-Cache"))
+}
@@ -0,0 +1,229 @@
+package testing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/circuit"
+	"kalshi/internal/config"
+	"kalshi/internal/gateway"
+	"kalshi/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCoalescingProxy creates a caching proxy with request coalescing enabled
+func newCoalescingProxy(t *testing.T, coalescing config.CoalescingConfig, handler http.HandlerFunc) *gateway.Proxy {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	backendManager := gateway.NewBackendManager()
+	require.NoError(t, backendManager.AddBackend("test-backend", server.URL, "/health", 1))
+
+	cfg := &config.Config{}
+	cfg.Cache.Coalescing = coalescing
+	return gateway.NewProxy(backendManager, NewMockCache(), circuit.NewManager(), &logger.Logger{}, cfg)
+}
+
+// serveConcurrently sends n identical requests at once and returns their responses
+func serveConcurrently(proxy *gateway.Proxy, n int, policy gateway.CachePolicy, header func(i int) http.Header) []*httptest.ResponseRecorder {
+	responses := make([]*httptest.ResponseRecorder, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "/markets", nil)
+			if header != nil {
+				for key, values := range header(i) {
+					req.Header[key] = values
+				}
+			}
+			responses[i] = httptest.NewRecorder()
+			proxy.ServeHTTPWithPolicy(responses[i], req, "test-backend", policy)
+		}(i)
+	}
+	wg.Wait()
+
+	return responses
+}
+
+func TestProxy_CoalescesConcurrentMisses(t *testing.T) {
+	var calls atomic.Int32
+	proxy := newCoalescingProxy(t, config.CoalescingConfig{Enabled: true}, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("shared"))
+	})
+
+	responses := serveConcurrently(proxy, 20, gateway.CachePolicy{TTL: time.Minute}, nil)
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, w := range responses {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "shared", w.Body.String())
+	}
+}
+
+func TestProxy_CoalescingSharesErrors(t *testing.T) {
+	var calls atomic.Int32
+	proxy := newCoalescingProxy(t, config.CoalescingConfig{Enabled: true}, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	responses := serveConcurrently(proxy, 10, gateway.CachePolicy{TTL: time.Minute}, nil)
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, w := range responses {
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	}
+}
+
+func TestProxy_CoalescingSurvivesLeaderCancellation(t *testing.T) {
+	var calls atomic.Int32
+	proxy := newCoalescingProxy(t, config.CoalescingConfig{Enabled: true}, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("shared"))
+	})
+	policy := gateway.CachePolicy{TTL: time.Minute}
+
+	// The leader's client disconnects while others wait on its fetch
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		req := httptest.NewRequest("GET", "/markets", nil).WithContext(ctx)
+		proxy.ServeHTTPWithPolicy(httptest.NewRecorder(), req, "test-backend", policy)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	for _, w := range serveConcurrently(proxy, 5, policy, nil) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "shared", w.Body.String())
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load(), "the fetch is not cancelled with the leader")
+}
+
+func TestProxy_CoalescingLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		coalescing config.CoalescingConfig
+		delay      time.Duration
+		expected   int32
+	}{
+		{
+			name:       "waiter cap",
+			coalescing: config.CoalescingConfig{Enabled: true, MaxWaiters: 1},
+			delay:      100 * time.Millisecond,
+			expected:   4, // Leader, one waiter, three fetching alone
+		},
+		{
+			name:       "wait timeout",
+			coalescing: config.CoalescingConfig{Enabled: true, MaxWait: 10 * time.Millisecond},
+			delay:      100 * time.Millisecond,
+			expected:   5,
+		},
+		{
+			name:       "disabled",
+			coalescing: config.CoalescingConfig{},
+			delay:      100 * time.Millisecond,
+			expected:   5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			proxy := newCoalescingProxy(t, tt.coalescing, func(w http.ResponseWriter, r *http.Request) {
+				// Only the first fetch is slow so waiters that give up finish quickly
+				if calls.Add(1) == 1 {
+					time.Sleep(tt.delay)
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("ok"))
+			})
+
+			// Start the leader first so every other request finds its flight
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				serveConcurrently(proxy, 1, gateway.CachePolicy{TTL: time.Minute}, nil)
+			}()
+			time.Sleep(20 * time.Millisecond)
+
+			for _, w := range serveConcurrently(proxy, 4, gateway.CachePolicy{TTL: time.Minute}, nil) {
+				assert.Equal(t, "ok", w.Body.String())
+			}
+			wg.Wait()
+
+			assert.Equal(t, tt.expected, calls.Load())
+		})
+	}
+}
+
+func TestProxy_CoalescingRespectsVariants(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		policy  gateway.CachePolicy
+	}{
+		{
+			name:    "vary mismatch",
+			headers: map[string]string{"Vary": "Accept-Language"},
+			policy:  gateway.CachePolicy{TTL: time.Minute},
+		},
+		{
+			name:    "private response",
+			headers: map[string]string{"Cache-Control": "private"},
+			policy:  gateway.CachePolicy{TTL: time.Minute, Mode: gateway.CacheModeHeaders},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			proxy := newCoalescingProxy(t, config.CoalescingConfig{Enabled: true}, func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					time.Sleep(100 * time.Millisecond)
+				}
+				for key, value := range tt.headers {
+					w.Header().Set(key, value)
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("lang=" + r.Header.Get("Accept-Language")))
+			})
+
+			languages := []string{"en", "fr", "de"}
+			responses := serveConcurrently(proxy, len(languages), tt.policy, func(i int) http.Header {
+				return http.Header{"Accept-Language": []string{languages[i]}}
+			})
+
+			// Every request gets its own variant rather than the leader's
+			for i, w := range responses {
+				assert.Equal(t, "lang="+languages[i], w.Body.String())
+			}
+		})
+	}
+}
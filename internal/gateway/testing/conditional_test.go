@@ -0,0 +1,160 @@
+package testing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/gateway"
+	"kalshi/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveConditional(proxy *gateway.Proxy, policy gateway.CachePolicy, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/markets", nil)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	proxy.ServeHTTPWithPolicy(w, req, "test-backend", policy)
+	return w
+}
+
+func TestProxy_NotModifiedFromCache(t *testing.T) {
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+	tests := []struct {
+		name      string
+		header    map[string]string
+		expect304 bool
+	}{
+		{name: "matching etag", header: map[string]string{"If-None-Match": `"v1"`}, expect304: true},
+		{name: "weak etag match", header: map[string]string{"If-None-Match": `W/"v1"`}, expect304: true},
+		{name: "etag in list", header: map[string]string{"If-None-Match": `"v0", "v1"`}, expect304: true},
+		{name: "wildcard", header: map[string]string{"If-None-Match": "*"}, expect304: true},
+		{name: "different etag", header: map[string]string{"If-None-Match": `"v2"`}},
+		{name: "not modified since", header: map[string]string{"If-Modified-Since": lastModified}, expect304: true},
+		{name: "modified since", header: map[string]string{"If-Modified-Since": "Sun, 01 Jan 2006 15:04:05 GMT"}},
+		{
+			name:   "if-none-match takes precedence",
+			header: map[string]string{"If-None-Match": `"v2"`, "If-Modified-Since": lastModified},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.Header().Set("ETag", `"v1"`)
+				w.Header().Set("Last-Modified", lastModified)
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("body"))
+			})
+			policy := gateway.CachePolicy{TTL: time.Minute}
+
+			serveConditional(proxy, policy, nil)
+			w := serveConditional(proxy, policy, tt.header)
+
+			assert.Equal(t, gateway.CacheStatusHit, w.Header().Get("X-Cache"))
+			assert.Equal(t, int32(1), calls.Load())
+			if tt.expect304 {
+				assert.Equal(t, http.StatusNotModified, w.Code)
+				assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+				assert.Empty(t, w.Body.String())
+			} else {
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.Equal(t, "body", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestProxy_ClientValidatorsAnsweredOnMiss(t *testing.T) {
+	var forwarded atomic.Value
+	proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		forwarded.Store(r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("body"))
+	})
+	policy := gateway.CachePolicy{TTL: time.Minute}
+
+	// The backend always sends the full body so the cache can store it
+	w := serveConditional(proxy, policy, map[string]string{"If-None-Match": `"v1"`})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, gateway.CacheStatusMiss, w.Header().Get("X-Cache"))
+	assert.Empty(t, forwarded.Load())
+
+	hit := serveConditional(proxy, policy, nil)
+	assert.Equal(t, gateway.CacheStatusHit, hit.Header().Get("X-Cache"))
+	assert.Equal(t, "body", hit.Body.String())
+}
+
+func TestProxy_ConditionalRevalidation(t *testing.T) {
+	var fullResponses, notModified atomic.Int32
+	proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.Header().Set("X-Refreshed", "yes")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fullResponses.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"price": 42}`))
+	})
+	policy := gateway.CachePolicy{TTL: 20 * time.Millisecond}
+
+	serveConditional(proxy, policy, nil)
+	time.Sleep(30 * time.Millisecond)
+
+	// The entry outlives its freshness because it can be revalidated
+	w := serveConditional(proxy, policy, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, gateway.CacheStatusRevalidated, w.Header().Get("X-Cache"))
+	assert.Equal(t, `{"price": 42}`, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "yes", w.Header().Get("X-Refreshed"))
+	assert.Equal(t, int32(1), fullResponses.Load())
+	assert.Equal(t, int32(1), notModified.Load())
+
+	// The refreshed entry is fresh again
+	hit := serveConditional(proxy, policy, nil)
+	assert.Equal(t, gateway.CacheStatusHit, hit.Header().Get("X-Cache"))
+	assert.Equal(t, `{"price": 42}`, hit.Body.String())
+
+	cached, err := proxy.GetCachedResponse(httptest.NewRequest("GET", "/markets", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "yes", cached.Headers.Get("X-Refreshed"))
+}
+
+func TestProxy_GenerateETag(t *testing.T) {
+	var calls atomic.Int32
+	proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("body"))
+	})
+	policy := gateway.CachePolicy{TTL: time.Minute, GenerateETag: true}
+
+	w := serveConditional(proxy, policy, nil)
+	etag := `"` + utils.HashBytes([]byte("body")) + `"`
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	hit := serveConditional(proxy, policy, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, hit.Code)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Without the option no ETag is invented
+	plain, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	assert.Empty(t, serveConditional(plain, gateway.CachePolicy{TTL: time.Minute}, nil).Header().Get("ETag"))
+}
@@ -0,0 +1,167 @@
+package testing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"kalshi/internal/config"
+	"kalshi/internal/gateway"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendManager_HealthThresholds(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if healthy.Load() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	opts := gateway.DefaultHealthCheckOptions()
+	opts.HealthyThreshold = 2
+	opts.UnhealthyThreshold = 3
+
+	bm := gateway.NewBackendManager()
+	require.NoError(t, bm.AddBackendWithOptions("test", server.URL, "/health", 1, opts))
+	backend, err := bm.GetBackendByName("test")
+	require.NoError(t, err)
+
+	healthy.Store(false)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, bm.TriggerHealthCheck("test"))
+		assert.True(t, backend.Healthy(), "should stay healthy before the unhealthy threshold")
+	}
+	require.NoError(t, bm.TriggerHealthCheck("test"))
+	assert.False(t, backend.Healthy())
+
+	healthy.Store(true)
+	require.NoError(t, bm.TriggerHealthCheck("test"))
+	assert.False(t, backend.Healthy(), "should stay unhealthy before the healthy threshold")
+	require.NoError(t, bm.TriggerHealthCheck("test"))
+	assert.True(t, backend.Healthy())
+}
+
+func TestBackendManager_HealthExpectations(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		body            string
+		opts            func(*gateway.HealthCheckOptions)
+		expectedHealthy bool
+	}{
+		{
+			name:            "default accepts 2xx",
+			status:          http.StatusNoContent,
+			expectedHealthy: true,
+		},
+		{
+			name:   "expected status list",
+			status: http.StatusOK,
+			opts: func(o *gateway.HealthCheckOptions) {
+				o.ExpectedStatuses = []int{http.StatusNoContent}
+			},
+			expectedHealthy: false,
+		},
+		{
+			name:   "body substring matches",
+			status: http.StatusOK,
+			body:   "status: ok",
+			opts: func(o *gateway.HealthCheckOptions) {
+				o.ExpectedBody = "ok"
+			},
+			expectedHealthy: true,
+		},
+		{
+			name:   "body substring missing",
+			status: http.StatusOK,
+			body:   "status: degraded",
+			opts: func(o *gateway.HealthCheckOptions) {
+				o.ExpectedBody = "ok"
+			},
+			expectedHealthy: false,
+		},
+		{
+			name:   "json path value matches",
+			status: http.StatusOK,
+			body:   `{"checks": {"db": "up"}}`,
+			opts: func(o *gateway.HealthCheckOptions) {
+				o.ExpectedJSONPath = "checks.db"
+				o.ExpectedJSONValue = "up"
+			},
+			expectedHealthy: true,
+		},
+		{
+			name:   "json path value differs",
+			status: http.StatusOK,
+			body:   `{"checks": {"db": "down"}}`,
+			opts: func(o *gateway.HealthCheckOptions) {
+				o.ExpectedJSONPath = "checks.db"
+				o.ExpectedJSONValue = "up"
+			},
+			expectedHealthy: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			opts := gateway.DefaultHealthCheckOptions()
+			if tt.opts != nil {
+				tt.opts(&opts)
+			}
+
+			bm := gateway.NewBackendManager()
+			require.NoError(t, bm.AddBackendWithOptions("test", server.URL, "/health", 1, opts))
+			require.NoError(t, bm.TriggerHealthCheck("test"))
+
+			backend, err := bm.GetBackendByName("test")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedHealthy, backend.Healthy())
+		})
+	}
+}
+
+func TestBackendManager_HealthMethodAndHeaders(t *testing.T) {
+	var method, host, token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		host = r.Host
+		token = r.Header.Get("X-Health-Token")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	opts := gateway.HealthCheckOptionsFromConfig(config.HealthCheckConfig{
+		Method: "head",
+		Headers: map[string]string{
+			"Host":           "markets.internal",
+			"X-Health-Token": "secret",
+		},
+	})
+
+	bm := gateway.NewBackendManager()
+	require.NoError(t, bm.AddBackendWithOptions("test", server.URL, "/health", 1, opts))
+	require.NoError(t, bm.TriggerHealthCheck("test"))
+
+	assert.Equal(t, http.MethodHead, method)
+	assert.Equal(t, "markets.internal", host)
+	assert.Equal(t, "secret", token)
+}
+
+func TestHealthCheckOptionsFromConfig_Defaults(t *testing.T) {
+	opts := gateway.HealthCheckOptionsFromConfig(config.HealthCheckConfig{})
+	assert.Equal(t, gateway.DefaultHealthCheckOptions(), opts)
+}
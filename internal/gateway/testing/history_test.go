@@ -0,0 +1,97 @@
+package testing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/gateway"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_HealthHistory(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if healthy.Load() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	bm := gateway.NewBackendManager()
+	require.NoError(t, bm.AddBackend("test", server.URL, "/health", 1))
+	backend, err := bm.GetBackendByName("test")
+	require.NoError(t, err)
+
+	// A passing probe on a healthy backend is not a transition
+	require.NoError(t, bm.TriggerHealthCheck("test"))
+	assert.Empty(t, backend.History())
+
+	healthy.Store(false)
+	require.NoError(t, bm.TriggerHealthCheck("test"))
+	require.NoError(t, bm.SetBackendHealth("test", true))
+
+	history := backend.History()
+	require.Len(t, history, 2)
+
+	assert.False(t, history[0].Healthy)
+	assert.Equal(t, gateway.TransitionSourceProbe, history[0].Source)
+	assert.Equal(t, "unexpected status 503", history[0].Reason)
+	assert.Greater(t, history[0].Latency, time.Duration(0))
+
+	assert.True(t, history[1].Healthy)
+	assert.Equal(t, gateway.TransitionSourceManual, history[1].Source)
+	assert.False(t, history[1].Time.Before(history[0].Time))
+}
+
+func TestBackend_HealthHistoryIsBounded(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	require.NoError(t, bm.AddBackend("test", "http://localhost:8080", "/health", 1))
+	backend, err := bm.GetBackendByName("test")
+	require.NoError(t, err)
+
+	transitions := gateway.DefaultHealthHistorySize + 5
+	for i := 0; i < transitions; i++ {
+		require.NoError(t, bm.SetBackendHealth("test", i%2 == 1))
+	}
+
+	history := backend.History()
+	require.Len(t, history, gateway.DefaultHealthHistorySize)
+	// The oldest entries are overwritten; the newest is last
+	assert.Equal(t, (transitions-1)%2 == 1, history[len(history)-1].Healthy)
+}
+
+func TestBackend_FlapStatus(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	require.NoError(t, bm.AddBackend("test", "http://localhost:8080", "/health", 1))
+	backend, err := bm.GetBackendByName("test")
+	require.NoError(t, err)
+
+	status := backend.FlapStatus(time.Minute, 3)
+	assert.False(t, status.Flapping)
+	assert.Equal(t, 0, status.Transitions)
+
+	require.NoError(t, bm.SetBackendHealth("test", false))
+	require.NoError(t, bm.SetBackendHealth("test", true))
+	assert.False(t, backend.FlapStatus(time.Minute, 3).Flapping)
+
+	require.NoError(t, bm.SetBackendHealth("test", false))
+	status = backend.FlapStatus(time.Minute, 3)
+	assert.True(t, status.Flapping)
+	assert.Equal(t, 3, status.Transitions)
+
+	// Transitions outside the window do not count
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, backend.FlapStatus(10*time.Millisecond, 3).Flapping)
+
+	defaults := backend.FlapStatus(0, 0)
+	assert.Equal(t, gateway.DefaultFlapWindow, defaults.Window)
+	assert.Equal(t, gateway.DefaultFlapThreshold, defaults.Threshold)
+}
@@ -0,0 +1,155 @@
+package testing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/cache"
+	"kalshi/internal/circuit"
+	"kalshi/internal/config"
+	"kalshi/internal/gateway"
+	"kalshi/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newInvalidatingProxy creates a proxy over a memory cache, which supports
+// bulk invalidation unlike MockCache
+func newInvalidatingProxy(t *testing.T, handler http.HandlerFunc) *gateway.Proxy {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	backendManager := gateway.NewBackendManager()
+	require.NoError(t, backendManager.AddBackend("test-backend", server.URL, "/health", 1))
+
+	return gateway.NewProxy(backendManager, cache.NewMemoryCache(100, time.Minute), circuit.NewManager(), &logger.Logger{}, &config.Config{})
+}
+
+func TestProxy_Purge(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           gateway.PurgeRequest
+		expectPurged  int
+		expectRefetch []string
+	}{
+		{
+			name:          "by key",
+			req:           gateway.PurgeRequest{Keys: []string{"GET:/markets/1"}},
+			expectPurged:  1,
+			expectRefetch: []string{"/markets/1"},
+		},
+		{
+			name:          "by prefix",
+			req:           gateway.PurgeRequest{Prefixes: []string{"GET:/markets"}},
+			expectPurged:  2,
+			expectRefetch: []string{"/markets/1", "/markets/2"},
+		},
+		{
+			name:          "by tag",
+			req:           gateway.PurgeRequest{Tags: []string{"market-2"}},
+			expectPurged:  1,
+			expectRefetch: []string{"/markets/2"},
+		},
+		{
+			name:          "shared tag",
+			req:           gateway.PurgeRequest{Tags: []string{"markets"}},
+			expectPurged:  2,
+			expectRefetch: []string{"/markets/1", "/markets/2"},
+		},
+		{
+			name:          "everything",
+			req:           gateway.PurgeRequest{All: true},
+			expectRefetch: []string{"/markets/1", "/markets/2", "/events"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make(map[string]*atomic.Int32)
+			for _, path := range []string{"/markets/1", "/markets/2", "/events"} {
+				calls[path] = &atomic.Int32{}
+			}
+
+			proxy := newInvalidatingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+				calls[r.URL.Path].Add(1)
+				switch r.URL.Path {
+				case "/markets/1":
+					w.Header().Set("Surrogate-Key", "markets market-1")
+				case "/markets/2":
+					w.Header().Set("Surrogate-Key", "markets market-2")
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			for path := range calls {
+				serveGet(proxy, path, nil)
+			}
+
+			purged, err := proxy.Purge(context.Background(), tt.req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectPurged, purged)
+
+			for path := range calls {
+				serveGet(proxy, path, nil)
+			}
+			for path, count := range calls {
+				expected := int32(1)
+				if contains(tt.expectRefetch, path) {
+					expected = 2
+				}
+				assert.Equal(t, expected, count.Load(), path)
+			}
+		})
+	}
+}
+
+func TestProxy_PurgeKeyRemovesVariants(t *testing.T) {
+	var calls atomic.Int32
+	proxy := newInvalidatingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Vary", "Accept-Language")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	english := http.Header{"Accept-Language": []string{"en"}}
+	french := http.Header{"Accept-Language": []string{"fr"}}
+	serveGet(proxy, "/markets", english)
+	serveGet(proxy, "/markets", french)
+
+	purged, err := proxy.Purge(context.Background(), gateway.PurgeRequest{Keys: []string{"GET:/markets"}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, purged, "the vary index and both variants")
+
+	keys, err := proxy.CacheKeys(context.Background(), "")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestProxy_PurgeUnsupported(t *testing.T) {
+	proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	_, err := proxy.Purge(context.Background(), gateway.PurgeRequest{Tags: []string{"markets"}})
+	assert.ErrorIs(t, err, gateway.ErrInvalidationUnsupported)
+
+	// Plain keys only need Delete
+	_, err = proxy.Purge(context.Background(), gateway.PurgeRequest{Keys: []string{"GET:/markets"}})
+	assert.NoError(t, err)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package testing
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"kalshi/internal/cache"
@@ -9,6 +10,7 @@ import (
 
 // MockCache implements cache.Cache for testing
 type MockCache struct {
+	mu   sync.Mutex
 	data map[string][]byte
 	ttl  map[string]time.Duration
 }
@@ -21,6 +23,9 @@ func NewMockCache() *MockCache {
 }
 
 func (m *MockCache) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if data, exists := m.data[key]; exists {
 		return data, nil
 	}
@@ -28,18 +33,27 @@ func (m *MockCache) Get(ctx context.Context, key string) ([]byte, error) {
 }
 
 func (m *MockCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.data[key] = value
 	m.ttl[key] = ttl
 	return nil
 }
 
 func (m *MockCache) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	delete(m.data, key)
 	delete(m.ttl, key)
 	return nil
 }
 
 func (m *MockCache) Exists(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	_, exists := m.data[key]
 	return exists, nil
 }
@@ -0,0 +1,98 @@
+package testing
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/gateway"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxy_NegativeCaching(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		header      http.Header
+		policy      gateway.CachePolicy
+		expectCalls int32
+	}{
+		{
+			name:        "404 is not cached by default",
+			status:      http.StatusNotFound,
+			policy:      gateway.CachePolicy{TTL: time.Minute},
+			expectCalls: 2,
+		},
+		{
+			name:        "404 is cached with a negative ttl",
+			status:      http.StatusNotFound,
+			policy:      gateway.CachePolicy{TTL: time.Minute, NegativeTTL: time.Minute},
+			expectCalls: 1,
+		},
+		{
+			name:        "unlisted statuses are not cached",
+			status:      http.StatusBadRequest,
+			policy:      gateway.CachePolicy{TTL: time.Minute, NegativeTTL: time.Minute},
+			expectCalls: 2,
+		},
+		{
+			name:        "listed statuses are cached",
+			status:      http.StatusGone,
+			policy:      gateway.CachePolicy{TTL: time.Minute, NegativeTTL: time.Minute, NegativeStatuses: []int{404, 410}},
+			expectCalls: 1,
+		},
+		{
+			name:        "no-store wins in headers mode",
+			status:      http.StatusNotFound,
+			header:      http.Header{"Cache-Control": {"no-store"}},
+			policy:      gateway.CachePolicy{TTL: time.Minute, Mode: gateway.CacheModeHeaders, NegativeTTL: time.Minute},
+			expectCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			proxy := newInvalidatingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				for name, values := range tt.header {
+					w.Header()[name] = values
+				}
+				w.WriteHeader(tt.status)
+			})
+
+			first := serveWithPolicy(proxy, tt.policy)
+			second := serveWithPolicy(proxy, tt.policy)
+
+			assert.Equal(t, tt.status, first.Code)
+			assert.Equal(t, tt.status, second.Code)
+			assert.Equal(t, tt.expectCalls, calls.Load())
+		})
+	}
+}
+
+func TestProxy_NegativeCacheExpires(t *testing.T) {
+	var calls atomic.Int32
+	proxy := newInvalidatingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	// The short negative TTL applies even when the backend allows longer,
+	// and negative entries are never served stale
+	policy := gateway.CachePolicy{
+		TTL:          time.Minute,
+		Mode:         gateway.CacheModeOverride,
+		NegativeTTL:  50 * time.Millisecond,
+		StaleIfError: time.Minute,
+	}
+
+	serveWithPolicy(proxy, policy)
+	assert.Equal(t, gateway.CacheStatusHit, serveWithPolicy(proxy, policy).Header().Get("X-Cache"))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, gateway.CacheStatusMiss, serveWithPolicy(proxy, policy).Header().Get("X-Cache"))
+	assert.Equal(t, int32(2), calls.Load())
+}
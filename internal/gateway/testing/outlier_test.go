@@ -0,0 +1,173 @@
+package testing
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"kalshi/internal/config"
+	"kalshi/internal/discovery"
+	"kalshi/internal/gateway"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOutlierPool(t *testing.T, opts gateway.OutlierOptions, hosts ...string) *gateway.BackendManager {
+	t.Helper()
+
+	bm := gateway.NewBackendManager()
+	bm.AddPool("markets", "/health", 1, gateway.DefaultHealthCheckOptions())
+	require.NoError(t, bm.SetOutlierOptions("markets", opts))
+
+	endpoints := make([]discovery.Endpoint, 0, len(hosts))
+	for _, host := range hosts {
+		endpoints = append(endpoints, discovery.Endpoint{URL: "http://" + host})
+	}
+	require.NoError(t, bm.SyncPool("markets", endpoints))
+	return bm
+}
+
+func TestBackendManager_OutlierEjection(t *testing.T) {
+	opts := gateway.OutlierOptions{
+		ConsecutiveErrors:  3,
+		BaseEjectionTime:   time.Minute,
+		MaxEjectionTime:    time.Hour,
+		MaxEjectionPercent: 50,
+	}
+	bm := newOutlierPool(t, opts, "a:80", "b:80")
+
+	backend, err := bm.GetBackendByName("markets@a:80")
+	require.NoError(t, err)
+
+	bm.ReportResult(backend, http.StatusBadGateway, nil)
+	bm.ReportResult(backend, 0, errors.New("connection refused"))
+	assert.False(t, backend.Ejected())
+
+	// A success resets the streak
+	bm.ReportResult(backend, http.StatusOK, nil)
+	bm.ReportResult(backend, http.StatusInternalServerError, nil)
+	bm.ReportResult(backend, http.StatusInternalServerError, nil)
+	assert.False(t, backend.Ejected())
+
+	bm.ReportResult(backend, http.StatusInternalServerError, nil)
+	require.True(t, backend.Ejected())
+	assert.True(t, backend.Healthy(), "ejection does not change active health")
+	assert.WithinDuration(t, time.Now().Add(time.Minute), backend.EjectedUntil(), time.Second)
+
+	// Traffic only reaches the remaining instance
+	for i := 0; i < 4; i++ {
+		selected, err := bm.GetBackend("markets")
+		require.NoError(t, err)
+		assert.Equal(t, "markets@b:80", selected.Name)
+	}
+
+	// 4xx responses are not failures
+	bm.ReportResult(backend, http.StatusNotFound, nil)
+	require.NoError(t, bm.ClearEjection("markets@a:80"))
+	assert.False(t, backend.Ejected())
+}
+
+func TestBackendManager_OutlierEjectionGrowsExponentially(t *testing.T) {
+	opts := gateway.OutlierOptions{
+		ConsecutiveErrors:  1,
+		BaseEjectionTime:   time.Minute,
+		MaxEjectionTime:    3 * time.Minute,
+		MaxEjectionPercent: 100,
+	}
+	bm := newOutlierPool(t, opts, "a:80")
+
+	backend, err := bm.GetBackendByName("markets@a:80")
+	require.NoError(t, err)
+
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}
+	for _, duration := range expected {
+		bm.ReportResult(backend, http.StatusServiceUnavailable, nil)
+		require.True(t, backend.Ejected())
+		assert.WithinDuration(t, time.Now().Add(duration), backend.EjectedUntil(), time.Second)
+		require.NoError(t, bm.ClearEjection("markets@a:80"))
+	}
+}
+
+func TestBackendManager_OutlierEjectionCap(t *testing.T) {
+	opts := gateway.OutlierOptions{
+		ConsecutiveErrors:  1,
+		BaseEjectionTime:   time.Minute,
+		MaxEjectionTime:    time.Hour,
+		MaxEjectionPercent: 50,
+	}
+	bm := newOutlierPool(t, opts, "a:80", "b:80", "c:80", "d:80")
+
+	members, err := bm.GetPoolMembers("markets")
+	require.NoError(t, err)
+	for _, member := range members {
+		bm.ReportResult(member, http.StatusInternalServerError, nil)
+	}
+
+	ejected := 0
+	for _, member := range members {
+		if member.Ejected() {
+			ejected++
+		}
+	}
+	assert.Equal(t, 2, ejected)
+
+	_, err = bm.GetBackend("markets")
+	assert.NoError(t, err)
+}
+
+func TestBackendManager_OutlierDisabled(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	require.NoError(t, bm.AddBackend("test", "http://localhost:8080", "/health", 1))
+
+	backend, err := bm.GetBackendByName("test")
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		bm.ReportResult(backend, http.StatusInternalServerError, nil)
+	}
+	assert.False(t, backend.Ejected())
+}
+
+func TestBackendManager_OutlierEjectsStandaloneBackend(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	require.NoError(t, bm.AddBackend("test", "http://localhost:8080", "/health", 1))
+
+	// A standalone backend is its whole pool; 50% of one rounds down to
+	// zero, but one ejection is always allowed
+	opts := gateway.OutlierOptionsFromConfig(config.OutlierConfig{ConsecutiveErrors: 1})
+	require.Equal(t, gateway.DefaultMaxEjectionPercent, opts.MaxEjectionPercent)
+	require.NoError(t, bm.SetOutlierOptions("test", opts))
+
+	backend, err := bm.GetBackendByName("test")
+	require.NoError(t, err)
+	bm.ReportResult(backend, http.StatusInternalServerError, nil)
+	assert.True(t, backend.Ejected())
+
+	_, err = bm.GetBackend("test")
+	assert.Error(t, err)
+}
+
+func TestBackendManager_OutlierEjectionCapAllowsOne(t *testing.T) {
+	opts := gateway.OutlierOptions{
+		ConsecutiveErrors:  1,
+		BaseEjectionTime:   time.Minute,
+		MaxEjectionTime:    time.Hour,
+		MaxEjectionPercent: 10,
+	}
+	bm := newOutlierPool(t, opts, "a:80", "b:80", "c:80")
+
+	members, err := bm.GetPoolMembers("markets")
+	require.NoError(t, err)
+	for _, member := range members {
+		bm.ReportResult(member, http.StatusInternalServerError, nil)
+	}
+
+	ejected := 0
+	for _, member := range members {
+		if member.Ejected() {
+			ejected++
+		}
+	}
+	assert.Equal(t, 1, ejected)
+}
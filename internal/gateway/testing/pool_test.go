@@ -0,0 +1,213 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/discovery"
+	"kalshi/internal/gateway"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticProvider returns a fixed endpoint list or error
+type staticProvider struct {
+	endpoints []discovery.Endpoint
+	err       error
+}
+
+func (p *staticProvider) Discover(ctx context.Context) ([]discovery.Endpoint, error) {
+	return p.endpoints, p.err
+}
+
+func (p *staticProvider) Name() string {
+	return "static"
+}
+
+// closingProvider counts its resolutions and records being closed
+type closingProvider struct {
+	staticProvider
+	discovers atomic.Int32
+	closed    atomic.Bool
+}
+
+func (p *closingProvider) Discover(ctx context.Context) ([]discovery.Endpoint, error) {
+	p.discovers.Add(1)
+	return p.staticProvider.Discover(ctx)
+}
+
+func (p *closingProvider) Close() error {
+	p.closed.Store(true)
+	return nil
+}
+
+func TestBackendManager_SyncPool(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	bm.AddPool("markets", "/health", 1, gateway.DefaultHealthCheckOptions())
+
+	err := bm.SyncPool("markets", []discovery.Endpoint{
+		{URL: "http://10.0.0.1:8080"},
+		{URL: "http://10.0.0.2:8080", Weight: 5},
+	})
+	require.NoError(t, err)
+
+	members, err := bm.GetPoolMembers("markets")
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "markets@10.0.0.1:8080", members[0].Name)
+	assert.Equal(t, 1, members[0].Weight)
+	assert.Equal(t, 5, members[1].Weight)
+	assert.Equal(t, "markets", members[1].Pool)
+	assert.Equal(t, "/health", members[1].HealthCheck)
+
+	// Members are visible to the rest of the manager
+	assert.Len(t, bm.GetBackends(), 2)
+
+	// Existing members keep their state, removed ones disappear
+	require.NoError(t, bm.SetBackendHealth("markets@10.0.0.2:8080", false))
+	err = bm.SyncPool("markets", []discovery.Endpoint{
+		{URL: "http://10.0.0.2:8080", Weight: 5},
+		{URL: "http://10.0.0.3:8080"},
+	})
+	require.NoError(t, err)
+
+	members, err = bm.GetPoolMembers("markets")
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.False(t, members[0].Healthy())
+	assert.Equal(t, "markets@10.0.0.3:8080", members[1].Name)
+
+	_, err = bm.GetBackendByName("markets@10.0.0.1:8080")
+	assert.Error(t, err)
+}
+
+func TestBackendManager_SyncPool_InvalidEndpoints(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	bm.AddPool("markets", "/health", 1, gateway.DefaultHealthCheckOptions())
+
+	err := bm.SyncPool("markets", []discovery.Endpoint{
+		{URL: "not a url"},
+		{URL: "http://10.0.0.1:8080"},
+	})
+	assert.Error(t, err)
+
+	members, err := bm.GetPoolMembers("markets")
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+
+	assert.Error(t, bm.SyncPool("unknown", nil))
+}
+
+func TestBackendManager_PoolWeightedRoundRobin(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	bm.AddPool("markets", "/health", 1, gateway.DefaultHealthCheckOptions())
+
+	require.NoError(t, bm.SyncPool("markets", []discovery.Endpoint{
+		{URL: "http://a:80", Weight: 3},
+		{URL: "http://b:80", Weight: 1},
+	}))
+
+	counts := make(map[string]int)
+	for i := 0; i < 8; i++ {
+		backend, err := bm.GetBackend("markets")
+		require.NoError(t, err)
+		counts[backend.Name]++
+	}
+
+	assert.Equal(t, 6, counts["markets@a:80"])
+	assert.Equal(t, 2, counts["markets@b:80"])
+}
+
+func TestBackendManager_PoolSkipsUnhealthyMembers(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	bm.AddPool("markets", "/health", 1, gateway.DefaultHealthCheckOptions())
+
+	require.NoError(t, bm.SyncPool("markets", []discovery.Endpoint{
+		{URL: "http://a:80"},
+		{URL: "http://b:80"},
+	}))
+	require.NoError(t, bm.SetBackendHealth("markets@a:80", false))
+
+	for i := 0; i < 4; i++ {
+		backend, err := bm.GetBackend("markets")
+		require.NoError(t, err)
+		assert.Equal(t, "markets@b:80", backend.Name)
+	}
+
+	require.NoError(t, bm.SetBackendHealth("markets@b:80", false))
+	_, err := bm.GetBackend("markets")
+	assert.Error(t, err)
+}
+
+func TestBackendManager_StartDiscovery(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	bm.AddPool("markets", "/health", 1, gateway.DefaultHealthCheckOptions())
+
+	provider := &staticProvider{endpoints: []discovery.Endpoint{{URL: "http://a:80"}}}
+	bm.StartDiscovery("markets", provider, time.Hour, nil)
+
+	// The first resolution happens synchronously
+	backend, err := bm.GetBackend("markets")
+	require.NoError(t, err)
+	assert.Equal(t, "a:80", backend.URL.Host)
+}
+
+func TestBackendManager_StartDiscovery_ProviderError(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	bm.AddPool("markets", "/health", 1, gateway.DefaultHealthCheckOptions())
+
+	provider := &staticProvider{err: errors.New("resolver unavailable")}
+	bm.StartDiscovery("markets", provider, time.Hour, nil)
+
+	members, err := bm.GetPoolMembers("markets")
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+func TestBackendManager_StopDiscovery(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	bm.AddPool("markets", "/health", 1, gateway.DefaultHealthCheckOptions())
+
+	provider := &closingProvider{staticProvider: staticProvider{endpoints: []discovery.Endpoint{{URL: "http://a:80"}}}}
+	bm.StartDiscovery("markets", provider, 5*time.Millisecond, nil)
+	require.Eventually(t, func() bool { return provider.discovers.Load() >= 2 }, time.Second, time.Millisecond)
+
+	bm.StopDiscovery()
+	assert.True(t, provider.closed.Load(), "stopping discovery closes the provider")
+
+	discovers := provider.discovers.Load()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, discovers, provider.discovers.Load(), "no refreshes after stopping")
+
+	// The last membership is kept, and stopping again is harmless
+	_, err := bm.GetBackend("markets")
+	assert.NoError(t, err)
+	bm.StopDiscovery()
+}
+
+func TestBackendManager_PoolWeightUpdatesWhileBalancing(t *testing.T) {
+	bm := gateway.NewBackendManager()
+	bm.AddPool("markets", "/health", 1, gateway.DefaultHealthCheckOptions())
+	require.NoError(t, bm.SyncPool("markets", []discovery.Endpoint{{URL: "http://a:80"}, {URL: "http://b:80"}}))
+
+	// Rediscovered members have their weight updated in place; run with
+	// -race to check balancing reads it safely
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			bm.SyncPool("markets", []discovery.Endpoint{{URL: "http://a:80", Weight: i%5 + 1}, {URL: "http://b:80"}})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_, err := bm.GetBackend("markets")
+		require.NoError(t, err)
+	}
+	wg.Wait()
+}
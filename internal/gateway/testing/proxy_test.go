@@ -148,12 +148,17 @@ func TestProxy_cacheResponse(t *testing.T) {
 
 	proxy.CacheResponse(req, resp, body, ttl)
 
-	// Verify cache was set
+	// Verify the full response was cached
 	cacheKey := proxy.GenerateCacheKey(req)
-	cachedData, exists := mockCache.data[cacheKey]
+	_, exists := mockCache.data[cacheKey]
 	assert.True(t, exists)
-	assert.Equal(t, body, cachedData)
 	assert.Equal(t, ttl, mockCache.ttl[cacheKey])
+
+	cached, err := proxy.GetCachedResponse(req)
+	require.NoError(t, err)
+	assert.Equal(t, 200, cached.StatusCode)
+	assert.Equal(t, "application/json", cached.Headers.Get("Content-Type"))
+	assert.Equal(t, body, cached.Body)
 }
 
 func TestProxy_forwardRequest(t *testing.T) {
@@ -0,0 +1,116 @@
+package testing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/circuit"
+	"kalshi/internal/config"
+	"kalshi/internal/gateway"
+	"kalshi/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCachingProxy creates a proxy in front of handler with an in-memory cache
+func newCachingProxy(t *testing.T, handler http.HandlerFunc) (*gateway.Proxy, *MockCache) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	backendManager := gateway.NewBackendManager()
+	require.NoError(t, backendManager.AddBackend("test-backend", server.URL, "/health", 1))
+
+	mockCache := NewMockCache()
+	proxy := gateway.NewProxy(backendManager, mockCache, circuit.NewManager(), &logger.Logger{}, &config.Config{})
+	return proxy, mockCache
+}
+
+func serveGet(proxy *gateway.Proxy, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, req, "test-backend", time.Minute)
+	return w
+}
+
+func TestProxy_CachesFullResponse(t *testing.T) {
+	var calls atomic.Int32
+	proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("Age", "10")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"price": 42}`))
+	})
+
+	miss := serveGet(proxy, "/markets", nil)
+	assert.Equal(t, http.StatusOK, miss.Code)
+	assert.Equal(t, gateway.CacheStatusMiss, miss.Header().Get("X-Cache"))
+
+	hit := serveGet(proxy, "/markets", nil)
+	assert.Equal(t, http.StatusOK, hit.Code)
+	assert.Equal(t, gateway.CacheStatusHit, hit.Header().Get("X-Cache"))
+	assert.Equal(t, "application/json", hit.Header().Get("Content-Type"))
+	assert.Equal(t, `"v1"`, hit.Header().Get("ETag"))
+	assert.Empty(t, hit.Header().Get("Set-Cookie"), "cookies are never replayed from cache")
+	assert.Equal(t, `{"price": 42}`, hit.Body.String())
+	assert.Equal(t, int32(1), calls.Load())
+
+	// The upstream Age is carried forward
+	age, err := strconv.Atoi(hit.Header().Get("Age"))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, age, 10)
+}
+
+func TestProxy_CacheHonoursVary(t *testing.T) {
+	var calls atomic.Int32
+	proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Vary", "Accept-Language")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("lang=" + r.Header.Get("Accept-Language")))
+	})
+
+	english := http.Header{"Accept-Language": []string{"en"}}
+	french := http.Header{"Accept-Language": []string{"fr"}}
+
+	assert.Equal(t, "lang=en", serveGet(proxy, "/markets", english).Body.String())
+	assert.Equal(t, "lang=fr", serveGet(proxy, "/markets", french).Body.String())
+	assert.Equal(t, int32(2), calls.Load())
+
+	hit := serveGet(proxy, "/markets", english)
+	assert.Equal(t, gateway.CacheStatusHit, hit.Header().Get("X-Cache"))
+	assert.Equal(t, "lang=en", hit.Body.String())
+
+	hit = serveGet(proxy, "/markets", french)
+	assert.Equal(t, gateway.CacheStatusHit, hit.Header().Get("X-Cache"))
+	assert.Equal(t, "lang=fr", hit.Body.String())
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestProxy_VaryStarIsNotCached(t *testing.T) {
+	var calls atomic.Int32
+	proxy, mockCache := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Vary", "*")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serveGet(proxy, "/markets", nil)
+	serveGet(proxy, "/markets", nil)
+
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Empty(t, mockCache.data)
+}
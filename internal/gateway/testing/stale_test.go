@@ -0,0 +1,140 @@
+package testing
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/circuit"
+	"kalshi/internal/config"
+	"kalshi/internal/gateway"
+	"kalshi/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveWithPolicy(proxy *gateway.Proxy, policy gateway.CachePolicy) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/markets", nil)
+	w := httptest.NewRecorder()
+	proxy.ServeHTTPWithPolicy(w, req, "test-backend", policy)
+	return w
+}
+
+func TestProxy_StaleWhileRevalidate(t *testing.T) {
+	var version atomic.Int32
+	proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "v%d", version.Add(1))
+	})
+	policy := gateway.CachePolicy{
+		TTL:                  20 * time.Millisecond,
+		StaleWhileRevalidate: time.Minute,
+	}
+
+	assert.Equal(t, "v1", serveWithPolicy(proxy, policy).Body.String())
+	time.Sleep(30 * time.Millisecond)
+
+	// The expired copy is served immediately and refreshed in the background
+	stale := serveWithPolicy(proxy, policy)
+	assert.Equal(t, gateway.CacheStatusStale, stale.Header().Get("X-Cache"))
+	assert.Equal(t, "v1", stale.Body.String())
+
+	assert.Eventually(t, func() bool {
+		w := serveWithPolicy(proxy, policy)
+		return w.Header().Get("X-Cache") == gateway.CacheStatusHit && w.Body.String() == "v2"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(2), version.Load())
+}
+
+func TestProxy_StaleIfError(t *testing.T) {
+	var failing atomic.Bool
+	proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("good"))
+	})
+	policy := gateway.CachePolicy{
+		TTL:          20 * time.Millisecond,
+		StaleIfError: time.Minute,
+	}
+
+	serveWithPolicy(proxy, policy)
+	failing.Store(true)
+	time.Sleep(30 * time.Millisecond)
+
+	w := serveWithPolicy(proxy, policy)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, gateway.CacheStatusStale, w.Header().Get("X-Cache"))
+	assert.Equal(t, "good", w.Body.String())
+}
+
+func TestProxy_StaleIfErrorBackendUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("good"))
+	}))
+	defer server.Close()
+
+	backendManager := gateway.NewBackendManager()
+	assert.NoError(t, backendManager.AddBackend("test-backend", server.URL, "/health", 1))
+	proxy := gateway.NewProxy(backendManager, NewMockCache(), circuit.NewManager(), &logger.Logger{}, &config.Config{})
+	policy := gateway.CachePolicy{
+		TTL:          20 * time.Millisecond,
+		StaleIfError: time.Minute,
+	}
+
+	serveWithPolicy(proxy, policy)
+	time.Sleep(30 * time.Millisecond)
+
+	// Stale content also covers backends that cannot take traffic at all
+	assert.NoError(t, backendManager.SetBackendHealth("test-backend", false))
+	w := serveWithPolicy(proxy, policy)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, gateway.CacheStatusStale, w.Header().Get("X-Cache"))
+	assert.Equal(t, "good", w.Body.String())
+}
+
+func TestProxy_StaleDirectivesFromHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		expectStale  bool
+	}{
+		{name: "stale-if-error directive", cacheControl: "stale-if-error=60", expectStale: true},
+		{name: "must-revalidate disables stale", cacheControl: "must-revalidate, stale-if-error=60"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failing atomic.Bool
+			proxy, _ := newCachingProxy(t, func(w http.ResponseWriter, r *http.Request) {
+				if failing.Load() {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Header().Set("Cache-Control", tt.cacheControl)
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("good"))
+			})
+			policy := gateway.CachePolicy{TTL: 20 * time.Millisecond, Mode: gateway.CacheModeOverride}
+
+			serveWithPolicy(proxy, policy)
+			failing.Store(true)
+			time.Sleep(30 * time.Millisecond)
+
+			w := serveWithPolicy(proxy, policy)
+			if tt.expectStale {
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.Equal(t, "good", w.Body.String())
+			} else {
+				assert.Equal(t, http.StatusBadGateway, w.Code)
+			}
+		})
+	}
+}
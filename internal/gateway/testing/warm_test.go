@@ -0,0 +1,137 @@
+package testing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"kalshi/internal/cache"
+	"kalshi/internal/config"
+	"kalshi/internal/gateway"
+	"kalshi/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pathCounter counts backend requests per path and query
+type pathCounter struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *pathCounter) handler(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	if c.calls == nil {
+		c.calls = make(map[string]int)
+	}
+	c.calls[r.URL.RequestURI()]++
+	c.mu.Unlock()
+
+	if r.URL.Path == "/missing" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Write([]byte(r.URL.RequestURI()))
+}
+
+func (c *pathCounter) count(target string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[target]
+}
+
+func TestProxy_Warm(t *testing.T) {
+	var counter pathCounter
+	proxy := newInvalidatingProxy(t, counter.handler)
+	policy := gateway.CachePolicy{TTL: time.Minute, Key: gateway.CacheKeyPolicy{Namespace: "/*", Query: gateway.QueryKeySorted}}
+
+	reqs := []gateway.WarmRequest{
+		{Backend: "test-backend", Policy: policy, Target: "/markets?a=1&b=2"},
+		{Backend: "test-backend", Policy: policy, Target: "/markets?b=2&a=1"}, // Same cache key
+		{Backend: "test-backend", Policy: policy, Target: "/missing"},
+		{Backend: "unknown", Policy: policy, Target: "/events"},
+	}
+
+	result := proxy.Warm(context.Background(), reqs, 2)
+	assert.Equal(t, gateway.WarmResult{Warmed: 1, Skipped: 1, Failed: 1}, result)
+	assert.Equal(t, 1, counter.count("/markets?a=1&b=2"))
+
+	// Warmed entries are served without reaching the backend
+	r := httptest.NewRequest(http.MethodGet, "/markets?b=2&a=1", nil)
+	w := httptest.NewRecorder()
+	proxy.ServeHTTPWithPolicy(w, r, "test-backend", policy)
+	assert.Equal(t, gateway.CacheStatusHit, w.Header().Get("X-Cache"))
+
+	// Warming again leaves fresh entries alone
+	result = proxy.Warm(context.Background(), reqs[:1], 2)
+	assert.Equal(t, gateway.WarmResult{Fresh: 1}, result)
+	assert.Equal(t, 1, counter.count("/markets?a=1&b=2"))
+}
+
+func TestGateway_WarmCache(t *testing.T) {
+	var counter pathCounter
+	server := httptest.NewServer(http.HandlerFunc(counter.handler))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		Backend: []config.BackendConfig{
+			{Name: "test-backend", URL: server.URL, HealthCheck: "/health", Weight: 1},
+		},
+		Routes: []config.RouteConfig{
+			{
+				Path:     "/api/*",
+				Backend:  "test-backend",
+				Methods:  []string{http.MethodGet},
+				CacheTTL: time.Minute,
+				CacheKey: config.CacheKeyConfig{Headers: []string{"Accept-Language"}},
+				WarmURLs: []string{"/api/markets"},
+			},
+			{
+				Path:     "/private/*",
+				Backend:  "test-backend",
+				Methods:  []string{http.MethodGet},
+				CacheTTL: time.Minute,
+				CacheKey: config.CacheKeyConfig{User: true},
+			},
+		},
+	}
+
+	manager := cache.NewManager(cache.NewMemoryCache(100, time.Minute), nil, false)
+	manager.TrackPopularity(100)
+	gw := gateway.New(cfg, manager, &logger.Logger{})
+
+	// Traffic before a deploy makes some keys popular
+	serve := func(route *config.RouteConfig, target string, header http.Header, apiKey string) {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		if apiKey != "" {
+			r.Header.Set("X-API-Key", apiKey)
+		}
+		gw.GetProxy().ServeHTTPWithPolicy(httptest.NewRecorder(), r, route.Backend, gateway.RouteCachePolicy(route))
+	}
+	french := http.Header{"Accept-Language": {"fr"}}
+	serve(&cfg.Routes[0], "/api/events?page=2", french, "")
+	serve(&cfg.Routes[1], "/private/portfolio", nil, "key-a")
+
+	// A cold replica warms the route URLs and the replayable popular keys
+	require.NoError(t, manager.Clear(context.Background()))
+	result := gw.WarmCache(context.Background(), 10)
+
+	assert.Equal(t, 2, result.Warmed, "warm url and the popular shared key")
+	assert.Equal(t, 1, counter.count("/api/markets"))
+	assert.Equal(t, 2, counter.count("/api/events?page=2"))
+	assert.Equal(t, 1, counter.count("/private/portfolio"), "per-user keys are not replayed")
+
+	// The replayed key kept its header component
+	r := httptest.NewRequest(http.MethodGet, "/api/events?page=2", nil)
+	r.Header.Set("Accept-Language", "fr")
+	w := httptest.NewRecorder()
+	gw.GetProxy().ServeHTTPWithPolicy(w, r, "test-backend", gateway.RouteCachePolicy(&cfg.Routes[0]))
+	assert.Equal(t, gateway.CacheStatusHit, w.Header().Get("X-Cache"))
+}
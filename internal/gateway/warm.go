@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultWarmConcurrency bounds the warm-up fetches in flight at once
+const DefaultWarmConcurrency = 4
+
+// WarmRequest is a request fetched into the cache ahead of client traffic
+type WarmRequest struct {
+	Backend string
+	Policy  CachePolicy
+	Target  string      // Path and query
+	Header  http.Header // Request headers the cache key depends on
+}
+
+// WarmResult counts the outcomes of a warm-up
+type WarmResult struct {
+	Warmed  int `json:"warmed"`  // Fetched and cached
+	Fresh   int `json:"fresh"`   // Already cached and fresh
+	Skipped int `json:"skipped"` // Not cacheable under the route's policy
+	Failed  int `json:"failed"`
+}
+
+// warmOutcome is how a single warm-up fetch ended
+type warmOutcome int
+
+const (
+	warmWarmed warmOutcome = iota
+	warmFresh
+	warmSkipped
+	warmFailed
+)
+
+func (w *WarmResult) add(outcome warmOutcome) {
+	switch outcome {
+	case warmWarmed:
+		w.Warmed++
+	case warmFresh:
+		w.Fresh++
+	case warmSkipped:
+		w.Skipped++
+	default:
+		w.Failed++
+	}
+}
+
+// popularKeySource is implemented by caches that count key lookups
+type popularKeySource interface {
+	PopularKeys(n int) []string
+}
+
+// lookupRecorder is implemented by caches that count client lookups towards
+// the popular keys
+type lookupRecorder interface {
+	RecordLookup(key string)
+}
+
+// Warm fetches requests into the cache, at most concurrency at a time.
+// Requests sharing a cache key are fetched once, and requests for entries
+// that are still fresh are not fetched at all.
+func (p *Proxy) Warm(ctx context.Context, reqs []WarmRequest, concurrency int) WarmResult {
+	if concurrency <= 0 {
+		concurrency = DefaultWarmConcurrency
+	}
+
+	var (
+		mu     sync.Mutex
+		result WarmResult
+		wg     sync.WaitGroup
+		seen   = make(map[string]bool, len(reqs))
+		slots  = make(chan struct{}, concurrency)
+	)
+
+	for _, warm := range reqs {
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, warm.Target, nil)
+		if err != nil {
+			mu.Lock()
+			result.add(warmFailed)
+			mu.Unlock()
+			continue
+		}
+		for name, values := range warm.Header {
+			r.Header[name] = values
+		}
+
+		cacheKey := p.cacheKey(r, warm.Policy.Key)
+		if seen[cacheKey] {
+			continue
+		}
+		seen[cacheKey] = true
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			result.add(warmFailed)
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *http.Request, warm WarmRequest, cacheKey string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			outcome := p.warmOne(r, warm, cacheKey)
+
+			mu.Lock()
+			result.add(outcome)
+			mu.Unlock()
+		}(r, warm, cacheKey)
+	}
+
+	wg.Wait()
+	return result
+}
+
+// warmOne fetches a single request into the cache
+func (p *Proxy) warmOne(r *http.Request, warm WarmRequest, cacheKey string) warmOutcome {
+	policy := warm.Policy
+	if policy.TTL <= 0 {
+		return warmSkipped
+	}
+
+	var revalidating *CachedResponse
+	if cached, err := p.lookupCachedResponse(r.Context(), cacheKey, r); err == nil {
+		if cached.fresh(time.Now()) {
+			return warmFresh
+		}
+		if cached.hasValidators() {
+			revalidating = cached
+		}
+	}
+
+	result, leader := p.fetchCoalesced(r, warm.Backend, policy, revalidating)
+	if result.err != nil {
+		return warmFailed
+	}
+
+	resp := result.response()
+	if policy.storeTTL(r, resp) <= 0 {
+		return warmSkipped
+	}
+
+	// A client request that led the fetch stores the response itself
+	if leader {
+		p.storeResponse(r, resp, result.body, policy)
+	}
+	return warmWarmed
+}
+
+// PopularWarmRequests turns the n most requested cache keys back into warm
+// requests. routes maps route namespaces to a template carrying the route's
+// backend and cache policy; keys of unknown routes are dropped.
+func (p *Proxy) PopularWarmRequests(n int, routes map[string]WarmRequest) []WarmRequest {
+	source, ok := p.cacheManager.(popularKeySource)
+	if !ok || n <= 0 {
+		return nil
+	}
+
+	reqs := make([]WarmRequest, 0, n)
+	for _, key := range source.PopularKeys(n) {
+		namespace, target, header, ok := parseCacheKey(key)
+		if !ok {
+			continue
+		}
+		route, ok := routes[namespace]
+		if !ok {
+			continue
+		}
+
+		route.Target = target
+		route.Header = header
+		reqs = append(reqs, route)
+	}
+	return reqs
+}
+
+// WarmCache fetches every route's warm URLs and the popularKeys most
+// requested cache keys into the cache
+func (g *Gateway) WarmCache(ctx context.Context, popularKeys int) WarmResult {
+	var reqs []WarmRequest
+	routes := make(map[string]WarmRequest, len(g.config.Routes))
+	for i := range g.config.Routes {
+		route := &g.config.Routes[i]
+		if !slices.Contains(route.Methods, http.MethodGet) {
+			continue
+		}
+
+		template := WarmRequest{
+			Backend: route.Backend,
+			Policy:  RouteCachePolicy(route),
+		}
+		routes[route.Path] = template
+
+		for _, target := range route.WarmURLs {
+			warm := template
+			warm.Target = target
+			reqs = append(reqs, warm)
+		}
+	}
+
+	reqs = append(reqs, g.proxy.PopularWarmRequests(popularKeys, routes)...)
+	return g.proxy.Warm(ctx, reqs, g.config.Cache.Warming.Concurrency)
+}
@@ -0,0 +1,182 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"kalshi/internal/storage"
+)
+
+// DefaultCheckTimeout bounds a single dependency check
+const DefaultCheckTimeout = 2 * time.Second
+
+// ErrShuttingDown is reported once shutdown has begun
+var ErrShuttingDown = errors.New("shutting down")
+
+// CheckFunc verifies a single dependency, returning nil when it is usable
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of a single dependency check
+type CheckResult struct {
+	Name    string        `json:"name"`
+	Healthy bool          `json:"healthy"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// Report is the outcome of a readiness evaluation
+type Report struct {
+	Ready        bool          `json:"ready"`
+	ShuttingDown bool          `json:"shutting_down"`
+	Checks       []CheckResult `json:"checks"`
+}
+
+// Readiness aggregates dependency checks and tracks shutdown state so load
+// balancers stop sending traffic as soon as the gateway begins draining
+type Readiness struct {
+	mu           sync.RWMutex
+	checks       map[string]CheckFunc
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewReadiness creates a readiness tracker. A zero timeout uses DefaultCheckTimeout.
+func NewReadiness(timeout time.Duration) *Readiness {
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+
+	return &Readiness{
+		checks:  make(map[string]CheckFunc),
+		timeout: timeout,
+	}
+}
+
+// Register adds or replaces a named dependency check
+func (r *Readiness) Register(name string, check CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = check
+}
+
+// BeginShutdown marks the gateway as draining; readiness fails from now on
+func (r *Readiness) BeginShutdown() {
+	r.shuttingDown.Store(true)
+}
+
+// Drain begins shutdown and waits delay, or until ctx is done, so load
+// balancers polling readiness see it fail and stop routing here while the
+// server still accepts connections and finishes requests in flight
+func (r *Readiness) Drain(ctx context.Context, delay time.Duration) {
+	r.BeginShutdown()
+	if delay <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// ShuttingDown reports whether shutdown has begun
+func (r *Readiness) ShuttingDown() bool {
+	return r.shuttingDown.Load()
+}
+
+// Check runs every registered check concurrently, each bounded by the
+// configured timeout. Once shutdown has begun no checks are run.
+func (r *Readiness) Check(ctx context.Context) Report {
+	if r.ShuttingDown() {
+		return Report{ShuttingDown: true, Checks: []CheckResult{}}
+	}
+
+	r.mu.RLock()
+	checks := make(map[string]CheckFunc, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, 0, len(checks))
+	var resultsMu sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check CheckFunc) {
+			defer wg.Done()
+			result := r.run(ctx, name, check)
+
+			resultsMu.Lock()
+			results = append(results, result)
+			resultsMu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	ready := true
+	for _, result := range results {
+		if !result.Healthy {
+			ready = false
+			break
+		}
+	}
+
+	return Report{Ready: ready, Checks: results}
+}
+
+func (r *Readiness) run(ctx context.Context, name string, check CheckFunc) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{
+		Name:    name,
+		Healthy: err == nil,
+		Latency: time.Since(start),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// PingCheck checks a dependency that can be pinged, such as RedisStorage or RedisCache
+func PingCheck(pinger interface{ Ping(context.Context) error }) CheckFunc {
+	return pinger.Ping
+}
+
+// StorageRoundTripCheck writes, reads back and deletes a short-lived key
+func StorageRoundTripCheck(store storage.Storage) CheckFunc {
+	return func(ctx context.Context) error {
+		key := fmt.Sprintf("health:readiness:%d", time.Now().UnixNano())
+		value := "ok"
+
+		if err := store.Set(ctx, key, value, time.Minute); err != nil {
+			return fmt.Errorf("write failed: %w", err)
+		}
+		defer store.Delete(context.WithoutCancel(ctx), key)
+
+		got, err := store.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("read failed: %w", err)
+		}
+		if got != value {
+			return fmt.Errorf("read back %q, expected %q", got, value)
+		}
+		return nil
+	}
+}
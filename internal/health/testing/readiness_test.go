@@ -0,0 +1,92 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kalshi/internal/health"
+	"kalshi/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadiness_AllChecksPass(t *testing.T) {
+	readiness := health.NewReadiness(0)
+	readiness.Register("storage", health.StorageRoundTripCheck(storage.NewMemoryStorage()))
+	readiness.Register("config", func(ctx context.Context) error { return nil })
+
+	report := readiness.Check(context.Background())
+	assert.True(t, report.Ready)
+	assert.False(t, report.ShuttingDown)
+	require.Len(t, report.Checks, 2)
+
+	// Results are sorted by name
+	assert.Equal(t, "config", report.Checks[0].Name)
+	assert.Equal(t, "storage", report.Checks[1].Name)
+	for _, check := range report.Checks {
+		assert.True(t, check.Healthy)
+		assert.Empty(t, check.Error)
+	}
+}
+
+func TestReadiness_FailingCheck(t *testing.T) {
+	readiness := health.NewReadiness(0)
+	readiness.Register("ok", func(ctx context.Context) error { return nil })
+	readiness.Register("redis", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report := readiness.Check(context.Background())
+	assert.False(t, report.Ready)
+	require.Len(t, report.Checks, 2)
+	assert.False(t, report.Checks[1].Healthy)
+	assert.Equal(t, "connection refused", report.Checks[1].Error)
+}
+
+func TestReadiness_CheckTimeout(t *testing.T) {
+	readiness := health.NewReadiness(20 * time.Millisecond)
+	readiness.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := readiness.Check(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.False(t, report.Ready)
+	assert.GreaterOrEqual(t, report.Checks[0].Latency, 20*time.Millisecond)
+}
+
+func TestReadiness_Shutdown(t *testing.T) {
+	called := false
+	readiness := health.NewReadiness(0)
+	readiness.Register("check", func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+
+	readiness.BeginShutdown()
+	report := readiness.Check(context.Background())
+
+	assert.True(t, readiness.ShuttingDown())
+	assert.True(t, report.ShuttingDown)
+	assert.False(t, report.Ready)
+	assert.False(t, called, "checks are skipped once draining")
+}
+
+func TestReadiness_Drain(t *testing.T) {
+	readiness := health.NewReadiness(0)
+
+	start := time.Now()
+	readiness.Drain(context.Background(), 30*time.Millisecond)
+	assert.True(t, readiness.ShuttingDown())
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond, "drain waits for the delay")
+
+	// A cancelled context ends the wait early
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	health.NewReadiness(0).Drain(ctx, time.Minute)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,305 @@
+package ratelimit
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// activityTTL is how long a client's activity is kept after its last
+	// request
+	activityTTL = 15 * time.Minute
+	// maxTrackedClients bounds the clients whose activity is kept; the least
+	// recently seen are forgotten first
+	maxTrackedClients = 100000
+	// prunedTrackedClients is what pruning over the bound evicts down to, so
+	// that new clients do not each trigger another prune
+	prunedTrackedClients = maxTrackedClients * 9 / 10
+)
+
+// BucketStatus is the state of one of a client's buckets and the requests
+// it has seen
+type BucketStatus struct {
+	Layer        Layer         `json:"layer"`
+	Scope        string        `json:"scope"`
+	Algorithm    AlgorithmType `json:"algorithm"`
+	Rate         int           `json:"rate"`
+	Limit        int64         `json:"limit"`
+	Remaining    int64         `json:"remaining"`
+	FullAt       time.Time     `json:"full_at"` // When the bucket has refilled completely
+	Admitted     int64         `json:"admitted"`
+	Rejected     int64         `json:"rejected"`
+	LastRequest  time.Time     `json:"last_request"`
+	LastRejected time.Time     `json:"last_rejected,omitempty"`
+}
+
+// ClientStatus is a client's rate limit state across every bucket it has
+// used recently
+type ClientStatus struct {
+	ClientID string         `json:"client_id"`
+	Admitted int64          `json:"admitted"`
+	Rejected int64          `json:"rejected"`
+	LastSeen time.Time      `json:"last_seen,omitempty"`
+	Override *Override      `json:"override,omitempty"`
+	Buckets  []BucketStatus `json:"buckets"`
+}
+
+// ThrottledClient is a client's count of rejected requests
+type ThrottledClient struct {
+	ClientID     string    `json:"client_id"`
+	Admitted     int64     `json:"admitted"`
+	Rejected     int64     `json:"rejected"`
+	LastRejected time.Time `json:"last_rejected"`
+}
+
+// ActivityStats summarises the requests of every tracked client
+type ActivityStats struct {
+	TrackedClients int   `json:"tracked_clients"`
+	Admitted       int64 `json:"admitted"`
+	Rejected       int64 `json:"rejected"`
+}
+
+// bucketKey identifies one of a client's buckets without building a string
+// per request
+type bucketKey struct {
+	layer Layer
+	scope string
+}
+
+// bucketActivity counts the requests one bucket has decided
+type bucketActivity struct {
+	limit        Limit
+	admitted     int64
+	rejected     int64
+	lastRequest  time.Time
+	lastRejected time.Time
+}
+
+// clientActivity is what the limiter has seen of one client
+type clientActivity struct {
+	mu           sync.Mutex
+	buckets      map[bucketKey]*bucketActivity
+	admitted     int64
+	rejected     int64
+	lastSeen     time.Time
+	lastRejected time.Time
+
+	override          *Override // Cached from storage
+	overrideCheckedAt time.Time
+}
+
+// activityTracker keeps recent per-client activity in process. Counters are
+// per replica and a client's are dropped once it has been idle for
+// activityTTL.
+type activityTracker struct {
+	clients   sync.Map // client ID to *clientActivity
+	count     atomic.Int64
+	lastPrune atomic.Int64
+	pruning   atomic.Bool // Set while a prune runs, so only one runs at a time
+}
+
+// client returns the client's activity, starting it when missing
+func (t *activityTracker) client(clientID string) *clientActivity {
+	if activity, ok := t.clients.Load(clientID); ok {
+		return activity.(*clientActivity)
+	}
+
+	activity, loaded := t.clients.LoadOrStore(clientID, &clientActivity{buckets: make(map[bucketKey]*bucketActivity)})
+	if !loaded && t.count.Add(1) > maxTrackedClients {
+		t.prune(time.Now())
+	}
+	return activity.(*clientActivity)
+}
+
+// lookup returns the client's activity without starting it
+func (t *activityTracker) lookup(clientID string) (*clientActivity, bool) {
+	activity, ok := t.clients.Load(clientID)
+	if !ok {
+		return nil, false
+	}
+	return activity.(*clientActivity), true
+}
+
+// record counts a layer's decision on a client's request
+func (t *activityTracker) record(clientID string, limit Limit, allowed bool) {
+	now := time.Now()
+	activity := t.client(clientID)
+
+	activity.mu.Lock()
+	key := bucketKey{layer: limit.Layer, scope: limit.Scope}
+	bucket, exists := activity.buckets[key]
+	if !exists {
+		bucket = &bucketActivity{}
+		activity.buckets[key] = bucket
+	}
+	bucket.limit = limit
+	bucket.lastRequest = now
+	activity.lastSeen = now
+	if allowed {
+		bucket.admitted++
+	} else {
+		bucket.rejected++
+		bucket.lastRejected = now
+		activity.rejected++
+		activity.lastRejected = now
+	}
+	activity.mu.Unlock()
+
+	if last := t.lastPrune.Load(); now.UnixNano()-last > int64(time.Minute) && t.lastPrune.CompareAndSwap(last, now.UnixNano()) {
+		t.prune(now)
+	}
+}
+
+// admitted counts a request every layer admitted
+func (t *activityTracker) admitted(clientID string) {
+	activity := t.client(clientID)
+	activity.mu.Lock()
+	activity.admitted++
+	activity.mu.Unlock()
+}
+
+// forget drops a client's counters and buckets, keeping its cached override
+func (t *activityTracker) forget(clientID string) {
+	if activity, ok := t.lookup(clientID); ok {
+		activity.mu.Lock()
+		activity.buckets = make(map[bucketKey]*bucketActivity)
+		activity.admitted, activity.rejected = 0, 0
+		activity.lastRejected = time.Time{}
+		activity.mu.Unlock()
+	}
+}
+
+// prune forgets clients idle for activityTTL. When more than
+// maxTrackedClients remain, the least recently seen are forgotten until
+// prunedTrackedClients are left. A prune already running is not repeated.
+func (t *activityTracker) prune(now time.Time) {
+	if !t.pruning.CompareAndSwap(false, true) {
+		return
+	}
+	defer t.pruning.Store(false)
+
+	type seen struct {
+		clientID string
+		lastSeen time.Time
+	}
+	var active []seen
+
+	t.clients.Range(func(key, value any) bool {
+		activity := value.(*clientActivity)
+		activity.mu.Lock()
+		lastSeen := activity.lastSeen
+		activity.mu.Unlock()
+
+		if now.Sub(lastSeen) > activityTTL {
+			t.delete(key.(string))
+		} else {
+			active = append(active, seen{clientID: key.(string), lastSeen: lastSeen})
+		}
+		return true
+	})
+
+	if len(active) > maxTrackedClients {
+		sort.Slice(active, func(i, j int) bool { return active[i].lastSeen.Before(active[j].lastSeen) })
+		for _, client := range active[:len(active)-prunedTrackedClients] {
+			t.delete(client.clientID)
+		}
+	}
+}
+
+// delete forgets a client, counting it only if it was still tracked
+func (t *activityTracker) delete(clientID string) {
+	if _, loaded := t.clients.LoadAndDelete(clientID); loaded {
+		t.count.Add(-1)
+	}
+}
+
+// status returns the client's counters without bucket state, and the limit
+// of each bucket in the same order
+func (t *activityTracker) status(clientID string) (ClientStatus, []Limit) {
+	status := ClientStatus{ClientID: clientID, Buckets: []BucketStatus{}}
+	activity, ok := t.lookup(clientID)
+	if !ok {
+		return status, nil
+	}
+
+	activity.mu.Lock()
+	status.Admitted = activity.admitted
+	status.Rejected = activity.rejected
+	status.LastSeen = activity.lastSeen
+	buckets := make([]bucketActivity, 0, len(activity.buckets))
+	for _, bucket := range activity.buckets {
+		buckets = append(buckets, *bucket)
+	}
+	activity.mu.Unlock()
+
+	sort.Slice(buckets, func(i, j int) bool {
+		a, b := buckets[i].limit, buckets[j].limit
+		if a.Layer != b.Layer {
+			return a.Layer < b.Layer
+		}
+		return a.Scope < b.Scope
+	})
+
+	limits := make([]Limit, 0, len(buckets))
+	for _, bucket := range buckets {
+		limits = append(limits, bucket.limit)
+		status.Buckets = append(status.Buckets, BucketStatus{
+			Layer:        bucket.limit.Layer,
+			Scope:        bucket.limit.Scope,
+			Algorithm:    bucket.limit.Algorithm,
+			Rate:         bucket.limit.Rate,
+			Admitted:     bucket.admitted,
+			Rejected:     bucket.rejected,
+			LastRequest:  bucket.lastRequest,
+			LastRejected: bucket.lastRejected,
+		})
+	}
+	return status, limits
+}
+
+// topThrottled returns up to n clients with the most rejected requests
+func (t *activityTracker) topThrottled(n int) []ThrottledClient {
+	var clients []ThrottledClient
+	t.clients.Range(func(key, value any) bool {
+		activity := value.(*clientActivity)
+		activity.mu.Lock()
+		if activity.rejected > 0 {
+			clients = append(clients, ThrottledClient{
+				ClientID:     key.(string),
+				Admitted:     activity.admitted,
+				Rejected:     activity.rejected,
+				LastRejected: activity.lastRejected,
+			})
+		}
+		activity.mu.Unlock()
+		return true
+	})
+
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].Rejected != clients[j].Rejected {
+			return clients[i].Rejected > clients[j].Rejected
+		}
+		return clients[i].ClientID < clients[j].ClientID
+	})
+	if len(clients) > n {
+		clients = clients[:n]
+	}
+	return clients
+}
+
+// stats sums the counters of every tracked client
+func (t *activityTracker) stats() ActivityStats {
+	var stats ActivityStats
+	t.clients.Range(func(key, value any) bool {
+		activity := value.(*clientActivity)
+		activity.mu.Lock()
+		stats.TrackedClients++
+		stats.Admitted += activity.admitted
+		stats.Rejected += activity.rejected
+		activity.mu.Unlock()
+		return true
+	})
+	return stats
+}
@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"kalshi/internal/storage"
+)
+
+// AlgorithmType names a rate limiting algorithm
+type AlgorithmType string
+
+const (
+	// AlgorithmTokenBucket refills Rate tokens per minute into a bucket of
+	// Burst tokens
+	AlgorithmTokenBucket AlgorithmType = "token_bucket"
+	// AlgorithmGCRA is the generic cell rate algorithm: the token bucket's
+	// behaviour kept as a single timestamp per client
+	AlgorithmGCRA AlgorithmType = "gcra"
+	// AlgorithmFixedWindow admits Rate requests per calendar minute
+	AlgorithmFixedWindow AlgorithmType = "fixed_window"
+	// AlgorithmSlidingWindowCounter approximates Rate requests per rolling
+	// minute from the counts of two fixed windows
+	AlgorithmSlidingWindowCounter AlgorithmType = "sliding_window_counter"
+	// AlgorithmSlidingWindowLog admits exactly Rate requests per rolling
+	// minute by recording every request
+	AlgorithmSlidingWindowLog AlgorithmType = "sliding_window_log"
+)
+
+// Algorithm decides whether a client's requests fit a limit and keeps the
+// state that decision needs
+type Algorithm interface {
+	// Take counts n requests for the client on path if they fit
+	Take(ctx context.Context, clientID, path string, n int64) (Result, error)
+	// Reset forgets the client's requests on path
+	Reset(ctx context.Context, clientID, path string) error
+	// Close releases background resources
+	Close()
+}
+
+// NewAlgorithm creates the named algorithm for rate requests per minute
+// with bursts of up to burst. Burst only applies to the bucket algorithms;
+// windows admit rate requests per minute. State is kept in store, or in
+// process when inProcess is set or store cannot run the algorithm
+// atomically.
+func NewAlgorithm(algorithm AlgorithmType, store storage.Storage, rate, burst int, inProcess bool) (Algorithm, error) {
+	bucket := newBucket(burst, rate, time.Minute)
+	window := storage.Window{Limit: int64(rate), Size: time.Minute}
+
+	switch algorithm {
+	case "", AlgorithmTokenBucket:
+		if inProcess {
+			return &localAlgorithm{local: NewLocalLimiter(burst, rate, time.Minute)}, nil
+		}
+		return &tokenBucketAlgorithm{tokenBucket: NewTokenBucket(store, burst, rate, time.Minute)}, nil
+	case AlgorithmGCRA:
+		if inProcess {
+			return &localAlgorithm{local: NewLocalLimiter(burst, rate, time.Minute)}, nil
+		}
+		atomic, owned := algorithmStorage(store)
+		return &gcraAlgorithm{store: atomic, owned: owned, bucket: bucket}, nil
+	case AlgorithmFixedWindow:
+		window.Kind = storage.FixedWindow
+	case AlgorithmSlidingWindowCounter:
+		window.Kind = storage.SlidingWindowCounter
+	case AlgorithmSlidingWindowLog:
+		window.Kind = storage.SlidingWindowLog
+	default:
+		return nil, fmt.Errorf("unknown rate limit algorithm: %s", algorithm)
+	}
+
+	if inProcess {
+		store = nil
+	}
+	atomic, owned := algorithmStorage(store)
+	return &windowAlgorithm{store: atomic, owned: owned, window: window}, nil
+}
+
+// atomicStorage is storage that runs every algorithm atomically
+type atomicStorage interface {
+	storage.Storage
+	storage.AlgorithmStorage
+}
+
+// algorithmStorage returns store when it runs the algorithms atomically, and
+// otherwise a process-local memory storage the caller owns
+func algorithmStorage(store storage.Storage) (atomicStorage, *storage.MemoryStorage) {
+	if atomic, ok := store.(atomicStorage); ok {
+		return atomic, nil
+	}
+	owned := storage.NewMemoryStorage()
+	return owned, owned
+}
+
+// algorithmKey is where an algorithm keeps a client's state for path
+func algorithmKey(prefix, clientID, path string) string {
+	return fmt.Sprintf("%s:ratelimit:%s:%s", prefix, clientID, path)
+}
+
+// resultFrom converts a storage result for a limit of size limit
+func resultFrom(result storage.BucketResult, limit int64) Result {
+	return Result{
+		Allowed:    result.Allowed,
+		Limit:      limit,
+		Remaining:  result.Remaining,
+		RetryAfter: result.RetryAfter,
+		ResetAfter: result.ResetAfter,
+	}
+}
+
+// tokenBucketAlgorithm keeps token buckets in storage
+type tokenBucketAlgorithm struct {
+	tokenBucket *TokenBucket
+}
+
+func (a *tokenBucketAlgorithm) Take(ctx context.Context, clientID, path string, n int64) (Result, error) {
+	result, err := a.tokenBucket.Take(ctx, fmt.Sprintf("ratelimit:%s:%s", clientID, path), n)
+	if err != nil {
+		return Result{}, err
+	}
+	return resultFrom(result, int64(a.tokenBucket.capacity)), nil
+}
+
+func (a *tokenBucketAlgorithm) Reset(ctx context.Context, clientID, path string) error {
+	return a.tokenBucket.Reset(ctx, fmt.Sprintf("ratelimit:%s:%s", clientID, path))
+}
+
+func (a *tokenBucketAlgorithm) Close() {}
+
+// localAlgorithm keeps token buckets in process
+type localAlgorithm struct {
+	local *LocalLimiter
+}
+
+func (a *localAlgorithm) Take(ctx context.Context, clientID, path string, n int64) (Result, error) {
+	return a.local.Take(clientID, path, n), nil
+}
+
+func (a *localAlgorithm) Reset(ctx context.Context, clientID, path string) error {
+	a.local.Reset(clientID, path)
+	return nil
+}
+
+func (a *localAlgorithm) Close() {
+	a.local.Close()
+}
+
+// gcraAlgorithm keeps GCRA buckets in storage
+type gcraAlgorithm struct {
+	store  atomicStorage
+	owned  *storage.MemoryStorage // Set when store is private to the algorithm
+	bucket storage.Bucket
+}
+
+func (a *gcraAlgorithm) Take(ctx context.Context, clientID, path string, n int64) (Result, error) {
+	result, err := a.store.TakeGCRA(ctx, algorithmKey("gcra", clientID, path), a.bucket, n)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take tokens: %w", err)
+	}
+	return resultFrom(result, a.bucket.Capacity), nil
+}
+
+func (a *gcraAlgorithm) Reset(ctx context.Context, clientID, path string) error {
+	return a.store.Delete(ctx, algorithmKey("gcra", clientID, path))
+}
+
+func (a *gcraAlgorithm) Close() {
+	if a.owned != nil {
+		a.owned.Close()
+	}
+}
+
+// windowAlgorithm keeps fixed and sliding windows in storage
+type windowAlgorithm struct {
+	store  atomicStorage
+	owned  *storage.MemoryStorage // Set when store is private to the algorithm
+	window storage.Window
+}
+
+func (a *windowAlgorithm) key(clientID, path string) string {
+	return algorithmKey(string(a.window.Kind), clientID, path)
+}
+
+func (a *windowAlgorithm) Take(ctx context.Context, clientID, path string, n int64) (Result, error) {
+	result, err := a.store.TakeWindow(ctx, a.key(clientID, path), a.window, n)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to count requests: %w", err)
+	}
+	return resultFrom(result, a.window.Limit), nil
+}
+
+func (a *windowAlgorithm) Reset(ctx context.Context, clientID, path string) error {
+	return a.store.Delete(ctx, a.key(clientID, path))
+}
+
+func (a *windowAlgorithm) Close() {
+	if a.owned != nil {
+		a.owned.Close()
+	}
+}
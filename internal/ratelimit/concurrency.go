@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"kalshi/pkg/metrics"
+	"kalshi/pkg/utils"
+)
+
+// LayerConcurrency bounds a client's requests in flight at once, apart from
+// the rate of its requests
+const LayerConcurrency Layer = "concurrency"
+
+// DefaultLeaseTTL is how long a lease outlives a holder that stops renewing
+// it, such as a replica that crashed mid-request
+const DefaultLeaseTTL = 30 * time.Second
+
+// Lease is a client's slot for one request in flight. Release it once the
+// request completes; until then it is renewed in the background so slow
+// requests keep their slot.
+type Lease struct {
+	Acquired bool  // False when the client already has Limit requests in flight
+	InFlight int64 // The client's requests in flight, counting this one if acquired
+	Limit    int64
+
+	limiter  *Limiter
+	key, id  string
+	once     sync.Once
+	renewal  *time.Timer
+	renewMu  sync.Mutex
+	released bool
+}
+
+// leaseKey is where a client's leases are kept in storage
+func leaseKey(clientID string) string {
+	return fmt.Sprintf("concurrency:%s", clientID)
+}
+
+// Acquire takes one of the client's limit concurrent request slots for a
+// request on path. Slots are shared by every path. A lease that was not
+// acquired needs no release. When storage fails the request is
+// given an untracked lease by a fail-open limiter, and rejected with
+// ErrLimiterUnavailable by a fail-closed one.
+func (l *Limiter) Acquire(ctx context.Context, clientID, path string, limit int64) (*Lease, error) {
+	lease := &Lease{Limit: limit, limiter: l, key: leaseKey(clientID), id: utils.GenerateUUID()}
+
+	ctx, cancel := context.WithTimeout(ctx, l.storageTimeout)
+	defer cancel()
+
+	inFlight, acquired, err := l.leases.AcquireLease(ctx, lease.key, lease.id, limit, l.leaseTTL)
+	if err != nil {
+		metrics.RateLimitStorageErrors.WithLabelValues(string(l.failureMode)).Inc()
+		if l.failureMode == FailOpen {
+			return &Lease{Acquired: true, Limit: limit}, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrLimiterUnavailable, err)
+	}
+
+	lease.Acquired, lease.InFlight = acquired, inFlight
+	if !acquired {
+		metrics.RateLimitLayerHits.WithLabelValues(string(LayerConcurrency), path).Inc()
+		return lease, nil
+	}
+
+	lease.renewMu.Lock()
+	lease.renewal = time.AfterFunc(l.leaseTTL/3, lease.renew)
+	lease.renewMu.Unlock()
+	return lease, nil
+}
+
+// renew extends the lease for another TTL and schedules the next renewal.
+// Renewals only extend a lease still held, so one racing Release cannot
+// take the slot again after it is given up.
+func (lease *Lease) renew() {
+	l := lease.limiter
+	ctx, cancel := context.WithTimeout(context.Background(), l.storageTimeout)
+	defer cancel()
+
+	// A failed renewal is retried; the lease only lapses if renewals keep
+	// failing for a whole TTL
+	held, err := l.leases.RenewLease(ctx, lease.key, lease.id, l.leaseTTL)
+	if err == nil && !held {
+		// Released, or expired while storage was unreachable
+		return
+	}
+
+	lease.renewMu.Lock()
+	defer lease.renewMu.Unlock()
+	if !lease.released {
+		lease.renewal.Reset(l.leaseTTL / 3)
+	}
+}
+
+// Release frees the lease's slot for the client's next request. It is safe
+// to call more than once, and on leases that were not acquired.
+func (lease *Lease) Release() {
+	if lease == nil || lease.limiter == nil || !lease.Acquired {
+		return
+	}
+
+	lease.once.Do(func() {
+		lease.renewMu.Lock()
+		lease.released = true
+		lease.renewal.Stop()
+		lease.renewMu.Unlock()
+
+		l := lease.limiter
+		ctx, cancel := context.WithTimeout(context.Background(), l.storageTimeout)
+		defer cancel()
+
+		// A lease that cannot be released expires after its TTL instead
+		if err := l.leases.ReleaseLease(ctx, lease.key, lease.id); err != nil {
+			metrics.RateLimitStorageErrors.WithLabelValues(string(l.failureMode)).Inc()
+		}
+	})
+}
@@ -2,49 +2,382 @@ package ratelimit
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"kalshi/internal/storage"
 	"kalshi/pkg/metrics"
 )
 
+// FailureMode decides what happens to requests when the limiter's storage
+// cannot be reached
+type FailureMode string
+
+const (
+	// FailOpen admits requests without limiting while storage is down
+	FailOpen FailureMode = "open"
+	// FailClosed rejects requests while storage is down
+	FailClosed FailureMode = "closed"
+)
+
+// DefaultStorageTimeout bounds a single rate limit check against storage
+const DefaultStorageTimeout = 100 * time.Millisecond
+
+// ErrLimiterUnavailable is returned by a fail-closed limiter whose storage
+// cannot be reached
+var ErrLimiterUnavailable = errors.New("rate limiter unavailable")
+
+// LimiterOptions configures a Limiter
+type LimiterOptions struct {
+	DefaultRate    int           // Tokens added per minute
+	BurstCapacity  int           // Bucket size
+	FailureMode    FailureMode   // Empty behaves as FailOpen
+	StorageTimeout time.Duration // Bounds each check; zero uses DefaultStorageTimeout
+	Algorithm      AlgorithmType // For layers that do not choose one; empty is the token bucket
+	LeaseTTL       time.Duration // Expiry of concurrent request leases; zero uses DefaultLeaseTTL
+	Schedule       *Schedule     // Adjusts limits by time of the week; nil keeps them fixed
+
+	// InProcess keeps rate limit state in process instead of storage. Limits
+	// then apply per replica, so it suits single replicas and memory storage.
+	InProcess bool
+}
+
 type Limiter struct {
-	storage       storage.Storage
-	defaultRate   int
-	burstCapacity int
-	tokenBucket   *TokenBucket
+	storage        storage.Storage
+	defaultRate    int
+	burstCapacity  int
+	algorithm      AlgorithmType
+	inProcess      bool
+	failureMode    FailureMode
+	storageTimeout time.Duration
+
+	mu         sync.RWMutex
+	algorithms map[bucketShape]Algorithm // Shared by layers with the same algorithm, rate and burst
+
+	activity activityTracker
+
+	leases      storage.LeaseStorage
+	ownedLeases *storage.MemoryStorage // Set when leases are kept in process
+	leaseTTL    time.Duration
+
+	schedule *Schedule
+}
+
+// bucketShape is the algorithm, rate and size of a layer's buckets
+type bucketShape struct {
+	algorithm   AlgorithmType
+	rate, burst int
 }
 
 func NewLimiter(storage storage.Storage, defaultRate, burstCapacity int) *Limiter {
-	tb := NewTokenBucket(storage, burstCapacity, defaultRate, time.Minute)
+	return NewLimiterWithOptions(storage, LimiterOptions{
+		DefaultRate:   defaultRate,
+		BurstCapacity: burstCapacity,
+	})
+}
 
-	return &Limiter{
-		storage:       storage,
-		defaultRate:   defaultRate,
-		burstCapacity: burstCapacity,
-		tokenBucket:   tb,
+// NewLimiterWithOptions creates a limiter with an explicit failure mode
+func NewLimiterWithOptions(store storage.Storage, opts LimiterOptions) *Limiter {
+	if opts.FailureMode == "" {
+		opts.FailureMode = FailOpen
+	}
+	if opts.StorageTimeout <= 0 {
+		opts.StorageTimeout = DefaultStorageTimeout
+	}
+	if opts.Algorithm == "" {
+		opts.Algorithm = AlgorithmTokenBucket
 	}
+	if opts.LeaseTTL <= 0 {
+		opts.LeaseTTL = DefaultLeaseTTL
+	}
+
+	limiter := &Limiter{
+		storage:        store,
+		defaultRate:    opts.DefaultRate,
+		burstCapacity:  opts.BurstCapacity,
+		algorithm:      opts.Algorithm,
+		inProcess:      opts.InProcess,
+		failureMode:    opts.FailureMode,
+		storageTimeout: opts.StorageTimeout,
+		algorithms:     make(map[bucketShape]Algorithm),
+		leaseTTL:       opts.LeaseTTL,
+		schedule:       opts.Schedule,
+	}
+	if leases, ok := store.(storage.LeaseStorage); ok && !opts.InProcess {
+		limiter.leases = leases
+	} else {
+		limiter.ownedLeases = storage.NewMemoryStorage()
+		limiter.leases = limiter.ownedLeases
+	}
+	return limiter
 }
 
+// Allow takes a token from the global layer for the client on path. When
+// storage fails the request is admitted by a fail-open limiter, and rejected
+// with ErrLimiterUnavailable by a fail-closed one.
 func (l *Limiter) Allow(ctx context.Context, clientID, path string) (bool, error) {
-	key := fmt.Sprintf("ratelimit:%s:%s", clientID, path)
+	decision, err := l.Check(ctx, clientID, path)
+	return decision.Allowed, err
+}
 
-	// Use token bucket for rate limiting
-	allowed, err := l.tokenBucket.Allow(ctx, key)
-	if err != nil {
-		return false, err
+// GlobalLimit is the layer every request is checked against, with the rate
+// and burst of the current schedule period where it sets them
+func (l *Limiter) GlobalLimit(path string) Limit {
+	limit := Limit{Layer: LayerGlobal, Scope: path, Rate: l.defaultRate, Burst: l.burstCapacity}
+
+	_, policy := l.SchedulePolicy()
+	if policy.DefaultRate > 0 {
+		limit.Rate = policy.DefaultRate
+	}
+	if policy.BurstCapacity > 0 {
+		limit.Burst = policy.BurstCapacity
+	}
+	return limit
+}
+
+// SchedulePolicy returns the current schedule period and its policy, or an
+// empty period and policy without a schedule
+func (l *Limiter) SchedulePolicy() (SchedulePeriod, SchedulePolicy) {
+	return l.schedule.Policy(time.Now())
+}
+
+// ScheduleStatus returns the current schedule period, or nil without a
+// schedule
+func (l *Limiter) ScheduleStatus() *ScheduleStatus {
+	return l.schedule.Status(time.Now())
+}
+
+// Scheduled returns limit as the current schedule period adjusts it. Only
+// API key layers are scaled; the global layer is adjusted by GlobalLimit.
+func (l *Limiter) Scheduled(limit Limit) Limit {
+	_, policy := l.SchedulePolicy()
+	if limit.Layer == LayerKey {
+		limit.Rate = int(scale(int64(limit.Rate), policy.KeyRateMultiplier))
+		limit.Burst = int(scale(int64(limit.Burst), policy.KeyRateMultiplier))
+	}
+	return limit
+}
+
+// Check takes a token for the client on path from the global layer and each
+// of layers, and admits the request only if every layer does. Layers with a
+// zero rate are skipped. Layers are checked from the lowest rate up and the
+// first rejection ends the check; tokens already taken from earlier layers
+// are not returned. Storage failures are handled as in Allow.
+func (l *Limiter) Check(ctx context.Context, clientID, path string, layers ...Limit) (Decision, error) {
+	return l.CheckN(ctx, clientID, path, 1, layers...)
+}
+
+// CheckN is Check for a request costing cost tokens in every layer. A cost
+// larger than a layer's burst is always rejected by that layer.
+func (l *Limiter) CheckN(ctx context.Context, clientID, path string, cost int64, layers ...Limit) (Decision, error) {
+	if !l.inProcess {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.storageTimeout)
+		defer cancel()
 	}
 
-	if !allowed {
-		// Record rate limit hit
-		metrics.RateLimitHits.WithLabelValues(clientID, path).Inc()
+	limits := l.limitsFor(ctx, clientID, path, layers)
+	sort.SliceStable(limits, func(i, j int) bool { return limits[i].Rate < limits[j].Rate })
+
+	var decision Decision
+	for _, limit := range limits {
+		algorithm, err := l.algorithmFor(limit)
+		if err != nil {
+			return Decision{}, err
+		}
+
+		result, err := algorithm.Take(ctx, clientID, limit.bucketPath(), cost)
+		if err != nil {
+			metrics.RateLimitStorageErrors.WithLabelValues(string(l.failureMode)).Inc()
+			if l.failureMode == FailOpen {
+				return Decision{Allowed: true}, nil
+			}
+			return Decision{}, fmt.Errorf("%w: %v", ErrLimiterUnavailable, err)
+		}
+
+		decision.decide(limit, result)
+		l.activity.record(clientID, limit, result.Allowed)
+		if !result.Allowed {
+			// Record rate limit hit
+			metrics.RateLimitHits.WithLabelValues(path, clientID).Inc()
+			metrics.RateLimitLayerHits.WithLabelValues(string(limit.Layer), path).Inc()
+			break
+		}
 	}
 
-	return allowed, nil
+	if decision.Allowed {
+		l.activity.admitted(clientID)
+	}
+	return decision, nil
 }
 
+// limitsFor returns the layers a client's request on path is checked
+// against: the global layer and those of layers with a rate, or the client's
+// override in place of the global and API key layers. Layers are adjusted
+// for the current schedule period; overrides are not.
+func (l *Limiter) limitsFor(ctx context.Context, clientID, path string, layers []Limit) []Limit {
+	limits := make([]Limit, 0, len(layers)+1)
+	override := l.override(ctx, clientID)
+	if override != nil {
+		limits = append(limits, override.limit(path))
+	} else {
+		limits = append(limits, l.GlobalLimit(path))
+	}
+
+	for _, limit := range layers {
+		if limit.Rate > 0 && (override == nil || limit.Layer != LayerKey) {
+			limits = append(limits, l.Scheduled(limit))
+		}
+	}
+	return limits
+}
+
+// Charge takes n more tokens from every layer for a request that has
+// already been admitted, such as when the backend reports that it cost more
+// than expected. A layer without n tokens left is emptied instead, so the
+// client waits for the refill on its next request.
+func (l *Limiter) Charge(ctx context.Context, clientID, path string, n int64, layers ...Limit) error {
+	if !l.inProcess {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.storageTimeout)
+		defer cancel()
+	}
+
+	for _, limit := range l.limitsFor(ctx, clientID, path, layers) {
+		algorithm, err := l.algorithmFor(limit)
+		if err != nil {
+			return err
+		}
+
+		result, err := algorithm.Take(ctx, clientID, limit.bucketPath(), n)
+		if err == nil && !result.Allowed && result.Remaining > 0 {
+			_, err = algorithm.Take(ctx, clientID, limit.bucketPath(), result.Remaining)
+		}
+		if err != nil {
+			metrics.RateLimitStorageErrors.WithLabelValues(string(l.failureMode)).Inc()
+			return fmt.Errorf("failed to charge %s layer: %w", limit.Layer, err)
+		}
+	}
+
+	return nil
+}
+
+// algorithmFor returns the algorithm keeping a layer's buckets, creating it
+// when missing
+func (l *Limiter) algorithmFor(limit Limit) (Algorithm, error) {
+	shape := bucketShape{algorithm: limit.Algorithm, rate: limit.Rate, burst: limit.burst()}
+	if shape.algorithm == "" {
+		shape.algorithm = l.algorithm
+	}
+
+	l.mu.RLock()
+	algorithm, exists := l.algorithms[shape]
+	l.mu.RUnlock()
+	if exists {
+		return algorithm, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if algorithm, exists = l.algorithms[shape]; exists {
+		return algorithm, nil
+	}
+
+	algorithm, err := NewAlgorithm(shape.algorithm, l.storage, shape.rate, shape.burst, l.inProcess)
+	if err != nil {
+		return nil, err
+	}
+	l.algorithms[shape] = algorithm
+	return algorithm, nil
+}
+
+// Reset clears the client's global bucket for path
 func (l *Limiter) Reset(ctx context.Context, clientID, path string) error {
-	key := fmt.Sprintf("ratelimit:%s:%s", clientID, path)
-	return l.tokenBucket.Reset(ctx, key)
+	limit := l.GlobalLimit(path)
+	algorithm, err := l.algorithmFor(limit)
+	if err != nil {
+		return err
+	}
+	return algorithm.Reset(ctx, clientID, limit.bucketPath())
+}
+
+// Status reports the client's recent requests and the current state of
+// every bucket they used on this replica, without taking tokens
+func (l *Limiter) Status(ctx context.Context, clientID string) (ClientStatus, error) {
+	status, limits := l.activity.status(clientID)
+	status.Override = l.GetOverride(ctx, clientID)
+
+	now := time.Now()
+	for i, limit := range limits {
+		algorithm, err := l.algorithmFor(limit)
+		if err != nil {
+			return ClientStatus{}, err
+		}
+
+		result, err := algorithm.Take(ctx, clientID, limit.bucketPath(), 0)
+		if err != nil {
+			return ClientStatus{}, fmt.Errorf("failed to read %s bucket: %w", limit.Layer, err)
+		}
+
+		bucket := &status.Buckets[i]
+		if bucket.Algorithm == "" {
+			bucket.Algorithm = l.algorithm
+		}
+		bucket.Limit = result.Limit
+		bucket.Remaining = result.Remaining
+		bucket.FullAt = now.Add(result.ResetAfter)
+	}
+
+	return status, nil
+}
+
+// ResetClient refills every bucket the client used on this replica and
+// clears their counters. It returns how many buckets were reset.
+func (l *Limiter) ResetClient(ctx context.Context, clientID string) (int, error) {
+	_, limits := l.activity.status(clientID)
+	for _, limit := range limits {
+		algorithm, err := l.algorithmFor(limit)
+		if err != nil {
+			return 0, err
+		}
+		if err := algorithm.Reset(ctx, clientID, limit.bucketPath()); err != nil {
+			return 0, fmt.Errorf("failed to reset %s bucket: %w", limit.Layer, err)
+		}
+	}
+
+	l.activity.forget(clientID)
+	return len(limits), nil
+}
+
+// TopThrottled returns up to n clients with the most rejected requests on
+// this replica, most rejected first
+func (l *Limiter) TopThrottled(n int) []ThrottledClient {
+	return l.activity.topThrottled(n)
+}
+
+// ActivityStats sums the requests of the clients tracked on this replica
+func (l *Limiter) ActivityStats() ActivityStats {
+	return l.activity.stats()
+}
+
+// Algorithm is the algorithm of layers that do not choose one
+func (l *Limiter) Algorithm() AlgorithmType {
+	return l.algorithm
+}
+
+// Close releases the algorithms' background resources and the limiter's
+// private lease storage
+func (l *Limiter) Close() {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, algorithm := range l.algorithms {
+		algorithm.Close()
+	}
+	if l.ownedLeases != nil {
+		l.ownedLeases.Close()
+	}
 }
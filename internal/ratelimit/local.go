@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultLocalShards is the number of independently locked bucket maps
+	DefaultLocalShards = 64
+	// DefaultLocalCleanupInterval is how often idle buckets are dropped
+	DefaultLocalCleanupInterval = time.Minute
+)
+
+// Result is the outcome of a rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      int64         // Bucket capacity
+	Remaining  int64         // Whole tokens left after the request
+	RetryAfter time.Duration // Until the request could succeed; zero when allowed
+	ResetAfter time.Duration // Until the bucket is full again
+}
+
+// LocalLimiter is an in-process token bucket limiter for a single replica.
+// Buckets are spread over shards by key, and each bucket is one atomic
+// theoretical arrival time (GCRA) updated with compare-and-swap, so checks
+// never block each other and do not allocate once a bucket exists.
+type LocalLimiter struct {
+	shards   []localShard
+	mask     uint32
+	capacity int64
+	interval int64 // Nanoseconds to refill one token
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// localKey identifies a bucket without concatenating strings
+type localKey struct {
+	client, path string
+}
+
+type localShard struct {
+	mu      sync.RWMutex
+	buckets map[localKey]*atomic.Int64 // Theoretical arrival time in Unix nanoseconds
+}
+
+// NewLocalLimiter creates a limiter that admits capacity requests at once and
+// refills rate tokens every period
+func NewLocalLimiter(capacity, rate int, period time.Duration) *LocalLimiter {
+	l := &LocalLimiter{
+		shards:   make([]localShard, DefaultLocalShards),
+		mask:     DefaultLocalShards - 1,
+		capacity: int64(capacity),
+		interval: int64(period) / int64(max(rate, 1)),
+		stop:     make(chan struct{}),
+	}
+	for i := range l.shards {
+		l.shards[i].buckets = make(map[localKey]*atomic.Int64)
+	}
+
+	go l.cleanup(DefaultLocalCleanupInterval)
+
+	return l
+}
+
+// shard selects the shard for a key using FNV-1a over both components
+func (l *LocalLimiter) shard(key localKey) *localShard {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key.client); i++ {
+		hash ^= uint32(key.client[i])
+		hash *= 16777619
+	}
+	hash ^= 0xff // Separates ("ab", "c") from ("a", "bc")
+	hash *= 16777619
+	for i := 0; i < len(key.path); i++ {
+		hash ^= uint32(key.path[i])
+		hash *= 16777619
+	}
+	return &l.shards[hash&l.mask]
+}
+
+// bucket returns the state of a key's bucket, creating it when missing
+func (l *LocalLimiter) bucket(key localKey) *atomic.Int64 {
+	s := l.shard(key)
+
+	s.mu.RLock()
+	tat, exists := s.buckets[key]
+	s.mu.RUnlock()
+	if exists {
+		return tat
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tat, exists = s.buckets[key]; !exists {
+		tat = new(atomic.Int64)
+		s.buckets[key] = tat
+	}
+	return tat
+}
+
+// Allow takes one token for the client on path
+func (l *LocalLimiter) Allow(clientID, path string) bool {
+	return l.Take(clientID, path, 1).Allowed
+}
+
+// Take takes n tokens for the client on path if enough are available. A
+// bucket is full when its theoretical arrival time is in the past; each
+// token pushes it one interval further, and a request is admitted while it
+// stays within capacity intervals of now.
+func (l *LocalLimiter) Take(clientID, path string, n int64) Result {
+	state := l.bucket(localKey{client: clientID, path: path})
+	burst := l.capacity * l.interval
+
+	for {
+		now := time.Now().UnixNano()
+		stored := state.Load()
+		tat := max(stored, now)
+
+		next := tat + n*l.interval
+		if next-now > burst {
+			return Result{
+				Limit:      l.capacity,
+				Remaining:  (burst - (tat - now)) / l.interval,
+				RetryAfter: time.Duration(next - now - burst),
+				ResetAfter: time.Duration(tat - now),
+			}
+		}
+
+		if state.CompareAndSwap(stored, next) {
+			return Result{
+				Allowed:    true,
+				Limit:      l.capacity,
+				Remaining:  (burst - (next - now)) / l.interval,
+				ResetAfter: time.Duration(next - now),
+			}
+		}
+	}
+}
+
+// Reset refills the client's bucket for path
+func (l *LocalLimiter) Reset(clientID, path string) {
+	key := localKey{client: clientID, path: path}
+	s := l.shard(key)
+	s.mu.Lock()
+	delete(s.buckets, key)
+	s.mu.Unlock()
+}
+
+// Close stops the background cleanup
+func (l *LocalLimiter) Close() {
+	l.stopOnce.Do(func() { close(l.stop) })
+}
+
+// cleanup periodically drops full buckets, which behave exactly like
+// missing ones. A check racing with the removal may update the dropped
+// bucket, so at most the tokens of that one check are forgotten.
+func (l *LocalLimiter) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		for i := range l.shards {
+			s := &l.shards[i]
+			s.mu.Lock()
+			now := time.Now().UnixNano()
+			for key, tat := range s.buckets {
+				if tat.Load() <= now {
+					delete(s.buckets, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
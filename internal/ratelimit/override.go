@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LayerOverride is a client's temporary limit set by an operator. It
+// replaces the global and API key layers until it expires.
+const LayerOverride Layer = "override"
+
+// overrideRefresh is how long a replica trusts its cached copy of a client's
+// override before reading storage again
+const overrideRefresh = 5 * time.Second
+
+// ErrInvalidOverride is returned for overrides without a rate or expiry
+var ErrInvalidOverride = errors.New("invalid rate limit override")
+
+// Override raises or lowers a client's limit until ExpiresAt
+type Override struct {
+	Rate      int       `json:"rate"`            // Requests per minute
+	Burst     int       `json:"burst,omitempty"` // Zero allows a full minute's rate at once
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// active reports whether the override applies at now
+func (o *Override) active(now time.Time) bool {
+	return o != nil && now.Before(o.ExpiresAt)
+}
+
+// limit is the override's layer for path
+func (o *Override) limit(path string) Limit {
+	return Limit{Layer: LayerOverride, Scope: path, Rate: o.Rate, Burst: o.Burst}
+}
+
+// overrideKey is where a client's override is kept in storage
+func overrideKey(clientID string) string {
+	return fmt.Sprintf("ratelimit:override:%s", clientID)
+}
+
+// SetOverride replaces the client's global and API key limits with override
+// until it expires. Route limits still apply. The override is kept in
+// storage, so every replica sharing it applies the override within
+// overrideRefresh.
+func (l *Limiter) SetOverride(ctx context.Context, clientID string, override Override) error {
+	ttl := time.Until(override.ExpiresAt)
+	if override.Rate <= 0 || override.Burst < 0 || ttl <= 0 {
+		return ErrInvalidOverride
+	}
+
+	data, err := json.Marshal(override)
+	if err != nil {
+		return fmt.Errorf("failed to encode override: %w", err)
+	}
+	if err := l.storage.Set(ctx, overrideKey(clientID), string(data), ttl); err != nil {
+		return fmt.Errorf("failed to store override: %w", err)
+	}
+
+	l.cacheOverride(clientID, &override)
+	return nil
+}
+
+// RemoveOverride restores the client's configured limits
+func (l *Limiter) RemoveOverride(ctx context.Context, clientID string) error {
+	if err := l.storage.Delete(ctx, overrideKey(clientID)); err != nil {
+		return fmt.Errorf("failed to delete override: %w", err)
+	}
+
+	l.cacheOverride(clientID, nil)
+	return nil
+}
+
+// GetOverride returns the client's active override, or nil
+func (l *Limiter) GetOverride(ctx context.Context, clientID string) *Override {
+	override := l.loadOverride(ctx, clientID)
+	l.cacheOverride(clientID, override)
+	return override
+}
+
+// override returns the client's active override, reading storage only when
+// the cached copy is older than overrideRefresh
+func (l *Limiter) override(ctx context.Context, clientID string) *Override {
+	now := time.Now()
+	activity := l.activity.client(clientID)
+
+	activity.mu.Lock()
+	override, checkedAt := activity.override, activity.overrideCheckedAt
+	activity.mu.Unlock()
+
+	if now.Sub(checkedAt) > overrideRefresh {
+		override = l.loadOverride(ctx, clientID)
+		l.cacheOverride(clientID, override)
+	}
+	if !override.active(now) {
+		return nil
+	}
+	return override
+}
+
+// loadOverride reads the client's override from storage. A missing or
+// unreadable override counts as none.
+func (l *Limiter) loadOverride(ctx context.Context, clientID string) *Override {
+	data, err := l.storage.Get(ctx, overrideKey(clientID))
+	if err != nil {
+		return nil
+	}
+
+	var override Override
+	if err := json.Unmarshal([]byte(data), &override); err != nil || !override.active(time.Now()) {
+		return nil
+	}
+	return &override
+}
+
+// cacheOverride remembers the client's override on this replica
+func (l *Limiter) cacheOverride(clientID string, override *Override) {
+	activity := l.activity.client(clientID)
+	activity.mu.Lock()
+	activity.override = override
+	activity.overrideCheckedAt = time.Now()
+	activity.mu.Unlock()
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+)
+
+// Layer names one level of a layered rate limit policy
+type Layer string
+
+const (
+	// LayerGlobal is the default rate every client gets on every path
+	LayerGlobal Layer = "global"
+	// LayerRoute is a configured route's rate_limit
+	LayerRoute Layer = "route"
+	// LayerKey is an API key's rate_limit
+	LayerKey Layer = "key"
+)
+
+// Limit is the rate one layer allows a client: Rate requests per minute with
+// up to Burst at once. Each layer keeps its own bucket per client and Scope.
+type Limit struct {
+	Layer     Layer
+	Scope     string // What the bucket covers besides the client, e.g. a route pattern
+	Rate      int
+	Burst     int           // Zero allows a full minute's rate at once
+	Algorithm AlgorithmType // Empty uses the limiter's algorithm
+}
+
+// RouteLimit is the route layer for a route's pattern and rate_limit
+func RouteLimit(pattern string, rate int) Limit {
+	return Limit{Layer: LayerRoute, Scope: pattern, Rate: rate}
+}
+
+// KeyLimit is the API key layer for a key's rate_limit. It covers every path
+// the key is used on.
+func KeyLimit(rate int) Limit {
+	return Limit{Layer: LayerKey, Rate: rate}
+}
+
+// burst is how many tokens the layer's bucket holds
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+// bucketPath distinguishes the layer's buckets for one client. The global
+// layer keeps the plain path so existing buckets stay valid.
+func (l Limit) bucketPath() string {
+	if l.Layer == LayerGlobal {
+		return l.Scope
+	}
+	return fmt.Sprintf("%s:%s", l.Layer, l.Scope)
+}
+
+// Decision is the outcome of checking every layer of a policy. A rejected
+// request reports the layer that rejected it; an admitted one reports the
+// layer with the fewest tokens left, which is the limit the client is
+// closest to.
+type Decision struct {
+	Allowed    bool
+	Layer      Layer
+	Rate       int   // Requests per minute of the deciding layer
+	Limit      int64 // Bucket size of the deciding layer
+	Remaining  int64
+	RetryAfter time.Duration // Zero when allowed
+	ResetAfter time.Duration
+}
+
+// decide records one layer's result, keeping the most restrictive
+func (d *Decision) decide(limit Limit, result Result) {
+	if d.Layer != "" && result.Allowed && result.Remaining >= d.Remaining {
+		return
+	}
+	*d = Decision{
+		Allowed:    result.Allowed,
+		Layer:      limit.Layer,
+		Rate:       limit.Rate,
+		Limit:      result.Limit,
+		Remaining:  result.Remaining,
+		RetryAfter: result.RetryAfter,
+		ResetAfter: result.ResetAfter,
+	}
+}
@@ -0,0 +1,317 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"kalshi/internal/storage"
+	"kalshi/pkg/metrics"
+	"kalshi/pkg/utils"
+)
+
+// QuotaPeriod is the calendar period a usage quota covers
+type QuotaPeriod string
+
+const (
+	// QuotaDaily resets at midnight
+	QuotaDaily QuotaPeriod = "daily"
+	// QuotaMonthly resets at midnight on the first of the month
+	QuotaMonthly QuotaPeriod = "monthly"
+)
+
+// quotaRetention keeps a period's counter around briefly after it ends
+const quotaRetention = time.Hour
+
+// Bounds returns the start of the period containing t and the start of the
+// next one, in t's location. Unknown periods return zero times.
+func (p QuotaPeriod) Bounds(t time.Time) (start, end time.Time) {
+	switch p {
+	case QuotaDaily:
+		start = utils.StartOfDay(t)
+		return start, start.AddDate(0, 0, 1)
+	case QuotaMonthly:
+		start = utils.StartOfMonth(t)
+		return start, start.AddDate(0, 1, 0)
+	}
+	return time.Time{}, time.Time{}
+}
+
+// stamp names the period starting at start in counter keys
+func (p QuotaPeriod) stamp(start time.Time) string {
+	if p == QuotaMonthly {
+		return start.Format("2006-01")
+	}
+	return start.Format("2006-01-02")
+}
+
+// Quota allows a client Limit request units per Period
+type Quota struct {
+	Period QuotaPeriod
+	Limit  int64
+}
+
+// QuotaUsage is a client's standing against one quota
+type QuotaUsage struct {
+	Period    QuotaPeriod `json:"period"`
+	Limit     int64       `json:"limit"`
+	Used      int64       `json:"used"`
+	Remaining int64       `json:"remaining"`
+	ResetsAt  time.Time   `json:"resets_at"`
+	Exceeded  bool        `json:"exceeded,omitempty"` // The request asked for more than remained
+	Warning   float64     `json:"warning,omitempty"`  // Highest warning threshold reached
+}
+
+// QuotaDecision is the outcome of consuming from every quota of a client
+type QuotaDecision struct {
+	Allowed bool
+	Usage   []QuotaUsage // Empty when no quota applied or storage failed open
+}
+
+// Tightest returns the exceeded quota of a rejected request, and otherwise
+// the quota with the fewest units left. It returns nil without usage.
+func (d QuotaDecision) Tightest() *QuotaUsage {
+	var tightest *QuotaUsage
+	for i := range d.Usage {
+		usage := &d.Usage[i]
+		if usage.Exceeded {
+			return usage
+		}
+		if tightest == nil || usage.Remaining < tightest.Remaining {
+			tightest = usage
+		}
+	}
+	return tightest
+}
+
+// QuotaOptions configures a QuotaManager
+type QuotaOptions struct {
+	Location          *time.Location // Where periods start; nil is UTC
+	WarningThresholds []float64      // Fractions of a quota, e.g. 0.8, that raise a warning once reached
+	FailureMode       FailureMode    // Empty behaves as FailOpen
+	StorageTimeout    time.Duration  // Bounds each consumption; zero uses DefaultStorageTimeout
+	Schedule          *Schedule      // Scales quotas by time of the week; nil keeps them fixed
+}
+
+// quotaStorage is storage that keeps quota counters atomically
+type quotaStorage interface {
+	storage.Storage
+	storage.QuotaStorage
+}
+
+// QuotaManager counts clients' usage against daily and monthly quotas.
+// Counters reset on calendar boundaries in the configured location.
+type QuotaManager struct {
+	store          quotaStorage
+	owned          *storage.MemoryStorage // Set when store is private to the manager
+	location       *time.Location
+	warnings       []float64
+	failureMode    FailureMode
+	storageTimeout time.Duration
+	schedule       *Schedule
+}
+
+// NewQuotaManager creates a quota manager keeping its counters in store, or
+// in process when store cannot keep them atomically
+func NewQuotaManager(store storage.Storage, opts QuotaOptions) *QuotaManager {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if opts.FailureMode == "" {
+		opts.FailureMode = FailOpen
+	}
+	if opts.StorageTimeout <= 0 {
+		opts.StorageTimeout = DefaultStorageTimeout
+	}
+
+	warnings := append([]float64(nil), opts.WarningThresholds...)
+	sort.Float64s(warnings)
+
+	manager := &QuotaManager{
+		location:       opts.Location,
+		warnings:       warnings,
+		failureMode:    opts.FailureMode,
+		storageTimeout: opts.StorageTimeout,
+		schedule:       opts.Schedule,
+	}
+	if atomic, ok := store.(quotaStorage); ok {
+		manager.store = atomic
+	} else {
+		manager.owned = storage.NewMemoryStorage()
+		manager.store = manager.owned
+	}
+	return manager
+}
+
+// Consume counts n request units for the client against each quota, and
+// admits the request only if every quota has n units left. While the
+// current schedule period scales quotas, the scaled limits apply to the
+// period's usage so far. A rejected request is not counted against any
+// quota. When storage fails the request
+// is admitted by a fail-open manager, and rejected with
+// ErrLimiterUnavailable by a fail-closed one.
+func (m *QuotaManager) Consume(ctx context.Context, clientID string, quotas []Quota, n int64) (QuotaDecision, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.storageTimeout)
+	defer cancel()
+
+	_, policy := m.schedule.Policy(time.Now())
+	decision := QuotaDecision{Allowed: true}
+	var consumed []quotaCounter
+	for _, quota := range quotas {
+		if quota.Limit <= 0 {
+			continue
+		}
+		quota.Limit = scale(quota.Limit, policy.QuotaMultiplier)
+
+		usage, counter, err := m.take(ctx, clientID, quota, n)
+		if err != nil {
+			m.refund(ctx, consumed, n)
+			metrics.RateLimitStorageErrors.WithLabelValues(string(m.failureMode)).Inc()
+			if m.failureMode == FailOpen {
+				return QuotaDecision{Allowed: true}, nil
+			}
+			return QuotaDecision{}, fmt.Errorf("%w: %v", ErrLimiterUnavailable, err)
+		}
+
+		decision.Usage = append(decision.Usage, usage)
+		if usage.Exceeded {
+			m.refund(ctx, consumed, n)
+			metrics.QuotaExceeded.WithLabelValues(string(quota.Period)).Inc()
+			decision.Allowed = false
+			return decision, nil
+		}
+		consumed = append(consumed, counter)
+		m.recordWarnings(quota, usage.Used-n, usage.Used)
+	}
+
+	return decision, nil
+}
+
+// Charge counts n more units against each quota for a request that has
+// already been admitted, such as when the backend reports that it cost more
+// than expected. Usage may then exceed a quota until it resets.
+func (m *QuotaManager) Charge(ctx context.Context, clientID string, quotas []Quota, n int64) error {
+	ctx, cancel := context.WithTimeout(ctx, m.storageTimeout)
+	defer cancel()
+
+	for _, quota := range quotas {
+		if quota.Limit <= 0 {
+			continue
+		}
+		usage, _, err := m.take(ctx, clientID, Quota{Period: quota.Period, Limit: math.MaxInt64}, n)
+		if err != nil {
+			metrics.RateLimitStorageErrors.WithLabelValues(string(m.failureMode)).Inc()
+			return err
+		}
+		m.recordWarnings(quota, usage.Used-n, usage.Used)
+	}
+	return nil
+}
+
+// Usage reports the client's standing against each quota without counting
+// a request
+func (m *QuotaManager) Usage(ctx context.Context, clientID string, quotas []Quota) ([]QuotaUsage, error) {
+	usages := make([]QuotaUsage, 0, len(quotas))
+	for _, quota := range quotas {
+		if quota.Limit <= 0 {
+			continue
+		}
+		usage, _, err := m.take(ctx, clientID, quota, 0)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+// Reset clears the client's usage for the current period of each of periods
+func (m *QuotaManager) Reset(ctx context.Context, clientID string, periods ...QuotaPeriod) error {
+	now := time.Now().In(m.location)
+	for _, period := range periods {
+		start, _ := period.Bounds(now)
+		if start.IsZero() {
+			return fmt.Errorf("unknown quota period: %s", period)
+		}
+		if err := m.store.Delete(ctx, m.key(clientID, period, start)); err != nil {
+			return fmt.Errorf("failed to reset %s quota: %w", period, err)
+		}
+	}
+	return nil
+}
+
+// Close releases the manager's private storage
+func (m *QuotaManager) Close() {
+	if m.owned != nil {
+		m.owned.Close()
+	}
+}
+
+// quotaCounter is the storage counter of one quota period
+type quotaCounter struct {
+	key string
+	ttl time.Duration
+}
+
+// take counts n units against the quota's current period, returning the
+// usage and the period's counter
+func (m *QuotaManager) take(ctx context.Context, clientID string, quota Quota, n int64) (QuotaUsage, quotaCounter, error) {
+	now := time.Now().In(m.location)
+	start, end := quota.Period.Bounds(now)
+	if start.IsZero() {
+		return QuotaUsage{}, quotaCounter{}, fmt.Errorf("unknown quota period: %s", quota.Period)
+	}
+
+	counter := quotaCounter{key: m.key(clientID, quota.Period, start), ttl: end.Sub(now) + quotaRetention}
+	used, allowed, err := m.store.ConsumeQuota(ctx, counter.key, quota.Limit, n, counter.ttl)
+	if err != nil {
+		return QuotaUsage{}, quotaCounter{}, fmt.Errorf("failed to count quota usage: %w", err)
+	}
+
+	return QuotaUsage{
+		Period:    quota.Period,
+		Limit:     quota.Limit,
+		Used:      used,
+		Remaining: max(quota.Limit-used, 0),
+		ResetsAt:  end,
+		Exceeded:  !allowed,
+		Warning:   m.warning(quota.Limit, used),
+	}, counter, nil
+}
+
+// refund returns n units to counters taken earlier in a rejected request
+func (m *QuotaManager) refund(ctx context.Context, counters []quotaCounter, n int64) {
+	for _, counter := range counters {
+		m.store.ConsumeQuota(ctx, counter.key, 0, -n, counter.ttl)
+	}
+}
+
+// key is where the client's usage of the period starting at start is counted
+func (m *QuotaManager) key(clientID string, period QuotaPeriod, start time.Time) string {
+	return fmt.Sprintf("quota:%s:%s:%s", period, clientID, period.stamp(start))
+}
+
+// warning is the highest threshold used units of limit have reached
+func (m *QuotaManager) warning(limit, used int64) float64 {
+	var reached float64
+	for _, threshold := range m.warnings {
+		if float64(used) >= threshold*float64(limit) {
+			reached = threshold
+		}
+	}
+	return reached
+}
+
+// recordWarnings counts the thresholds crossed by usage going from before
+// to after, so each is reported once per period
+func (m *QuotaManager) recordWarnings(quota Quota, before, after int64) {
+	for _, threshold := range m.warnings {
+		mark := threshold * float64(quota.Limit)
+		if float64(before) < mark && float64(after) >= mark {
+			metrics.QuotaWarnings.WithLabelValues(string(quota.Period), strconv.FormatFloat(threshold, 'g', -1, 64)).Inc()
+		}
+	}
+}
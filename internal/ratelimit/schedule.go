@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"kalshi/pkg/utils"
+)
+
+// SchedulePeriod names a part of the week with its own limits
+type SchedulePeriod string
+
+const (
+	// PeriodTrading is a business day between the opening and closing time
+	PeriodTrading SchedulePeriod = "trading"
+	// PeriodOffHours is a business day outside trading hours
+	PeriodOffHours SchedulePeriod = "off_hours"
+	// PeriodWeekend is Saturday and Sunday
+	PeriodWeekend SchedulePeriod = "weekend"
+)
+
+// SchedulePolicy adjusts limits while its period applies. Zero fields keep
+// the configured limits.
+type SchedulePolicy struct {
+	DefaultRate       int     `json:"default_rate,omitempty"`        // Global layer rate
+	BurstCapacity     int     `json:"burst_capacity,omitempty"`      // Global layer burst
+	KeyRateMultiplier float64 `json:"key_rate_multiplier,omitempty"` // Scales API key rates and bursts
+	MaxConcurrent     int     `json:"max_concurrent,omitempty"`      // Default requests in flight per client
+	QuotaMultiplier   float64 `json:"quota_multiplier,omitempty"`    // Scales every quota
+}
+
+// ScheduleStatus is the schedule period in force and when it ends
+type ScheduleStatus struct {
+	Period SchedulePeriod `json:"period"`
+	Policy SchedulePolicy `json:"policy"`
+	Until  time.Time      `json:"until"`
+}
+
+// Schedule picks the policy for the time of the week in its location.
+// A nil schedule applies no policy.
+type Schedule struct {
+	location    *time.Location
+	open, close time.Duration // Wall clock time since midnight
+	policies    map[SchedulePeriod]SchedulePolicy
+}
+
+// NewSchedule creates a schedule whose trading hours run from open to close
+// on business days in timezone. open and close are wall clock times since
+// midnight, so trading hours follow daylight saving time.
+func NewSchedule(timezone string, open, close time.Duration, policies map[SchedulePeriod]SchedulePolicy) (*Schedule, error) {
+	now, err := utils.ConvertTimezone(time.Now(), timezone)
+	if err != nil {
+		return nil, err
+	}
+	if open < 0 || close > 24*time.Hour || open >= close {
+		return nil, fmt.Errorf("trading hours must open before they close, got %v to %v", open, close)
+	}
+
+	return &Schedule{
+		location: now.Location(),
+		open:     open,
+		close:    close,
+		policies: policies,
+	}, nil
+}
+
+// Period returns the part of the week t falls in
+func (s *Schedule) Period(t time.Time) SchedulePeriod {
+	local := t.In(s.location)
+	if !utils.IsBusinessDay(local) {
+		return PeriodWeekend
+	}
+
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second
+	if clock >= s.open && clock < s.close {
+		return PeriodTrading
+	}
+	return PeriodOffHours
+}
+
+// Policy returns the period t falls in and its policy. A nil schedule
+// returns an empty period and policy.
+func (s *Schedule) Policy(t time.Time) (SchedulePeriod, SchedulePolicy) {
+	if s == nil {
+		return "", SchedulePolicy{}
+	}
+	period := s.Period(t)
+	return period, s.policies[period]
+}
+
+// Status returns the period t falls in, its policy and when it ends, or
+// nil for a nil schedule
+func (s *Schedule) Status(t time.Time) *ScheduleStatus {
+	if s == nil {
+		return nil
+	}
+	period, policy := s.Policy(t)
+	return &ScheduleStatus{Period: period, Policy: policy, Until: s.PeriodEnd(t)}
+}
+
+// PeriodEnd returns when the period t falls in gives way to the next one
+func (s *Schedule) PeriodEnd(t time.Time) time.Time {
+	local := t.In(s.location)
+	today := utils.StartOfDay(local)
+	tomorrow := utils.StartOfDay(today.AddDate(0, 0, 1))
+
+	switch s.Period(local) {
+	case PeriodTrading:
+		return s.at(today, s.close)
+	case PeriodOffHours:
+		if opens := s.at(today, s.open); local.Before(opens) {
+			return opens
+		}
+		if !utils.IsBusinessDay(tomorrow) {
+			return tomorrow
+		}
+		return s.at(tomorrow, s.open)
+	default:
+		// The weekend ends at midnight before the next business day
+		return utils.StartOfDay(utils.NextBusinessDay(today))
+	}
+}
+
+// at is the wall clock time clock after midnight on day
+func (s *Schedule) at(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, int(clock/time.Second), 0, s.location)
+}
+
+// scale multiplies n by multiplier, keeping at least one. A zero multiplier
+// keeps n.
+func scale(n int64, multiplier float64) int64 {
+	if multiplier <= 0 || n <= 0 {
+		return n
+	}
+	return max(int64(math.Round(float64(n)*multiplier)), 1)
+}
@@ -0,0 +1,215 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"kalshi/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Status(t *testing.T) {
+	for name, limiter := range layeredLimiters(t, 60, 5) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			route := ratelimit.RouteLimit("/api/*", 2)
+
+			for i := 0; i < 3; i++ {
+				limiter.Check(ctx, "client1", "/api/test", route)
+			}
+
+			status, err := limiter.Status(ctx, "client1")
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), status.Admitted)
+			assert.Equal(t, int64(1), status.Rejected)
+			require.Len(t, status.Buckets, 2)
+
+			global, routeBucket := status.Buckets[0], status.Buckets[1]
+			assert.Equal(t, ratelimit.LayerGlobal, global.Layer)
+			assert.Equal(t, "/api/test", global.Scope)
+			assert.Equal(t, ratelimit.AlgorithmTokenBucket, global.Algorithm)
+			assert.Equal(t, int64(5), global.Limit)
+			assert.Equal(t, int64(3), global.Remaining)
+			assert.Equal(t, int64(2), global.Admitted)
+			assert.True(t, global.FullAt.After(time.Now()))
+
+			assert.Equal(t, ratelimit.LayerRoute, routeBucket.Layer)
+			assert.Equal(t, int64(0), routeBucket.Remaining)
+			assert.Equal(t, int64(1), routeBucket.Rejected)
+			assert.False(t, routeBucket.LastRejected.IsZero())
+
+			// Reading the status takes no tokens
+			again, err := limiter.Status(ctx, "client1")
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), again.Buckets[0].Remaining)
+
+			unknown, err := limiter.Status(ctx, "unknown")
+			require.NoError(t, err)
+			assert.Empty(t, unknown.Buckets)
+		})
+	}
+}
+
+func TestLimiter_ResetClient(t *testing.T) {
+	for name, limiter := range layeredLimiters(t, 60, 1) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := ratelimit.KeyLimit(1)
+
+			limiter.Check(ctx, "client1", "/api/a", key)
+			decision, _ := limiter.Check(ctx, "client1", "/api/b", key)
+			require.False(t, decision.Allowed)
+
+			reset, err := limiter.ResetClient(ctx, "client1")
+			require.NoError(t, err)
+			assert.Equal(t, 2, reset, "the key bucket rejected /api/b before its global bucket was used")
+
+			decision, err = limiter.Check(ctx, "client1", "/api/b", key)
+			require.NoError(t, err)
+			assert.True(t, decision.Allowed)
+
+			status, _ := limiter.Status(ctx, "client1")
+			assert.Equal(t, int64(0), status.Rejected)
+		})
+	}
+}
+
+func TestLimiter_TopThrottled(t *testing.T) {
+	for name, limiter := range layeredLimiters(t, 60, 1) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for client, requests := range map[string]int{"light": 2, "heavy": 5, "polite": 1} {
+				for i := 0; i < requests; i++ {
+					limiter.Check(ctx, client, "/api/test")
+				}
+			}
+
+			top := limiter.TopThrottled(2)
+			require.Len(t, top, 2)
+			assert.Equal(t, "heavy", top[0].ClientID)
+			assert.Equal(t, int64(4), top[0].Rejected)
+			assert.Equal(t, "light", top[1].ClientID)
+
+			stats := limiter.ActivityStats()
+			assert.Equal(t, 3, stats.TrackedClients)
+			assert.Equal(t, int64(3), stats.Admitted)
+			assert.Equal(t, int64(5), stats.Rejected)
+		})
+	}
+}
+
+func TestLimiter_Override(t *testing.T) {
+	for name, limiter := range layeredLimiters(t, 60, 2) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := ratelimit.KeyLimit(1)
+
+			// The override raises the global limit and replaces the key's
+			require.NoError(t, limiter.SetOverride(ctx, "partner", ratelimit.Override{
+				Rate: 600, Burst: 5, ExpiresAt: time.Now().Add(time.Hour),
+			}))
+			for i := 0; i < 5; i++ {
+				decision, err := limiter.Check(ctx, "partner", "/api/test", key)
+				require.NoError(t, err)
+				require.True(t, decision.Allowed)
+				assert.Equal(t, ratelimit.LayerOverride, decision.Layer)
+			}
+			decision, _ := limiter.Check(ctx, "partner", "/api/test", key)
+			assert.False(t, decision.Allowed)
+
+			// Route limits still apply
+			decision, _ = limiter.Check(ctx, "partner", "/api/route", ratelimit.RouteLimit("/api/route", 1))
+			assert.True(t, decision.Allowed)
+			decision, _ = limiter.Check(ctx, "partner", "/api/route", ratelimit.RouteLimit("/api/route", 1))
+			assert.Equal(t, ratelimit.LayerRoute, decision.Layer)
+			assert.False(t, decision.Allowed)
+
+			status, err := limiter.Status(ctx, "partner")
+			require.NoError(t, err)
+			require.NotNil(t, status.Override)
+			assert.Equal(t, 600, status.Override.Rate)
+
+			// Without the override the key's limit applies again
+			require.NoError(t, limiter.RemoveOverride(ctx, "partner"))
+			decision, _ = limiter.Check(ctx, "partner", "/api/other", key)
+			assert.True(t, decision.Allowed)
+			decision, _ = limiter.Check(ctx, "partner", "/api/other", key)
+			assert.False(t, decision.Allowed)
+			assert.Equal(t, ratelimit.LayerKey, decision.Layer)
+		})
+	}
+}
+
+func TestLimiter_OverrideExpires(t *testing.T) {
+	for name, limiter := range layeredLimiters(t, 60, 1) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, limiter.SetOverride(ctx, "client1", ratelimit.Override{
+				Rate: 1, ExpiresAt: time.Now().Add(50 * time.Millisecond),
+			}))
+
+			decision, _ := limiter.Check(ctx, "client1", "/api/test")
+			assert.Equal(t, ratelimit.LayerOverride, decision.Layer)
+
+			time.Sleep(60 * time.Millisecond)
+			decision, _ = limiter.Check(ctx, "client1", "/api/test")
+			assert.Equal(t, ratelimit.LayerGlobal, decision.Layer)
+			assert.Nil(t, limiter.GetOverride(ctx, "client1"))
+		})
+	}
+}
+
+func TestLimiter_InvalidOverride(t *testing.T) {
+	limiter := ratelimit.NewLimiter(NewMockStorage(), 60, 10)
+	defer limiter.Close()
+	ctx := context.Background()
+
+	for _, override := range []ratelimit.Override{
+		{Rate: 0, ExpiresAt: time.Now().Add(time.Hour)},
+		{Rate: 10, ExpiresAt: time.Now().Add(-time.Minute)},
+		{Rate: 10, Burst: -1, ExpiresAt: time.Now().Add(time.Hour)},
+	} {
+		assert.ErrorIs(t, limiter.SetOverride(ctx, "client1", override), ratelimit.ErrInvalidOverride)
+	}
+}
+
+func TestLimiter_ActivityBounded(t *testing.T) {
+	if testing.Short() {
+		t.Skip("tracks over a hundred thousand clients")
+	}
+	limiter := ratelimit.NewLimiterWithOptions(NewMockStorage(), ratelimit.LimiterOptions{
+		DefaultRate:   60,
+		BurstCapacity: 10,
+		InProcess:     true,
+	})
+	defer limiter.Close()
+	ctx := context.Background()
+
+	// Clients arriving at once past the bound all count towards it
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 13000; i++ {
+				limiter.Allow(ctx, fmt.Sprintf("client-%d-%d", g, i), "/api/test")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Pruning evicts well below the bound, so new clients do not each prune
+	tracked := limiter.ActivityStats().TrackedClients
+	assert.LessOrEqual(t, tracked, 100000)
+	assert.GreaterOrEqual(t, tracked, 90000)
+
+	for i := 0; i < 1000; i++ {
+		limiter.Allow(ctx, fmt.Sprintf("late-%d", i), "/api/test")
+	}
+	assert.Equal(t, tracked+1000, limiter.ActivityStats().TrackedClients)
+}
@@ -0,0 +1,129 @@
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kalshi/internal/ratelimit"
+	"kalshi/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var allAlgorithms = []ratelimit.AlgorithmType{
+	ratelimit.AlgorithmTokenBucket,
+	ratelimit.AlgorithmGCRA,
+	ratelimit.AlgorithmFixedWindow,
+	ratelimit.AlgorithmSlidingWindowCounter,
+	ratelimit.AlgorithmSlidingWindowLog,
+}
+
+func TestNewAlgorithm_Limit(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	for _, inProcess := range []bool{false, true} {
+		for _, name := range allAlgorithms {
+			t.Run(string(name), func(t *testing.T) {
+				algorithm, err := ratelimit.NewAlgorithm(name, store, 3, 3, inProcess)
+				require.NoError(t, err)
+				defer algorithm.Close()
+				ctx := context.Background()
+
+				for i := int64(2); i >= 0; i-- {
+					result, err := algorithm.Take(ctx, "client1", "/api/test", 1)
+					require.NoError(t, err)
+					assert.True(t, result.Allowed)
+					assert.Equal(t, i, result.Remaining)
+					assert.Equal(t, int64(3), result.Limit)
+				}
+
+				result, err := algorithm.Take(ctx, "client1", "/api/test", 1)
+				require.NoError(t, err)
+				assert.False(t, result.Allowed)
+				assert.Positive(t, result.RetryAfter)
+
+				// Other clients are unaffected, and a reset client starts over
+				result, _ = algorithm.Take(ctx, "client2", "/api/test", 1)
+				assert.True(t, result.Allowed)
+				require.NoError(t, algorithm.Reset(ctx, "client1", "/api/test"))
+				result, _ = algorithm.Take(ctx, "client1", "/api/test", 1)
+				assert.True(t, result.Allowed)
+			})
+		}
+	}
+}
+
+func TestNewAlgorithm_WithoutAtomicStorage(t *testing.T) {
+	// Storage without the algorithm operations keeps state in process
+	for _, name := range allAlgorithms {
+		t.Run(string(name), func(t *testing.T) {
+			algorithm, err := ratelimit.NewAlgorithm(name, NewMockStorage(), 2, 2, false)
+			require.NoError(t, err)
+			defer algorithm.Close()
+
+			for _, expected := range []bool{true, true, false} {
+				result, err := algorithm.Take(context.Background(), "client1", "/api/test", 1)
+				require.NoError(t, err)
+				assert.Equal(t, expected, result.Allowed)
+			}
+		})
+	}
+}
+
+func TestNewAlgorithm_Unknown(t *testing.T) {
+	_, err := ratelimit.NewAlgorithm("leaky", NewMockStorage(), 1, 1, false)
+	assert.Error(t, err)
+}
+
+func TestLimiter_AlgorithmPerLayer(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+	limiter := ratelimit.NewLimiterWithOptions(store, ratelimit.LimiterOptions{
+		DefaultRate:   1000,
+		BurstCapacity: 100,
+		Algorithm:     ratelimit.AlgorithmGCRA,
+	})
+	defer limiter.Close()
+	ctx := context.Background()
+
+	// A token bucket route layer with a burst of one next to a rolling window
+	// of two per minute
+	bucket := ratelimit.Limit{Layer: ratelimit.LayerRoute, Scope: "/api/*", Rate: 60, Burst: 1, Algorithm: ratelimit.AlgorithmTokenBucket}
+	window := ratelimit.KeyLimit(2)
+	window.Algorithm = ratelimit.AlgorithmSlidingWindowLog
+
+	decision, err := limiter.Check(ctx, "client1", "/api/test", bucket, window)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	decision, err = limiter.Check(ctx, "client1", "/api/test", bucket, window)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, ratelimit.LayerRoute, decision.Layer, "the bucket's burst is spent")
+
+	// The bucket refills within a second; the window, checked first for its
+	// lower rate, still holds both requests
+	time.Sleep(time.Second)
+	decision, err = limiter.Check(ctx, "client1", "/api/test", bucket, window)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, ratelimit.LayerKey, decision.Layer)
+	assert.Greater(t, decision.RetryAfter, 50*time.Second)
+}
+
+func TestLimiter_UnknownAlgorithm(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+	limiter := ratelimit.NewLimiterWithOptions(store, ratelimit.LimiterOptions{
+		DefaultRate:   60,
+		BurstCapacity: 10,
+		Algorithm:     "leaky",
+	})
+	defer limiter.Close()
+
+	_, err := limiter.Allow(context.Background(), "client1", "/api/test")
+	assert.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kalshi/internal/ratelimit"
+	"kalshi/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Acquire(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+	limiter := ratelimit.NewLimiter(store, 60, 10)
+	defer limiter.Close()
+	ctx := context.Background()
+
+	first, err := limiter.Acquire(ctx, "client1", "/api/a", 2)
+	require.NoError(t, err)
+	assert.True(t, first.Acquired)
+	second, _ := limiter.Acquire(ctx, "client1", "/api/b", 2)
+	assert.True(t, second.Acquired)
+	assert.Equal(t, int64(2), second.InFlight)
+
+	// Slots are shared by every path, but not by other clients
+	third, err := limiter.Acquire(ctx, "client1", "/api/c", 2)
+	require.NoError(t, err)
+	assert.False(t, third.Acquired)
+	assert.Equal(t, int64(2), third.InFlight)
+	third.Release()
+	other, _ := limiter.Acquire(ctx, "client2", "/api/a", 2)
+	assert.True(t, other.Acquired)
+	other.Release()
+
+	// Releasing twice frees one slot only
+	first.Release()
+	first.Release()
+	third, _ = limiter.Acquire(ctx, "client1", "/api/c", 2)
+	assert.True(t, third.Acquired)
+	fourth, _ := limiter.Acquire(ctx, "client1", "/api/c", 2)
+	assert.False(t, fourth.Acquired)
+
+	second.Release()
+	third.Release()
+}
+
+func TestLimiter_AcquireRenewsLease(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+	limiter := ratelimit.NewLimiterWithOptions(store, ratelimit.LimiterOptions{
+		DefaultRate:   60,
+		BurstCapacity: 10,
+		LeaseTTL:      60 * time.Millisecond,
+	})
+	defer limiter.Close()
+	ctx := context.Background()
+
+	slow, err := limiter.Acquire(ctx, "client1", "/api/slow", 1)
+	require.NoError(t, err)
+	require.True(t, slow.Acquired)
+
+	// A request running past the TTL keeps its slot
+	time.Sleep(150 * time.Millisecond)
+	lease, _ := limiter.Acquire(ctx, "client1", "/api/slow", 1)
+	assert.False(t, lease.Acquired)
+
+	// Once released, renewals stop and the slot frees up
+	slow.Release()
+	lease, _ = limiter.Acquire(ctx, "client1", "/api/slow", 1)
+	assert.True(t, lease.Acquired)
+	lease.Release()
+}
+
+func TestLimiter_ReleaseRacingRenewal(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+	limiter := ratelimit.NewLimiterWithOptions(store, ratelimit.LimiterOptions{
+		DefaultRate:   60,
+		BurstCapacity: 10,
+		LeaseTTL:      3 * time.Millisecond,
+	})
+	defer limiter.Close()
+	ctx := context.Background()
+
+	// Renewals fire every millisecond, so some land mid-release
+	for i := 0; i < 50; i++ {
+		lease, err := limiter.Acquire(ctx, "client1", "/api/slow", 1)
+		require.NoError(t, err)
+		require.True(t, lease.Acquired, "iteration %d", i)
+		time.Sleep(time.Duration(i%3) * time.Millisecond)
+		lease.Release()
+
+		// A renewal racing the release must not hold the slot again
+		time.Sleep(2 * time.Millisecond)
+	}
+}
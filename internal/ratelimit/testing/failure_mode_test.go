@@ -0,0 +1,108 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/ratelimit"
+	"kalshi/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unavailableStorage fails every operation, like an unreachable Redis
+type unavailableStorage struct {
+	MockStorage
+}
+
+var errUnavailable = errors.New("connection refused")
+
+func (s *unavailableStorage) TakeTokens(ctx context.Context, key string, bucket storage.Bucket, n int64) (storage.BucketResult, error) {
+	return storage.BucketResult{}, errUnavailable
+}
+
+func TestLimiter_FailureMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        ratelimit.FailureMode
+		expectAllow bool
+		expectErr   error
+	}{
+		{name: "default fails open", expectAllow: true},
+		{name: "open", mode: ratelimit.FailOpen, expectAllow: true},
+		{name: "closed", mode: ratelimit.FailClosed, expectErr: ratelimit.ErrLimiterUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := ratelimit.NewLimiterWithOptions(&unavailableStorage{}, ratelimit.LimiterOptions{
+				DefaultRate:   60,
+				BurstCapacity: 10,
+				FailureMode:   tt.mode,
+			})
+
+			allowed, err := limiter.Allow(context.Background(), "client1", "/api/test")
+			assert.Equal(t, tt.expectAllow, allowed)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLimiter_AtomicStorageNeverOverAdmits(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	// Two limiters sharing storage stand in for two gateway replicas
+	replicas := []*ratelimit.Limiter{
+		ratelimit.NewLimiter(store, 1, 20),
+		ratelimit.NewLimiter(store, 1, 20),
+	}
+
+	var admitted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(limiter *ratelimit.Limiter) {
+			defer wg.Done()
+			allowed, err := limiter.Allow(context.Background(), "client1", "/api/test")
+			require.NoError(t, err)
+			if allowed {
+				admitted.Add(1)
+			}
+		}(replicas[i%2])
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(20), admitted.Load())
+}
+
+func TestTokenBucket_Take(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+	tb := ratelimit.NewTokenBucket(store, 10, 60, time.Minute)
+	ctx := context.Background()
+
+	result, err := tb.Take(ctx, "key", 4)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(6), result.Remaining)
+
+	result, err = tb.Take(ctx, "key", 7)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	require.NoError(t, tb.Reset(ctx, "key"))
+	result, err = tb.Take(ctx, "key", 10)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
@@ -0,0 +1,213 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/ratelimit"
+	"kalshi/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalLimiter_Burst(t *testing.T) {
+	limiter := ratelimit.NewLocalLimiter(5, 60, time.Minute)
+	defer limiter.Close()
+
+	for i := 4; i >= 0; i-- {
+		result := limiter.Take("client1", "/api/test", 1)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(i), result.Remaining)
+	}
+
+	result := limiter.Take("client1", "/api/test", 1)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(5), result.Limit)
+	assert.InDelta(t, float64(time.Second), float64(result.RetryAfter), float64(10*time.Millisecond))
+
+	// Buckets are per client and path
+	assert.True(t, limiter.Allow("client2", "/api/test"))
+	assert.True(t, limiter.Allow("client1", "/api/other"))
+}
+
+func TestLocalLimiter_SubSecondRefill(t *testing.T) {
+	// One token every 20ms; whole-second timestamps would refill nothing
+	limiter := ratelimit.NewLocalLimiter(1, 50, time.Second)
+	defer limiter.Close()
+
+	assert.True(t, limiter.Allow("client1", "/api/test"))
+	assert.False(t, limiter.Allow("client1", "/api/test"))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, limiter.Allow("client1", "/api/test"))
+}
+
+func TestLocalLimiter_TakeAndReset(t *testing.T) {
+	limiter := ratelimit.NewLocalLimiter(10, 60, time.Minute)
+	defer limiter.Close()
+
+	assert.True(t, limiter.Take("client1", "/api/test", 8).Allowed)
+	assert.False(t, limiter.Take("client1", "/api/test", 3).Allowed, "a rejected take consumes nothing")
+	assert.True(t, limiter.Take("client1", "/api/test", 2).Allowed)
+	assert.False(t, limiter.Allow("client1", "/api/test"))
+
+	limiter.Reset("client1", "/api/test")
+	assert.True(t, limiter.Take("client1", "/api/test", 10).Allowed)
+}
+
+func TestLocalLimiter_KeysDoNotCollide(t *testing.T) {
+	limiter := ratelimit.NewLocalLimiter(1, 1, time.Hour)
+	defer limiter.Close()
+
+	assert.True(t, limiter.Allow("ab", "c"))
+	assert.True(t, limiter.Allow("a", "bc"))
+}
+
+func TestLocalLimiter_ConcurrentNeverOverAdmits(t *testing.T) {
+	limiter := ratelimit.NewLocalLimiter(100, 1, time.Hour)
+	defer limiter.Close()
+
+	var admitted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.Allow("client1", "/api/test") {
+				admitted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(100), admitted.Load())
+}
+
+func TestLimiter_InProcess(t *testing.T) {
+	limiter := ratelimit.NewLimiterWithOptions(NewMockStorage(), ratelimit.LimiterOptions{
+		DefaultRate:   60,
+		BurstCapacity: 2,
+		InProcess:     true,
+	})
+	defer limiter.Close()
+	ctx := context.Background()
+
+	for _, expected := range []bool{true, true, false} {
+		allowed, err := limiter.Allow(ctx, "client1", "/api/test")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, allowed)
+	}
+
+	assert.NoError(t, limiter.Reset(ctx, "client1", "/api/test"))
+	allowed, _ := limiter.Allow(ctx, "client1", "/api/test")
+	assert.True(t, allowed)
+}
+
+// plainStorage hides the atomic bucket operations of the storage it wraps,
+// so token buckets take their original path: separate reads and writes of
+// formatted keys under the bucket's mutex
+type plainStorage struct {
+	storage.Storage
+}
+
+// benchmarkLimiters are the implementations compared by the benchmarks:
+// the original mutex-guarded token bucket, the atomic storage buckets and
+// the in-process buckets
+func benchmarkLimiters(b *testing.B) map[string]*ratelimit.Limiter {
+	store := storage.NewMemoryStorage()
+	b.Cleanup(func() { store.Close() })
+
+	opts := ratelimit.LimiterOptions{DefaultRate: 6000000, BurstCapacity: 1000000}
+	inProcess := opts
+	inProcess.InProcess = true
+
+	limiters := map[string]*ratelimit.Limiter{
+		"mutex":      ratelimit.NewLimiterWithOptions(plainStorage{store}, opts),
+		"storage":    ratelimit.NewLimiterWithOptions(store, opts),
+		"in_process": ratelimit.NewLimiterWithOptions(store, inProcess),
+	}
+	b.Cleanup(func() {
+		for _, limiter := range limiters {
+			limiter.Close()
+		}
+	})
+	return limiters
+}
+
+func benchmarkClients(n int) []string {
+	clients := make([]string, n)
+	for i := range clients {
+		clients[i] = fmt.Sprintf("client-%d", i)
+	}
+	return clients
+}
+
+func BenchmarkLimiter_AllowParallel(b *testing.B) {
+	clients := benchmarkClients(1000)
+
+	for name, limiter := range benchmarkLimiters(b) {
+		b.Run(name, func(b *testing.B) {
+			ctx := context.Background()
+			var next atomic.Int64
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					client := clients[next.Add(1)%int64(len(clients))]
+					_, _ = limiter.Allow(ctx, client, "/api/test")
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkLimiter_50kRPS paces checks at 50,000 requests per second over
+// 1000 clients and reports the latency a request sees at that load
+func BenchmarkLimiter_50kRPS(b *testing.B) {
+	const (
+		rps     = 50000
+		workers = 8
+	)
+	clients := benchmarkClients(1000)
+
+	for name, limiter := range benchmarkLimiters(b) {
+		b.Run(name, func(b *testing.B) {
+			ctx := context.Background()
+			latencies := make([]time.Duration, b.N)
+			spacing := time.Second / rps
+
+			b.ResetTimer()
+			start := time.Now()
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := w; i < b.N; i += workers {
+						// Request i is due at its slot in the schedule
+						if wait := time.Until(start.Add(time.Duration(i) * spacing)); wait > 0 {
+							time.Sleep(wait)
+						}
+						began := time.Now()
+						_, _ = limiter.Allow(ctx, clients[i%len(clients)], "/api/test")
+						latencies[i] = time.Since(began)
+					}
+				}(w)
+			}
+			wg.Wait()
+			elapsed := time.Since(start)
+			b.StopTimer()
+
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			b.ReportMetric(float64(b.N)/elapsed.Seconds(), "req/s")
+			b.ReportMetric(float64(latencies[len(latencies)/2].Nanoseconds()), "p50-ns")
+			b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+		})
+	}
+}
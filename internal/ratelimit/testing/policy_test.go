@@ -0,0 +1,192 @@
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kalshi/internal/ratelimit"
+	"kalshi/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// layeredLimiters returns a storage-backed and an in-process limiter with
+// the same global limit
+func layeredLimiters(t *testing.T, rate, burst int) map[string]*ratelimit.Limiter {
+	t.Helper()
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	limiters := map[string]*ratelimit.Limiter{
+		"storage": ratelimit.NewLimiterWithOptions(store, ratelimit.LimiterOptions{
+			DefaultRate: rate, BurstCapacity: burst,
+		}),
+		"in_process": ratelimit.NewLimiterWithOptions(store, ratelimit.LimiterOptions{
+			DefaultRate: rate, BurstCapacity: burst, InProcess: true,
+		}),
+	}
+	t.Cleanup(func() {
+		for _, limiter := range limiters {
+			limiter.Close()
+		}
+	})
+	return limiters
+}
+
+func TestLimiter_Check_MostRestrictiveLayerWins(t *testing.T) {
+	for name, limiter := range layeredLimiters(t, 1000, 100) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			layers := []ratelimit.Limit{
+				ratelimit.RouteLimit("/api/*", 5),
+				ratelimit.KeyLimit(3),
+			}
+
+			for i := 2; i >= 0; i-- {
+				decision, err := limiter.Check(ctx, "client1", "/api/test", layers...)
+				require.NoError(t, err)
+				assert.True(t, decision.Allowed)
+				assert.Equal(t, ratelimit.LayerKey, decision.Layer)
+				assert.Equal(t, int64(i), decision.Remaining)
+			}
+
+			decision, err := limiter.Check(ctx, "client1", "/api/test", layers...)
+			require.NoError(t, err)
+			assert.False(t, decision.Allowed)
+			assert.Equal(t, ratelimit.LayerKey, decision.Layer)
+			assert.Equal(t, 3, decision.Rate)
+			assert.Positive(t, decision.RetryAfter)
+
+			// Without the key layer the route still has tokens
+			decision, err = limiter.Check(ctx, "client1", "/api/test", layers[0])
+			require.NoError(t, err)
+			assert.True(t, decision.Allowed)
+			assert.Equal(t, ratelimit.LayerRoute, decision.Layer)
+		})
+	}
+}
+
+func TestLimiter_Check_GlobalLayer(t *testing.T) {
+	for name, limiter := range layeredLimiters(t, 60, 2) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			for _, expected := range []bool{true, true, false} {
+				decision, err := limiter.Check(ctx, "client1", "/api/test", ratelimit.KeyLimit(100))
+				require.NoError(t, err)
+				assert.Equal(t, expected, decision.Allowed)
+				assert.Equal(t, ratelimit.LayerGlobal, decision.Layer)
+			}
+
+			// Allow shares the global bucket
+			allowed, err := limiter.Allow(ctx, "client1", "/api/test")
+			require.NoError(t, err)
+			assert.False(t, allowed)
+		})
+	}
+}
+
+func TestLimiter_Check_LayerScopes(t *testing.T) {
+	for name, limiter := range layeredLimiters(t, 1000, 100) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// A route bucket covers every path matching the route
+			route := ratelimit.RouteLimit("/api/*", 1)
+			decision, _ := limiter.Check(ctx, "client1", "/api/a", route)
+			assert.True(t, decision.Allowed)
+			decision, _ = limiter.Check(ctx, "client1", "/api/b", route)
+			assert.False(t, decision.Allowed)
+
+			// Another route with the same rate has its own bucket
+			decision, _ = limiter.Check(ctx, "client1", "/other", ratelimit.RouteLimit("/other", 1))
+			assert.True(t, decision.Allowed)
+
+			// A key bucket covers every path, per client
+			decision, _ = limiter.Check(ctx, "client2", "/api/a", ratelimit.KeyLimit(1))
+			assert.True(t, decision.Allowed)
+			decision, _ = limiter.Check(ctx, "client2", "/other", ratelimit.KeyLimit(1))
+			assert.False(t, decision.Allowed)
+			decision, _ = limiter.Check(ctx, "client3", "/other", ratelimit.KeyLimit(1))
+			assert.True(t, decision.Allowed)
+		})
+	}
+}
+
+func TestLimiter_Check_ZeroRateLayerIgnored(t *testing.T) {
+	for name, limiter := range layeredLimiters(t, 1000, 100) {
+		t.Run(name, func(t *testing.T) {
+			decision, err := limiter.Check(context.Background(), "client1", "/api/test",
+				ratelimit.RouteLimit("/api/*", 0), ratelimit.KeyLimit(0))
+			require.NoError(t, err)
+			assert.True(t, decision.Allowed)
+			assert.Equal(t, ratelimit.LayerGlobal, decision.Layer)
+		})
+	}
+}
+
+func TestLimiter_CheckN_Cost(t *testing.T) {
+	for name, limiter := range layeredLimiters(t, 60, 10) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			decision, err := limiter.CheckN(ctx, "client1", "/api/search", 7)
+			require.NoError(t, err)
+			assert.True(t, decision.Allowed)
+			assert.Equal(t, int64(3), decision.Remaining)
+
+			// An expensive request that does not fit takes nothing; a cheap one
+			// still fits
+			decision, err = limiter.CheckN(ctx, "client1", "/api/search", 5)
+			require.NoError(t, err)
+			assert.False(t, decision.Allowed)
+			decision, err = limiter.CheckN(ctx, "client1", "/api/search", 1)
+			require.NoError(t, err)
+			assert.True(t, decision.Allowed)
+			assert.Equal(t, int64(2), decision.Remaining)
+		})
+	}
+}
+
+func TestLimiter_Charge(t *testing.T) {
+	for name, limiter := range layeredLimiters(t, 60, 10) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := ratelimit.KeyLimit(20)
+
+			decision, err := limiter.Check(ctx, "client1", "/api/search", key)
+			require.NoError(t, err)
+			require.True(t, decision.Allowed)
+
+			// The backend reports a cost of four: three more tokens from every
+			// layer
+			require.NoError(t, limiter.Charge(ctx, "client1", "/api/search", 3, key))
+			decision, err = limiter.Check(ctx, "client1", "/api/search", key)
+			require.NoError(t, err)
+			assert.Equal(t, int64(5), decision.Remaining)
+			assert.Equal(t, ratelimit.LayerGlobal, decision.Layer)
+
+			// A charge beyond what is left empties the bucket
+			require.NoError(t, limiter.Charge(ctx, "client1", "/api/search", 50, key))
+			decision, err = limiter.Check(ctx, "client1", "/api/search", key)
+			require.NoError(t, err)
+			assert.False(t, decision.Allowed)
+		})
+	}
+}
+
+func TestTokenBucket_AllowN(t *testing.T) {
+	tb := ratelimit.NewTokenBucket(NewMockStorage(), 10, 1, time.Minute)
+	ctx := context.Background()
+
+	allowed, err := tb.AllowN(ctx, "search", 10)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = tb.AllowN(ctx, "search", 1)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
@@ -0,0 +1,141 @@
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kalshi/internal/ratelimit"
+	"kalshi/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaPeriod_Bounds(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 03:30 UTC on March 1st is still February 28th in New York
+	now := time.Date(2026, 3, 1, 3, 30, 0, 0, time.UTC).In(newYork)
+
+	start, end := ratelimit.QuotaDaily.Bounds(now)
+	assert.Equal(t, time.Date(2026, 2, 28, 0, 0, 0, 0, newYork), start)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, newYork), end)
+
+	start, end = ratelimit.QuotaMonthly.Bounds(now)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, newYork), start)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, newYork), end)
+
+	// The day clocks spring forward is 23 hours long
+	start, end = ratelimit.QuotaDaily.Bounds(time.Date(2026, 3, 8, 12, 0, 0, 0, newYork))
+	assert.Equal(t, 23*time.Hour, end.Sub(start))
+
+	start, _ = ratelimit.QuotaPeriod("weekly").Bounds(now)
+	assert.True(t, start.IsZero())
+}
+
+func TestQuotaManager_Consume(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+	quotas := ratelimit.NewQuotaManager(store, ratelimit.QuotaOptions{})
+	defer quotas.Close()
+	ctx := context.Background()
+	daily := []ratelimit.Quota{{Period: ratelimit.QuotaDaily, Limit: 3}}
+
+	for i := int64(2); i >= 0; i-- {
+		decision, err := quotas.Consume(ctx, "client1", daily, 1)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, i, decision.Tightest().Remaining)
+	}
+
+	decision, err := quotas.Consume(ctx, "client1", daily, 1)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	usage := decision.Tightest()
+	assert.True(t, usage.Exceeded)
+	assert.Equal(t, int64(3), usage.Used, "a rejected request is not counted")
+	assert.True(t, usage.ResetsAt.After(time.Now()))
+	assert.True(t, usage.ResetsAt.Before(time.Now().Add(25*time.Hour)))
+
+	// Other clients have their own quota, and a reset client starts over
+	decision, _ = quotas.Consume(ctx, "client2", daily, 1)
+	assert.True(t, decision.Allowed)
+	require.NoError(t, quotas.Reset(ctx, "client1", ratelimit.QuotaDaily))
+	decision, _ = quotas.Consume(ctx, "client1", daily, 1)
+	assert.True(t, decision.Allowed)
+}
+
+func TestQuotaManager_WeightedCost(t *testing.T) {
+	quotas := ratelimit.NewQuotaManager(NewMockStorage(), ratelimit.QuotaOptions{})
+	defer quotas.Close()
+	ctx := context.Background()
+	daily := []ratelimit.Quota{{Period: ratelimit.QuotaDaily, Limit: 10}}
+
+	decision, err := quotas.Consume(ctx, "client1", daily, 8)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	// A costly request that does not fit is rejected; a cheaper one still fits
+	decision, _ = quotas.Consume(ctx, "client1", daily, 3)
+	assert.False(t, decision.Allowed)
+	decision, _ = quotas.Consume(ctx, "client1", daily, 2)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, int64(0), decision.Tightest().Remaining)
+}
+
+func TestQuotaManager_RejectionRefundsOtherQuotas(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+	quotas := ratelimit.NewQuotaManager(store, ratelimit.QuotaOptions{})
+	defer quotas.Close()
+	ctx := context.Background()
+
+	monthly := ratelimit.Quota{Period: ratelimit.QuotaMonthly, Limit: 100}
+	both := []ratelimit.Quota{monthly, {Period: ratelimit.QuotaDaily, Limit: 1}}
+
+	decision, _ := quotas.Consume(ctx, "client1", both, 1)
+	assert.True(t, decision.Allowed)
+	decision, _ = quotas.Consume(ctx, "client1", both, 1)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, ratelimit.QuotaDaily, decision.Tightest().Period)
+
+	usage, err := quotas.Usage(ctx, "client1", []ratelimit.Quota{monthly})
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, int64(1), usage[0].Used, "the monthly quota is refunded")
+}
+
+func TestQuotaManager_Warnings(t *testing.T) {
+	quotas := ratelimit.NewQuotaManager(NewMockStorage(), ratelimit.QuotaOptions{
+		WarningThresholds: []float64{0.9, 0.5},
+	})
+	defer quotas.Close()
+	ctx := context.Background()
+	daily := []ratelimit.Quota{{Period: ratelimit.QuotaDaily, Limit: 10}}
+
+	expected := []float64{0, 0, 0, 0, 0.5, 0.5, 0.5, 0.5, 0.9, 0.9}
+	for i, warning := range expected {
+		decision, err := quotas.Consume(ctx, "client1", daily, 1)
+		require.NoError(t, err)
+		assert.Equal(t, warning, decision.Tightest().Warning, "request %d", i+1)
+	}
+}
+
+func TestQuotaManager_Timezone(t *testing.T) {
+	// Periods in a zone far from UTC still reset within a day
+	quotas := ratelimit.NewQuotaManager(NewMockStorage(), ratelimit.QuotaOptions{
+		Location: time.FixedZone("UTC+14", 14*60*60),
+	})
+	defer quotas.Close()
+
+	usage, err := quotas.Usage(context.Background(), "client1", []ratelimit.Quota{{Period: ratelimit.QuotaDaily, Limit: 5}})
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+
+	resetsAt := usage[0].ResetsAt
+	assert.Equal(t, 0, resetsAt.Hour())
+	assert.Equal(t, "UTC+14", resetsAt.Location().String())
+	assert.LessOrEqual(t, time.Until(resetsAt), 24*time.Hour)
+}
@@ -0,0 +1,153 @@
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kalshi/internal/ratelimit"
+	"kalshi/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// marketSchedule trades from 09:30 to 16:00 New York time
+func marketSchedule(t *testing.T, policies map[ratelimit.SchedulePeriod]ratelimit.SchedulePolicy) *ratelimit.Schedule {
+	t.Helper()
+	schedule, err := ratelimit.NewSchedule("America/New_York", 9*time.Hour+30*time.Minute, 16*time.Hour, policies)
+	require.NoError(t, err)
+	return schedule
+}
+
+// everyPeriod applies the same policy all week, so tests do not depend on
+// when they run
+func everyPeriod(policy ratelimit.SchedulePolicy) map[ratelimit.SchedulePeriod]ratelimit.SchedulePolicy {
+	return map[ratelimit.SchedulePeriod]ratelimit.SchedulePolicy{
+		ratelimit.PeriodTrading:  policy,
+		ratelimit.PeriodOffHours: policy,
+		ratelimit.PeriodWeekend:  policy,
+	}
+}
+
+func TestSchedule_Period(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	schedule := marketSchedule(t, nil)
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected ratelimit.SchedulePeriod
+	}{
+		{"before the open", time.Date(2026, 10, 19, 9, 29, 0, 0, newYork), ratelimit.PeriodOffHours},
+		{"at the open", time.Date(2026, 10, 19, 9, 30, 0, 0, newYork), ratelimit.PeriodTrading},
+		{"before the close", time.Date(2026, 10, 19, 15, 59, 59, 0, newYork), ratelimit.PeriodTrading},
+		{"at the close", time.Date(2026, 10, 19, 16, 0, 0, 0, newYork), ratelimit.PeriodOffHours},
+		{"saturday", time.Date(2026, 10, 24, 12, 0, 0, 0, newYork), ratelimit.PeriodWeekend},
+		// 03:00 UTC on Saturday is still Friday evening in New York
+		{"in another zone", time.Date(2026, 10, 24, 3, 0, 0, 0, time.UTC), ratelimit.PeriodOffHours},
+		// Trading hours follow the wall clock after clocks spring forward
+		{"after daylight saving starts", time.Date(2026, 3, 9, 9, 45, 0, 0, newYork), ratelimit.PeriodTrading},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, schedule.Period(tt.at))
+		})
+	}
+}
+
+func TestSchedule_PeriodEnd(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	schedule := marketSchedule(t, nil)
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected time.Time
+	}{
+		{"trading ends at the close", time.Date(2026, 10, 19, 10, 0, 0, 0, newYork), time.Date(2026, 10, 19, 16, 0, 0, 0, newYork)},
+		{"the morning ends at the open", time.Date(2026, 10, 19, 8, 0, 0, 0, newYork), time.Date(2026, 10, 19, 9, 30, 0, 0, newYork)},
+		{"the evening ends at the next open", time.Date(2026, 10, 19, 20, 0, 0, 0, newYork), time.Date(2026, 10, 20, 9, 30, 0, 0, newYork)},
+		{"friday evening ends at the weekend", time.Date(2026, 10, 23, 17, 0, 0, 0, newYork), time.Date(2026, 10, 24, 0, 0, 0, 0, newYork)},
+		{"the weekend ends on monday", time.Date(2026, 10, 24, 12, 0, 0, 0, newYork), time.Date(2026, 10, 26, 0, 0, 0, 0, newYork)},
+		{"the weekend clocks spring forward", time.Date(2026, 3, 8, 12, 0, 0, 0, newYork), time.Date(2026, 3, 9, 0, 0, 0, 0, newYork)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.expected.Equal(schedule.PeriodEnd(tt.at)), "expected %v, got %v", tt.expected, schedule.PeriodEnd(tt.at))
+		})
+	}
+}
+
+func TestNewSchedule_Invalid(t *testing.T) {
+	_, err := ratelimit.NewSchedule("Mars/Olympus_Mons", 9*time.Hour, 17*time.Hour, nil)
+	assert.Error(t, err)
+
+	_, err = ratelimit.NewSchedule("UTC", 17*time.Hour, 9*time.Hour, nil)
+	assert.Error(t, err)
+}
+
+func TestLimiter_Schedule(t *testing.T) {
+	limiter := ratelimit.NewLimiterWithOptions(NewMockStorage(), ratelimit.LimiterOptions{
+		DefaultRate:   60,
+		BurstCapacity: 10,
+		Schedule: marketSchedule(t, everyPeriod(ratelimit.SchedulePolicy{
+			DefaultRate:       120,
+			BurstCapacity:     20,
+			KeyRateMultiplier: 0.5,
+		})),
+	})
+	defer limiter.Close()
+
+	global := limiter.GlobalLimit("/api/test")
+	assert.Equal(t, 120, global.Rate)
+	assert.Equal(t, 20, global.Burst)
+	assert.Equal(t, 2, limiter.Scheduled(ratelimit.KeyLimit(4)).Rate)
+	assert.Equal(t, 4, limiter.Scheduled(ratelimit.RouteLimit("/api/*", 4)).Rate, "route limits are not scheduled")
+	assert.NotNil(t, limiter.ScheduleStatus())
+
+	// The key's scaled rate of two per minute admits two requests
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		decision, err := limiter.Check(ctx, "client1", "/api/test", ratelimit.KeyLimit(4))
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+	}
+	decision, err := limiter.Check(ctx, "client1", "/api/test", ratelimit.KeyLimit(4))
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, ratelimit.LayerKey, decision.Layer)
+
+	// Without a schedule the configured limits apply
+	fixed := ratelimit.NewLimiter(NewMockStorage(), 60, 10)
+	defer fixed.Close()
+	assert.Equal(t, 60, fixed.GlobalLimit("/api/test").Rate)
+	assert.Equal(t, 4, fixed.Scheduled(ratelimit.KeyLimit(4)).Rate)
+	assert.Nil(t, fixed.ScheduleStatus())
+}
+
+func TestQuotaManager_Schedule(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+	quotas := ratelimit.NewQuotaManager(store, ratelimit.QuotaOptions{
+		Schedule: marketSchedule(t, everyPeriod(ratelimit.SchedulePolicy{QuotaMultiplier: 0.5})),
+	})
+	defer quotas.Close()
+	ctx := context.Background()
+	daily := []ratelimit.Quota{{Period: ratelimit.QuotaDaily, Limit: 4}}
+
+	for i := 0; i < 2; i++ {
+		decision, err := quotas.Consume(ctx, "client1", daily, 1)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+	}
+
+	decision, err := quotas.Consume(ctx, "client1", daily, 1)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed, "half of the quota is available")
+	assert.Equal(t, int64(2), decision.Tightest().Limit)
+}
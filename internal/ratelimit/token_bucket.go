@@ -9,24 +9,82 @@ import (
 	"kalshi/internal/storage"
 )
 
+// secondsTimestampLimit separates legacy refill timestamps in seconds from
+// nanosecond ones; it is year 5138 in seconds and 1970 in nanoseconds
+const secondsTimestampLimit = 1e11
+
+// TokenBucket admits requests while tokens remain, refilling refillRate
+// tokens every refillPeriod up to capacity. Storage that implements
+// storage.RateLimitStorage updates each bucket atomically, so replicas
+// sharing Redis cannot over-admit; other storage falls back to separate reads
+// and writes that are only safe within one process.
 type TokenBucket struct {
 	storage      storage.Storage
+	atomic       storage.RateLimitStorage // Nil when the storage cannot update buckets atomically
 	capacity     int
 	refillRate   int
 	refillPeriod time.Duration
 	mu           sync.RWMutex // Add mutex for thread safety
 }
 
-func NewTokenBucket(storage storage.Storage, capacity, refillRate int, refillPeriod time.Duration) *TokenBucket {
+func NewTokenBucket(store storage.Storage, capacity, refillRate int, refillPeriod time.Duration) *TokenBucket {
+	atomic, _ := store.(storage.RateLimitStorage)
 	return &TokenBucket{
-		storage:      storage,
+		storage:      store,
+		atomic:       atomic,
 		capacity:     capacity,
 		refillRate:   refillRate,
 		refillPeriod: refillPeriod,
 	}
 }
 
+// bucket describes the token bucket for atomic storage
+func (tb *TokenBucket) bucket() storage.Bucket {
+	return newBucket(tb.capacity, tb.refillRate, tb.refillPeriod)
+}
+
+// newBucket describes a bucket of capacity tokens refilled rate times every
+// period
+func newBucket(capacity, rate int, period time.Duration) storage.Bucket {
+	interval := period
+	if rate > 0 {
+		interval = period / time.Duration(rate)
+	}
+	return storage.Bucket{Capacity: int64(capacity), Interval: interval}
+}
+
+// atomicKey is where atomic storage keeps a bucket. It differs from the
+// legacy keys because the state has a different shape.
+func atomicKey(key string) string {
+	return fmt.Sprintf("tb:%s", key)
+}
+
 func (tb *TokenBucket) Allow(ctx context.Context, key string) (bool, error) {
+	return tb.AllowN(ctx, key, 1)
+}
+
+// AllowN reports whether a request costing n tokens is admitted, taking the
+// tokens if it is
+func (tb *TokenBucket) AllowN(ctx context.Context, key string, n int64) (bool, error) {
+	result, err := tb.Take(ctx, key, n)
+	return result.Allowed, err
+}
+
+// Take removes n tokens from the bucket for key if enough are available
+func (tb *TokenBucket) Take(ctx context.Context, key string, n int64) (storage.BucketResult, error) {
+	if tb.atomic != nil {
+		result, err := tb.atomic.TakeTokens(ctx, atomicKey(key), tb.bucket(), n)
+		if err != nil {
+			return storage.BucketResult{}, fmt.Errorf("failed to take tokens: %w", err)
+		}
+		return result, nil
+	}
+	return tb.takeLegacy(ctx, key, n)
+}
+
+// takeLegacy implements Take with separate reads and writes under a
+// process-local lock
+func (tb *TokenBucket) takeLegacy(ctx context.Context, key string, n int64) (storage.BucketResult, error) {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 
@@ -37,7 +95,7 @@ func (tb *TokenBucket) Allow(ctx context.Context, key string) (bool, error) {
 	// Get current tokens and last refill time
 	currentTokens, err := tb.getCurrentTokens(ctx, bucketKey)
 	if err != nil {
-		return false, fmt.Errorf("failed to get current tokens: %w", err)
+		return storage.BucketResult{}, fmt.Errorf("failed to get current tokens: %w", err)
 	}
 
 	lastRefill, err := tb.getLastRefill(ctx, timestampKey)
@@ -45,34 +103,42 @@ func (tb *TokenBucket) Allow(ctx context.Context, key string) (bool, error) {
 		lastRefill = now
 	}
 
-	// Calculate tokens to add based on elapsed time
+	// Calculate whole tokens to add based on elapsed time. The refill time
+	// only advances by the time those tokens took, so partial tokens carry
+	// over to the next request instead of being lost.
 	elapsed := now.Sub(lastRefill)
-	tokensToAdd := int((elapsed * time.Duration(tb.refillRate)) / tb.refillPeriod)
+	tokensToAdd := 0
+	refilledAt := now
+	if tb.refillRate > 0 {
+		tokensToAdd = int((elapsed * time.Duration(tb.refillRate)) / tb.refillPeriod)
+		refilledAt = lastRefill.Add(time.Duration(tokensToAdd) * tb.refillPeriod / time.Duration(tb.refillRate))
+	}
 
 	// Update token count
 	newTokens := currentTokens + tokensToAdd
-	if newTokens > tb.capacity {
+	if newTokens >= tb.capacity {
 		newTokens = tb.capacity
+		refilledAt = now
 	}
 
-	// Check if we can consume a token
-	if newTokens <= 0 {
-		return false, nil
+	// Check if we can consume the tokens
+	if int64(newTokens) < n {
+		return storage.BucketResult{Remaining: int64(newTokens)}, nil
 	}
 
-	// Consume one token
-	newTokens--
+	// Consume the tokens
+	newTokens -= int(n)
 
 	// Update storage with better error handling
 	if err := tb.setTokens(ctx, bucketKey, newTokens); err != nil {
-		return false, fmt.Errorf("failed to set tokens: %w", err)
+		return storage.BucketResult{}, fmt.Errorf("failed to set tokens: %w", err)
 	}
 
-	if err := tb.setLastRefill(ctx, timestampKey, now); err != nil {
-		return false, fmt.Errorf("failed to set last refill: %w", err)
+	if err := tb.setLastRefill(ctx, timestampKey, refilledAt); err != nil {
+		return storage.BucketResult{}, fmt.Errorf("failed to set last refill: %w", err)
 	}
 
-	return true, nil
+	return storage.BucketResult{Allowed: true, Remaining: int64(newTokens)}, nil
 }
 
 func (tb *TokenBucket) getCurrentTokens(ctx context.Context, key string) (int, error) {
@@ -117,11 +183,15 @@ func (tb *TokenBucket) getLastRefill(ctx context.Context, key string) (time.Time
 		return time.Time{}, fmt.Errorf("invalid timestamp format: %w", err)
 	}
 
-	return time.Unix(timestamp, 0), nil
+	// Timestamps were stored in whole seconds before nanosecond precision
+	if timestamp < secondsTimestampLimit {
+		return time.Unix(timestamp, 0), nil
+	}
+	return time.Unix(0, timestamp), nil
 }
 
 func (tb *TokenBucket) setLastRefill(ctx context.Context, key string, t time.Time) error {
-	return tb.storage.Set(ctx, key, fmt.Sprintf("%d", t.Unix()), time.Hour)
+	return tb.storage.Set(ctx, key, fmt.Sprintf("%d", t.UnixNano()), time.Hour)
 }
 
 // Reset clears the token bucket state for a given key
@@ -141,5 +211,9 @@ func (tb *TokenBucket) Reset(ctx context.Context, key string) error {
 		return fmt.Errorf("failed to delete timestamp key: %w", err)
 	}
 
+	if err := tb.storage.Delete(ctx, atomicKey(key)); err != nil {
+		return fmt.Errorf("failed to delete bucket key: %w", err)
+	}
+
 	return nil
 }
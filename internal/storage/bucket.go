@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Bucket describes a token bucket: Capacity tokens, refilled one at a time
+// every Interval
+type Bucket struct {
+	Capacity int64
+	Interval time.Duration
+}
+
+// BucketResult is the outcome of taking tokens from a bucket
+type BucketResult struct {
+	Allowed    bool
+	Remaining  int64         // Whole tokens left after the request
+	RetryAfter time.Duration // Until the request could succeed; zero when allowed
+	ResetAfter time.Duration // Until the bucket is full again
+}
+
+// ttl is how long an untouched bucket needs to refill completely, after
+// which its state is the same as a missing one
+func (b Bucket) ttl() time.Duration {
+	return time.Duration(b.Capacity)*b.Interval + time.Second
+}
+
+// take refills a bucket last updated at updated with tokens left and takes
+// n tokens if available. It returns the new token count and the result.
+func (b Bucket) take(tokens float64, updated, now time.Time, n int64) (float64, BucketResult) {
+	if elapsed := now.Sub(updated); elapsed > 0 {
+		tokens = math.Min(float64(b.Capacity), tokens+float64(elapsed)/float64(b.Interval))
+	}
+
+	var result BucketResult
+	if tokens >= float64(n) {
+		tokens -= float64(n)
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((float64(n) - tokens) * float64(b.Interval)))
+	}
+	result.Remaining = int64(tokens)
+	result.ResetAfter = time.Duration(math.Ceil((float64(b.Capacity) - tokens) * float64(b.Interval)))
+	return tokens, result
+}
+
+// takeTokensScript is the token bucket of Bucket.take as one atomic Redis
+// script. Time comes from the Redis server so replicas with skewed clocks
+// agree. State is a hash of the fractional token count and the time of the
+// last update in microseconds.
+//
+// KEYS[1] bucket key; ARGV capacity, interval (us), tokens to take, ttl (ms)
+var takeTokensScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) / interval)
+	ts = now
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+else
+	retry = math.ceil((cost - tokens) * interval)
+end
+local reset = math.ceil((capacity - tokens) * interval)
+
+-- Lua 5.1 would write the microsecond timestamp in exponent notation
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.6f', tokens), 'ts', string.format('%.0f', ts))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+// TakeTokens atomically refills the bucket at key and takes n tokens if
+// enough are available
+func (r *RedisStorage) TakeTokens(ctx context.Context, key string, bucket Bucket, n int64) (BucketResult, error) {
+	values, err := takeTokensScript.Run(ctx, r.client, []string{key},
+		bucket.Capacity,
+		max(bucket.Interval.Microseconds(), 1),
+		n,
+		bucket.ttl().Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return BucketResult{}, err
+	}
+	return bucketResultFromReply(values)
+}
+
+// TakeTokens refills the bucket at key and takes n tokens if enough are
+// available, under the storage lock
+func (m *MemoryStorage) TakeTokens(ctx context.Context, key string, bucket Bucket, n int64) (BucketResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	tokens, updated := float64(bucket.Capacity), now
+	if item, exists := m.data[key]; exists && now.Before(item.expiresAt) {
+		if t, u, ok := parseBucketState(item.value); ok {
+			tokens, updated = t, u
+		}
+	}
+
+	tokens, result := bucket.take(tokens, updated, now, n)
+	if updated.Before(now) {
+		updated = now
+	}
+
+	m.data[key] = &memoryItem{
+		value:     strconv.FormatFloat(tokens, 'f', -1, 64) + " " + strconv.FormatInt(updated.UnixMicro(), 10),
+		expiresAt: now.Add(bucket.ttl()),
+	}
+	return result, nil
+}
+
+// parseBucketState decodes the "tokens timestamp" state MemoryStorage keeps
+// for a bucket
+func parseBucketState(value string) (float64, time.Time, bool) {
+	tokensField, tsField, found := strings.Cut(value, " ")
+	if !found {
+		return 0, time.Time{}, false
+	}
+	tokens, err := strconv.ParseFloat(tokensField, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	ts, err := strconv.ParseInt(tsField, 10, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	return tokens, time.UnixMicro(ts), true
+}
@@ -31,4 +31,39 @@ type RateLimitStorage interface {
 	DecrementTokens(ctx context.Context, key string) (int64, error)
 	// IncrementTokens increases the token count by the specified amount
 	IncrementTokens(ctx context.Context, key string, by int64) (int64, error)
+	// TakeTokens atomically refills the bucket at key and takes n tokens if
+	// enough are available
+	TakeTokens(ctx context.Context, key string, bucket Bucket, n int64) (BucketResult, error)
+}
+
+// AlgorithmStorage provides atomic operations for the rate limiting
+// algorithms other than the token bucket
+type AlgorithmStorage interface {
+	// TakeGCRA atomically takes n tokens from the GCRA bucket at key if
+	// enough are available
+	TakeGCRA(ctx context.Context, key string, bucket Bucket, n int64) (BucketResult, error)
+	// TakeWindow atomically counts n requests in the window at key if they fit
+	TakeWindow(ctx context.Context, key string, window Window, n int64) (BucketResult, error)
+}
+
+// QuotaStorage provides atomic usage counters for long-window quotas
+type QuotaStorage interface {
+	// ConsumeQuota atomically adds n to the counter at key if the total stays
+	// within limit, and returns the resulting usage and whether n was added.
+	// Negative n always applies. The counter expires after ttl.
+	ConsumeQuota(ctx context.Context, key string, limit, n int64, ttl time.Duration) (int64, bool, error)
+}
+
+// LeaseStorage holds expiring leases that bound concurrent work
+type LeaseStorage interface {
+	// AcquireLease atomically holds the lease id in the set at key for ttl
+	// if fewer than limit other unexpired leases are held there, and returns
+	// the number held afterwards and whether id is held. Acquiring a held
+	// lease again extends it.
+	AcquireLease(ctx context.Context, key, id string, limit int64, ttl time.Duration) (int64, bool, error)
+	// RenewLease extends the lease id in the set at key for ttl if it is
+	// still held, and reports whether it was. It never takes a new lease.
+	RenewLease(ctx context.Context, key, id string, ttl time.Duration) (bool, error)
+	// ReleaseLease gives up the lease id in the set at key
+	ReleaseLease(ctx context.Context, key, id string) error
 }
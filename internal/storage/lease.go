@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// acquireLeaseScript holds a lease in a sorted set of lease IDs scored by
+// their expiry in microseconds of the Redis server's clock. Expired leases
+// are dropped first, so leases of crashed holders free up on their own.
+//
+// KEYS[1] lease set; ARGV lease ID, limit, ttl (us)
+var acquireLeaseScript = redis.NewScript(`
+local limit = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+-- Lua 5.1 would write the microsecond timestamps in exponent notation
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', string.format('%.0f', now))
+local count = redis.call('ZCARD', KEYS[1])
+
+local held = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not held then
+	if count >= limit then
+		return {0, count}
+	end
+	count = count + 1
+end
+
+redis.call('ZADD', KEYS[1], string.format('%.0f', now + ttl), ARGV[1])
+redis.call('PEXPIRE', KEYS[1], math.ceil(ttl / 1000))
+return {1, count}
+`)
+
+// renewLeaseScript extends a held, unexpired lease without ever adding one,
+// so a renewal racing the lease's release cannot take it again
+//
+// KEYS[1] lease set; ARGV lease ID, ttl (us)
+var renewLeaseScript = redis.NewScript(`
+local ttl = tonumber(ARGV[2])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local expiresAt = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not expiresAt or tonumber(expiresAt) <= now then
+	return 0
+end
+
+redis.call('ZADD', KEYS[1], 'XX', string.format('%.0f', now + ttl), ARGV[1])
+if redis.call('PTTL', KEYS[1]) < math.ceil(ttl / 1000) then
+	redis.call('PEXPIRE', KEYS[1], math.ceil(ttl / 1000))
+end
+return 1
+`)
+
+// AcquireLease atomically holds the lease id at key for ttl if fewer than
+// limit other leases are held, and returns the number held afterwards
+func (r *RedisStorage) AcquireLease(ctx context.Context, key, id string, limit int64, ttl time.Duration) (int64, bool, error) {
+	values, err := acquireLeaseScript.Run(ctx, r.client, []string{key}, id, limit, max(ttl.Microseconds(), 1)).Int64Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	if len(values) != 2 {
+		return 0, false, fmt.Errorf("unexpected lease reply: %v", values)
+	}
+	return values[1], values[0] == 1, nil
+}
+
+// RenewLease atomically extends the lease id at key for ttl if it is held
+func (r *RedisStorage) RenewLease(ctx context.Context, key, id string, ttl time.Duration) (bool, error) {
+	renewed, err := renewLeaseScript.Run(ctx, r.client, []string{key}, id, max(ttl.Microseconds(), 1)).Int()
+	if err != nil {
+		return false, err
+	}
+	return renewed == 1, nil
+}
+
+// ReleaseLease gives up the lease id at key
+func (r *RedisStorage) ReleaseLease(ctx context.Context, key, id string) error {
+	return r.client.ZRem(ctx, key, id).Err()
+}
+
+// AcquireLease holds the lease id at key for ttl if fewer than limit other
+// leases are held, under the storage lock
+func (m *MemoryStorage) AcquireLease(ctx context.Context, key, id string, limit int64, ttl time.Duration) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	leases := m.leases[key]
+	if leases == nil {
+		leases = make(map[string]time.Time)
+		m.leases[key] = leases
+	}
+	for leaseID, expiresAt := range leases {
+		if !now.Before(expiresAt) {
+			delete(leases, leaseID)
+		}
+	}
+
+	if _, held := leases[id]; !held && int64(len(leases)) >= limit {
+		return int64(len(leases)), false, nil
+	}
+	leases[id] = now.Add(ttl)
+	return int64(len(leases)), true, nil
+}
+
+// RenewLease extends the lease id at key for ttl if it is held, under the
+// storage lock
+func (m *MemoryStorage) RenewLease(ctx context.Context, key, id string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	expiresAt, held := m.leases[key][id]
+	if !held || !now.Before(expiresAt) {
+		return false, nil
+	}
+	m.leases[key][id] = now.Add(ttl)
+	return true, nil
+}
+
+// ReleaseLease gives up the lease id at key
+func (m *MemoryStorage) ReleaseLease(ctx context.Context, key, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if leases := m.leases[key]; leases != nil {
+		delete(leases, id)
+		if len(leases) == 0 {
+			delete(m.leases, key)
+		}
+	}
+	return nil
+}
@@ -11,6 +11,8 @@ import (
 // with automatic cleanup of expired items.
 type MemoryStorage struct {
 	data   map[string]*memoryItem
+	logs   map[string]*requestLog          // Sliding window logs, kept apart from string values
+	leases map[string]map[string]time.Time // Expiry of each held lease by lease set
 	mu     sync.RWMutex
 	stopCh chan struct{}
 }
@@ -20,10 +22,26 @@ type memoryItem struct {
 	expiresAt time.Time
 }
 
+// requestLog is the request times of a sliding window log in ascending Unix
+// nanoseconds
+type requestLog struct {
+	times     []int64
+	expiresAt time.Time
+}
+
+// Ensure MemoryStorage implements the interfaces
+var _ Storage = (*MemoryStorage)(nil)
+var _ RateLimitStorage = (*MemoryStorage)(nil)
+var _ AlgorithmStorage = (*MemoryStorage)(nil)
+var _ QuotaStorage = (*MemoryStorage)(nil)
+var _ LeaseStorage = (*MemoryStorage)(nil)
+
 // NewMemoryStorage creates a new memory storage instance with automatic cleanup
 func NewMemoryStorage() *MemoryStorage {
 	ms := &MemoryStorage{
 		data:   make(map[string]*memoryItem),
+		logs:   make(map[string]*requestLog),
+		leases: make(map[string]map[string]time.Time),
 		stopCh: make(chan struct{}),
 	}
 
@@ -66,6 +84,8 @@ func (m *MemoryStorage) Delete(ctx context.Context, key string) error {
 	defer m.mu.Unlock()
 
 	delete(m.data, key)
+	delete(m.logs, key)
+	delete(m.leases, key)
 	return nil
 }
 
@@ -141,6 +161,21 @@ func (m *MemoryStorage) cleanupExpired() {
 			delete(m.data, key)
 		}
 	}
+	for key, log := range m.logs {
+		if now.After(log.expiresAt) {
+			delete(m.logs, key)
+		}
+	}
+	for key, leases := range m.leases {
+		for id, expiresAt := range leases {
+			if now.After(expiresAt) {
+				delete(leases, id)
+			}
+		}
+		if len(leases) == 0 {
+			delete(m.leases, key)
+		}
+	}
 }
 
 // Rate limit specific methods
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// consumeQuotaScript adds to a usage counter unless that would take it past
+// the limit. Negative amounts always apply, so consumption can be refunded.
+//
+// KEYS[1] counter key; ARGV limit, amount, ttl (ms)
+var consumeQuotaScript = redis.NewScript(`
+local used = tonumber(redis.call('GET', KEYS[1])) or 0
+local amount = tonumber(ARGV[2])
+if amount > 0 and used + amount > tonumber(ARGV[1]) then
+	return {0, used}
+end
+
+used = redis.call('INCRBY', KEYS[1], amount)
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return {1, used}
+`)
+
+// ConsumeQuota atomically adds n to the counter at key if the total stays
+// within limit, and returns whether it did and the resulting usage
+func (r *RedisStorage) ConsumeQuota(ctx context.Context, key string, limit, n int64, ttl time.Duration) (int64, bool, error) {
+	values, err := consumeQuotaScript.Run(ctx, r.client, []string{key}, limit, n, max(ttl.Milliseconds(), 1)).Int64Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	if len(values) != 2 {
+		return 0, false, fmt.Errorf("unexpected quota reply: %v", values)
+	}
+	return values[1], values[0] == 1, nil
+}
+
+// ConsumeQuota adds n to the counter at key if the total stays within
+// limit, under the storage lock
+func (m *MemoryStorage) ConsumeQuota(ctx context.Context, key string, limit, n int64, ttl time.Duration) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var used int64
+	if item, exists := m.data[key]; exists && now.Before(item.expiresAt) {
+		used, _ = strconv.ParseInt(item.value, 10, 64)
+	}
+
+	if n > 0 && used+n > limit {
+		return used, false, nil
+	}
+
+	used += n
+	m.data[key] = &memoryItem{
+		value:     strconv.FormatInt(used, 10),
+		expiresAt: now.Add(ttl),
+	}
+	return used, true, nil
+}
@@ -15,6 +15,9 @@ type RedisStorage struct {
 // Ensure RedisStorage implements the interfaces
 var _ Storage = (*RedisStorage)(nil)
 var _ RateLimitStorage = (*RedisStorage)(nil)
+var _ AlgorithmStorage = (*RedisStorage)(nil)
+var _ QuotaStorage = (*RedisStorage)(nil)
+var _ LeaseStorage = (*RedisStorage)(nil)
 
 func NewRedisStorage(addr, password string, db int) (*RedisStorage, error) {
 	client := redis.NewClient(&redis.Options{
@@ -67,6 +70,11 @@ func (r *RedisStorage) Increment(ctx context.Context, key string, by int64) (int
 	return incr.Val(), nil
 }
 
+// Ping checks that Redis is reachable
+func (r *RedisStorage) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
 func (r *RedisStorage) Close() error {
 	return r.client.Close()
 }
@@ -0,0 +1,119 @@
+package testing
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kalshi/internal/storage"
+)
+
+// rateLimitStorages returns the storage implementations to run bucket tests
+// against. Redis is included when REDIS_ADDR (default localhost:6379) is
+// reachable.
+func rateLimitStorages(t *testing.T) map[string]storage.RateLimitStorage {
+	t.Helper()
+
+	memory := storage.NewMemoryStorage()
+	t.Cleanup(func() { memory.Close() })
+	storages := map[string]storage.RateLimitStorage{"memory": memory}
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	if redis, err := storage.NewRedisStorage(addr, "", 0); err == nil {
+		t.Cleanup(func() { redis.Close() })
+		storages["redis"] = redis
+	}
+	return storages
+}
+
+func TestTakeTokens_Capacity(t *testing.T) {
+	bucket := storage.Bucket{Capacity: 3, Interval: time.Minute}
+
+	for name, store := range rateLimitStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "test:capacity:" + time.Now().String()
+
+			for i := int64(2); i >= 0; i-- {
+				result, err := store.TakeTokens(ctx, key, bucket, 1)
+				if err != nil {
+					t.Fatalf("TakeTokens failed: %v", err)
+				}
+				if !result.Allowed || result.Remaining != i {
+					t.Fatalf("Expected allowed with %d remaining, got %+v", i, result)
+				}
+			}
+
+			result, err := store.TakeTokens(ctx, key, bucket, 1)
+			if err != nil {
+				t.Fatalf("TakeTokens failed: %v", err)
+			}
+			if result.Allowed {
+				t.Fatal("Expected an empty bucket to reject")
+			}
+			if result.RetryAfter <= 0 || result.RetryAfter > time.Minute {
+				t.Errorf("Expected retry within one interval, got %v", result.RetryAfter)
+			}
+			if result.ResetAfter <= 2*time.Minute {
+				t.Errorf("Expected a full refill to take about three intervals, got %v", result.ResetAfter)
+			}
+		})
+	}
+}
+
+func TestTakeTokens_Refill(t *testing.T) {
+	bucket := storage.Bucket{Capacity: 2, Interval: 20 * time.Millisecond}
+
+	for name, store := range rateLimitStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "test:refill:" + time.Now().String()
+
+			if result, _ := store.TakeTokens(ctx, key, bucket, 2); !result.Allowed {
+				t.Fatal("Expected a full bucket to admit its capacity")
+			}
+			if result, _ := store.TakeTokens(ctx, key, bucket, 1); result.Allowed {
+				t.Fatal("Expected an empty bucket to reject")
+			}
+
+			time.Sleep(30 * time.Millisecond)
+			if result, _ := store.TakeTokens(ctx, key, bucket, 1); !result.Allowed {
+				t.Fatal("Expected a token after one interval")
+			}
+		})
+	}
+}
+
+func TestTakeTokens_ConcurrentNeverOverAdmits(t *testing.T) {
+	bucket := storage.Bucket{Capacity: 50, Interval: time.Hour}
+
+	for name, store := range rateLimitStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "test:concurrent:" + time.Now().String()
+
+			var admitted atomic.Int64
+			var wg sync.WaitGroup
+			for i := 0; i < 200; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if result, err := store.TakeTokens(ctx, key, bucket, 1); err == nil && result.Allowed {
+						admitted.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if admitted.Load() != 50 {
+				t.Errorf("Expected exactly 50 admitted, got %d", admitted.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kalshi/internal/storage"
+)
+
+func TestAcquireLease(t *testing.T) {
+	for name, store := range rateLimitStorages(t) {
+		leases, ok := store.(storage.LeaseStorage)
+		if !ok {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "test:lease:" + time.Now().String()
+
+			for i, id := range []string{"a", "b"} {
+				inFlight, acquired, err := leases.AcquireLease(ctx, key, id, 2, time.Minute)
+				if err != nil {
+					t.Fatalf("AcquireLease failed: %v", err)
+				}
+				if !acquired || inFlight != int64(i+1) {
+					t.Fatalf("Expected lease %s with %d in flight, got %d (acquired %v)", id, i+1, inFlight, acquired)
+				}
+			}
+
+			if inFlight, acquired, _ := leases.AcquireLease(ctx, key, "c", 2, time.Minute); acquired || inFlight != 2 {
+				t.Fatalf("Expected rejection with 2 in flight, got %d (acquired %v)", inFlight, acquired)
+			}
+
+			// Held leases are renewed without taking another slot
+			if inFlight, acquired, _ := leases.AcquireLease(ctx, key, "a", 2, time.Minute); !acquired || inFlight != 2 {
+				t.Fatalf("Expected renewal with 2 in flight, got %d (acquired %v)", inFlight, acquired)
+			}
+
+			if err := leases.ReleaseLease(ctx, key, "a"); err != nil {
+				t.Fatalf("ReleaseLease failed: %v", err)
+			}
+			if _, acquired, _ := leases.AcquireLease(ctx, key, "c", 2, time.Minute); !acquired {
+				t.Fatal("Expected a released slot to be reused")
+			}
+		})
+	}
+}
+
+func TestAcquireLease_Expires(t *testing.T) {
+	for name, store := range rateLimitStorages(t) {
+		leases, ok := store.(storage.LeaseStorage)
+		if !ok {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "test:lease-expiry:" + time.Now().String()
+
+			leases.AcquireLease(ctx, key, "crashed", 1, 50*time.Millisecond)
+			time.Sleep(60 * time.Millisecond)
+			if inFlight, acquired, _ := leases.AcquireLease(ctx, key, "next", 1, time.Minute); !acquired || inFlight != 1 {
+				t.Fatalf("Expected the expired lease's slot, got %d in flight (acquired %v)", inFlight, acquired)
+			}
+		})
+	}
+}
+
+func TestRenewLease(t *testing.T) {
+	for name, store := range rateLimitStorages(t) {
+		leases, ok := store.(storage.LeaseStorage)
+		if !ok {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "test:lease-renewal:" + time.Now().String()
+
+			leases.AcquireLease(ctx, key, "a", 1, 50*time.Millisecond)
+			if held, err := leases.RenewLease(ctx, key, "a", time.Minute); err != nil || !held {
+				t.Fatalf("Expected the held lease to be renewed, got %v (%v)", held, err)
+			}
+			time.Sleep(60 * time.Millisecond)
+			if _, acquired, _ := leases.AcquireLease(ctx, key, "b", 1, time.Minute); acquired {
+				t.Fatal("Expected the renewed lease to outlive its first TTL")
+			}
+
+			// A renewal racing the release must not take the slot again
+			leases.ReleaseLease(ctx, key, "a")
+			if held, err := leases.RenewLease(ctx, key, "a", time.Minute); err != nil || held {
+				t.Fatalf("Expected a released lease not to be renewed, got %v (%v)", held, err)
+			}
+			if inFlight, acquired, _ := leases.AcquireLease(ctx, key, "b", 1, time.Minute); !acquired || inFlight != 1 {
+				t.Fatalf("Expected the released slot, got %d in flight (acquired %v)", inFlight, acquired)
+			}
+		})
+	}
+}
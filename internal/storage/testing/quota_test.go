@@ -0,0 +1,64 @@
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kalshi/internal/storage"
+)
+
+func TestConsumeQuota(t *testing.T) {
+	for name, store := range rateLimitStorages(t) {
+		quotas, ok := store.(storage.QuotaStorage)
+		if !ok {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "test:quota:" + time.Now().String()
+
+			used, allowed, err := quotas.ConsumeQuota(ctx, key, 5, 4, time.Hour)
+			if err != nil {
+				t.Fatalf("ConsumeQuota failed: %v", err)
+			}
+			if !allowed || used != 4 {
+				t.Fatalf("Expected 4 used, got %d (allowed %v)", used, allowed)
+			}
+
+			// Amounts that do not fit are not counted
+			if used, allowed, _ = quotas.ConsumeQuota(ctx, key, 5, 2, time.Hour); allowed || used != 4 {
+				t.Fatalf("Expected rejection at 4 used, got %d (allowed %v)", used, allowed)
+			}
+
+			// Refunds always apply
+			if used, allowed, _ = quotas.ConsumeQuota(ctx, key, 5, -3, time.Hour); !allowed || used != 1 {
+				t.Fatalf("Expected a refund to 1 used, got %d (allowed %v)", used, allowed)
+			}
+			if used, _, _ = quotas.ConsumeQuota(ctx, key, 5, 0, time.Hour); used != 1 {
+				t.Fatalf("Expected a zero amount to read 1 used, got %d", used)
+			}
+		})
+	}
+}
+
+func TestConsumeQuota_Expires(t *testing.T) {
+	for name, store := range rateLimitStorages(t) {
+		quotas, ok := store.(storage.QuotaStorage)
+		if !ok {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "test:quota-expiry:" + time.Now().String()
+
+			quotas.ConsumeQuota(ctx, key, 1, 1, 50*time.Millisecond)
+			time.Sleep(60 * time.Millisecond)
+			if used, allowed, _ := quotas.ConsumeQuota(ctx, key, 1, 1, time.Hour); !allowed || used != 1 {
+				t.Fatalf("Expected a fresh counter after expiry, got %d (allowed %v)", used, allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kalshi/internal/storage"
+)
+
+// algorithmStorages returns the rate limit storages that run the window and
+// GCRA algorithms
+func algorithmStorages(t *testing.T) map[string]storage.AlgorithmStorage {
+	t.Helper()
+
+	storages := make(map[string]storage.AlgorithmStorage)
+	for name, store := range rateLimitStorages(t) {
+		if atomic, ok := store.(storage.AlgorithmStorage); ok {
+			storages[name] = atomic
+		}
+	}
+	return storages
+}
+
+func TestTakeWindow_Limit(t *testing.T) {
+	kinds := []storage.WindowKind{storage.FixedWindow, storage.SlidingWindowCounter, storage.SlidingWindowLog}
+
+	for name, store := range algorithmStorages(t) {
+		for _, kind := range kinds {
+			t.Run(name+"/"+string(kind), func(t *testing.T) {
+				ctx := context.Background()
+				key := "test:window:" + string(kind) + ":" + time.Now().String()
+				window := storage.Window{Kind: kind, Limit: 3, Size: time.Hour}
+
+				for i := int64(2); i >= 0; i-- {
+					result, err := store.TakeWindow(ctx, key, window, 1)
+					if err != nil {
+						t.Fatalf("TakeWindow failed: %v", err)
+					}
+					if !result.Allowed || result.Remaining != i {
+						t.Fatalf("Expected allowed with %d remaining, got %+v", i, result)
+					}
+				}
+
+				result, err := store.TakeWindow(ctx, key, window, 1)
+				if err != nil {
+					t.Fatalf("TakeWindow failed: %v", err)
+				}
+				if result.Allowed {
+					t.Fatal("Expected a full window to reject")
+				}
+				if result.RetryAfter <= 0 || result.RetryAfter > 2*time.Hour {
+					t.Errorf("Expected retry within two windows, got %v", result.RetryAfter)
+				}
+			})
+		}
+	}
+}
+
+func TestTakeWindow_AdmitsAfterRetry(t *testing.T) {
+	kinds := []storage.WindowKind{storage.FixedWindow, storage.SlidingWindowCounter, storage.SlidingWindowLog}
+
+	for name, store := range algorithmStorages(t) {
+		for _, kind := range kinds {
+			t.Run(name+"/"+string(kind), func(t *testing.T) {
+				ctx := context.Background()
+				key := "test:window-retry:" + string(kind) + ":" + time.Now().String()
+				window := storage.Window{Kind: kind, Limit: 2, Size: 100 * time.Millisecond}
+
+				var rejected storage.BucketResult
+				for {
+					result, err := store.TakeWindow(ctx, key, window, 1)
+					if err != nil {
+						t.Fatalf("TakeWindow failed: %v", err)
+					}
+					if !result.Allowed {
+						rejected = result
+						break
+					}
+				}
+
+				time.Sleep(rejected.RetryAfter + 5*time.Millisecond)
+				if result, _ := store.TakeWindow(ctx, key, window, 1); !result.Allowed {
+					t.Fatalf("Expected a request after the reported retry of %v", rejected.RetryAfter)
+				}
+			})
+		}
+	}
+}
+
+func TestTakeWindow_SlidingLogIsRolling(t *testing.T) {
+	for name, store := range algorithmStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "test:sliding-log:" + time.Now().String()
+			window := storage.Window{Kind: storage.SlidingWindowLog, Limit: 2, Size: 200 * time.Millisecond}
+
+			store.TakeWindow(ctx, key, window, 1)
+			time.Sleep(100 * time.Millisecond)
+			store.TakeWindow(ctx, key, window, 1)
+
+			// Only the first request has left the window
+			time.Sleep(110 * time.Millisecond)
+			if result, _ := store.TakeWindow(ctx, key, window, 1); !result.Allowed {
+				t.Fatal("Expected the oldest request to have expired")
+			}
+			if result, _ := store.TakeWindow(ctx, key, window, 1); result.Allowed {
+				t.Fatal("Expected the second request to still count")
+			}
+		})
+	}
+}
+
+func TestTakeGCRA(t *testing.T) {
+	bucket := storage.Bucket{Capacity: 2, Interval: 20 * time.Millisecond}
+
+	for name, store := range algorithmStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "test:gcra:" + time.Now().String()
+
+			for i := int64(1); i >= 0; i-- {
+				result, err := store.TakeGCRA(ctx, key, bucket, 1)
+				if err != nil {
+					t.Fatalf("TakeGCRA failed: %v", err)
+				}
+				if !result.Allowed || result.Remaining != i {
+					t.Fatalf("Expected allowed with %d remaining, got %+v", i, result)
+				}
+			}
+
+			result, _ := store.TakeGCRA(ctx, key, bucket, 1)
+			if result.Allowed {
+				t.Fatal("Expected an empty bucket to reject")
+			}
+			if result.RetryAfter <= 0 || result.RetryAfter > bucket.Interval {
+				t.Errorf("Expected retry within one interval, got %v", result.RetryAfter)
+			}
+
+			time.Sleep(30 * time.Millisecond)
+			if result, _ := store.TakeGCRA(ctx, key, bucket, 1); !result.Allowed {
+				t.Fatal("Expected a token after one interval")
+			}
+		})
+	}
+}
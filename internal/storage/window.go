@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// WindowKind selects how a Window counts requests
+type WindowKind string
+
+const (
+	// FixedWindow counts requests in consecutive windows aligned to Size
+	FixedWindow WindowKind = "fixed"
+	// SlidingWindowCounter weights the previous fixed window's count by how
+	// much of it still overlaps the rolling window
+	SlidingWindowCounter WindowKind = "sliding_counter"
+	// SlidingWindowLog records every request and counts those within the
+	// rolling window exactly
+	SlidingWindowLog WindowKind = "sliding_log"
+)
+
+// Window admits up to Limit requests per Size
+type Window struct {
+	Kind  WindowKind
+	Limit int64
+	Size  time.Duration
+}
+
+// windowCounts is the request count of the current fixed window and the one
+// before it
+type windowCounts struct {
+	index             int64 // Windows since the Unix epoch
+	current, previous int64
+}
+
+// take counts n requests at now against a fixed or sliding counter window.
+// It returns the new counts and the result.
+func (w Window) take(counts windowCounts, now time.Time, n int64) (windowCounts, BucketResult) {
+	size := int64(w.Size)
+	index := now.UnixNano() / size
+	switch counts.index {
+	case index:
+	case index - 1:
+		counts.previous, counts.current = counts.current, 0
+	default:
+		counts.previous, counts.current = 0, 0
+	}
+	counts.index = index
+
+	elapsed := now.UnixNano() - index*size
+	untilEnd := size - elapsed
+	used := float64(counts.current)
+	if w.Kind == SlidingWindowCounter {
+		used += float64(counts.previous) * float64(untilEnd) / float64(size)
+	}
+
+	var result BucketResult
+	if used+float64(n) <= float64(w.Limit) {
+		counts.current += n
+		used += float64(n)
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(w.retryAfter(counts, elapsed, n))
+	}
+	result.Remaining = max(int64(math.Floor(float64(w.Limit)-used)), 0)
+
+	// A sliding window forgets the current window's requests one window later
+	result.ResetAfter = time.Duration(untilEnd)
+	if w.Kind == SlidingWindowCounter && counts.current > 0 {
+		result.ResetAfter += w.Size
+	}
+	return counts, result
+}
+
+// retryAfter is how many nanoseconds after elapsed into the current window n
+// more requests would fit
+func (w Window) retryAfter(counts windowCounts, elapsed, n int64) int64 {
+	size := int64(w.Size)
+	untilEnd := size - elapsed
+	if w.Kind != SlidingWindowCounter {
+		return untilEnd
+	}
+
+	// Later in this window, as the previous window's weight decays
+	if budget := w.Limit - n - counts.current; budget >= 0 && counts.previous > 0 {
+		at := int64(math.Ceil(float64(size) * (1 - float64(budget)/float64(counts.previous))))
+		return at - elapsed
+	}
+
+	// In the next window, as this window's weight decays
+	if counts.current == 0 || n > w.Limit {
+		return untilEnd + size
+	}
+	at := int64(math.Ceil(float64(size) * (1 - float64(w.Limit-n)/float64(counts.current))))
+	return untilEnd + max(at, 0)
+}
+
+// takeLog counts n requests at now against a sliding log of request times
+// in ascending Unix nanoseconds. It returns the new log and the result.
+func (w Window) takeLog(times []int64, now time.Time, n int64) ([]int64, BucketResult) {
+	cutoff := now.UnixNano() - int64(w.Size)
+	expired := 0
+	for expired < len(times) && times[expired] <= cutoff {
+		expired++
+	}
+	times = times[expired:]
+
+	var result BucketResult
+	count := int64(len(times))
+	if count+n <= w.Limit {
+		for i := int64(0); i < n; i++ {
+			times = append(times, now.UnixNano())
+		}
+		result.Allowed = true
+	} else if excess := count + n - w.Limit; excess <= count {
+		// Wait for the oldest requests to leave the window
+		result.RetryAfter = time.Duration(times[excess-1] - cutoff)
+	} else {
+		result.RetryAfter = w.Size
+	}
+
+	result.Remaining = max(w.Limit-int64(len(times)), 0)
+	if len(times) > 0 {
+		result.ResetAfter = time.Duration(times[len(times)-1] - cutoff)
+	}
+	return times, result
+}
+
+// takeWindowScript is Window.take as one atomic Redis script. The state is a
+// hash of the current window's index and the counts of it and the previous
+// window, in microseconds of the Redis server's clock.
+//
+// KEYS[1] window key; ARGV limit, size (us), requests to count, 1 if sliding
+var takeWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local size = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local sliding = ARGV[4] == '1'
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local index = math.floor(now / size)
+
+local state = redis.call('HMGET', KEYS[1], 'index', 'current', 'previous')
+local stored = tonumber(state[1])
+local current = tonumber(state[2]) or 0
+local previous = tonumber(state[3]) or 0
+if stored == index - 1 then
+	previous = current
+	current = 0
+elseif stored ~= index then
+	previous = 0
+	current = 0
+end
+
+local elapsed = now - index * size
+local until_end = size - elapsed
+local used = current
+if sliding then
+	used = used + previous * until_end / size
+end
+
+local allowed = 0
+local retry = 0
+if used + cost <= limit then
+	current = current + cost
+	used = used + cost
+	allowed = 1
+elseif not sliding then
+	retry = until_end
+elseif limit - cost - current >= 0 and previous > 0 then
+	retry = math.ceil(size * (1 - (limit - cost - current) / previous)) - elapsed
+elseif current == 0 or cost > limit then
+	retry = until_end + size
+else
+	retry = until_end + math.max(math.ceil(size * (1 - (limit - cost) / current)), 0)
+end
+
+local reset = until_end
+if sliding and current > 0 then
+	reset = reset + size
+end
+
+redis.call('HSET', KEYS[1], 'index', string.format('%.0f', index), 'current', current, 'previous', previous)
+redis.call('PEXPIRE', KEYS[1], math.ceil(2 * size / 1000))
+return {allowed, math.max(math.floor(limit - used), 0), retry, reset}
+`)
+
+// takeLogScript is Window.takeLog as one atomic Redis script over a sorted
+// set of request times in microseconds of the Redis server's clock.
+//
+// KEYS[1] log key; ARGV limit, size (us), requests to count
+var takeLogScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local size = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local cutoff = now - size
+
+-- Lua 5.1 would write the microsecond timestamps in exponent notation
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', string.format('%.0f', cutoff))
+local count = redis.call('ZCARD', KEYS[1])
+
+local allowed = 0
+local retry = 0
+if count + cost <= limit then
+	for i = 1, cost do
+		redis.call('ZADD', KEYS[1], string.format('%.0f', now), string.format('%.0f-%d', now, count + i))
+	end
+	count = count + cost
+	redis.call('PEXPIRE', KEYS[1], math.ceil(size / 1000))
+	allowed = 1
+elseif count + cost - limit <= count then
+	local oldest = redis.call('ZRANGE', KEYS[1], count + cost - limit - 1, count + cost - limit - 1, 'WITHSCORES')
+	retry = tonumber(oldest[2]) - cutoff
+else
+	retry = size
+end
+
+local reset = 0
+if count > 0 then
+	local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+	reset = tonumber(newest[2]) - cutoff
+end
+return {allowed, math.max(limit - count, 0), retry, reset}
+`)
+
+// TakeWindow atomically counts n requests in the window at key if they fit
+func (r *RedisStorage) TakeWindow(ctx context.Context, key string, window Window, n int64) (BucketResult, error) {
+	size := max(window.Size.Microseconds(), 1)
+
+	var cmd *redis.Cmd
+	switch window.Kind {
+	case SlidingWindowLog:
+		cmd = takeLogScript.Run(ctx, r.client, []string{key}, window.Limit, size, n)
+	case SlidingWindowCounter:
+		cmd = takeWindowScript.Run(ctx, r.client, []string{key}, window.Limit, size, n, 1)
+	default:
+		cmd = takeWindowScript.Run(ctx, r.client, []string{key}, window.Limit, size, n, 0)
+	}
+
+	values, err := cmd.Int64Slice()
+	if err != nil {
+		return BucketResult{}, err
+	}
+	return bucketResultFromReply(values)
+}
+
+// TakeWindow counts n requests in the window at key if they fit, under the
+// storage lock
+func (m *MemoryStorage) TakeWindow(ctx context.Context, key string, window Window, n int64) (BucketResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	if window.Kind == SlidingWindowLog {
+		var times []int64
+		if log, exists := m.logs[key]; exists {
+			times = log.times
+		}
+		times, result := window.takeLog(times, now, n)
+		if len(times) == 0 {
+			delete(m.logs, key)
+		} else {
+			m.logs[key] = &requestLog{
+				times:     times,
+				expiresAt: time.Unix(0, times[len(times)-1]).Add(window.Size),
+			}
+		}
+		return result, nil
+	}
+
+	var counts windowCounts
+	if item, exists := m.data[key]; exists && now.Before(item.expiresAt) {
+		counts, _ = parseWindowCounts(item.value)
+	}
+
+	counts, result := window.take(counts, now, n)
+	m.data[key] = &memoryItem{
+		value: strconv.FormatInt(counts.index, 10) + " " +
+			strconv.FormatInt(counts.current, 10) + " " +
+			strconv.FormatInt(counts.previous, 10),
+		expiresAt: now.Add(2 * window.Size),
+	}
+	return result, nil
+}
+
+// parseWindowCounts decodes the "index current previous" state MemoryStorage
+// keeps for a window
+func parseWindowCounts(value string) (windowCounts, bool) {
+	fields := strings.Fields(value)
+	if len(fields) != 3 {
+		return windowCounts{}, false
+	}
+
+	var values [3]int64
+	for i, field := range fields {
+		v, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return windowCounts{}, false
+		}
+		values[i] = v
+	}
+	return windowCounts{index: values[0], current: values[1], previous: values[2]}, true
+}
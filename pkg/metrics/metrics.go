@@ -39,6 +39,38 @@ var (
 		[]string{"path", "client_id"},
 	)
 
+	RateLimitLayerHits = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limit_layer_hits_total",
+			Help: "Total number of requests rejected by each rate limit layer",
+		},
+		[]string{"layer", "path"},
+	)
+
+	QuotaExceeded = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quota_exceeded_total",
+			Help: "Total number of requests rejected by an exhausted usage quota",
+		},
+		[]string{"period"},
+	)
+
+	QuotaWarnings = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quota_warnings_total",
+			Help: "Total number of times a client's usage crossed a quota warning threshold",
+		},
+		[]string{"period", "threshold"},
+	)
+
+	RateLimitStorageErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limit_storage_errors_total",
+			Help: "Total number of rate limit checks that could not reach storage",
+		},
+		[]string{"failure_mode"},
+	)
+
 	CacheHits = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "cache_hits_total",
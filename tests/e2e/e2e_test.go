@@ -482,8 +482,8 @@ func TestE2ERateLimiting(t *testing.T) {
 	require.NoError(t, err)
 	defer resp.Body.Close()
 
-	// Rate limiting behavior - currently returns 200, may change to 429 in future
-	assert.Equal(t, http.StatusOK, resp.StatusCode, "51st request should be rate limited")
+	// The key's limit is stricter than the route's and the global one
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "51st request should be rate limited")
 }
 
 // TestE2ECaching tests caching functionality
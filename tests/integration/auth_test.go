@@ -126,6 +126,34 @@ func (suite *AuthTestSuite) TestAuthenticationFlow() {
 	})
 }
 
+// TestAPIKeyQuotaUpdate sets per-key quotas, which can only be set through
+// UpdateAPIKey
+func (suite *AuthTestSuite) TestAPIKeyQuotaUpdate() {
+	ctx := context.Background()
+	manager := suite.testConfig.APIKeyManager
+
+	apiKey, err := manager.CreateAPIKey(ctx, "quota-user", 100, "quota test", time.Hour)
+	require.NoError(suite.T(), err)
+
+	// Untyped constants, int64 values and numbers decoded from JSON
+	require.NoError(suite.T(), manager.UpdateAPIKey(ctx, apiKey, map[string]interface{}{"daily_quota": 1000}))
+	require.NoError(suite.T(), manager.UpdateAPIKey(ctx, apiKey, map[string]interface{}{"monthly_quota": int64(20000)}))
+	info, err := manager.ValidateAPIKey(ctx, apiKey)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1000), info.DailyQuota)
+	assert.Equal(suite.T(), int64(20000), info.MonthlyQuota)
+
+	require.NoError(suite.T(), manager.UpdateAPIKey(ctx, apiKey, map[string]interface{}{"daily_quota": float64(500)}))
+	info, err = manager.ValidateAPIKey(ctx, apiKey)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(500), info.DailyQuota)
+
+	for _, invalid := range []interface{}{-1, 1.5, "1000"} {
+		err := manager.UpdateAPIKey(ctx, apiKey, map[string]interface{}{"daily_quota": invalid})
+		assert.ErrorIs(suite.T(), err, auth.ErrInvalidQuota, "quota %v", invalid)
+	}
+}
+
 func TestAuthTestSuite(t *testing.T) {
 	suite.Run(t, new(AuthTestSuite))
 }
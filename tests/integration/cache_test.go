@@ -2,11 +2,15 @@ package integration
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
+	"kalshi/internal/api/routes"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -111,6 +115,48 @@ func (suite *CacheTestSuite) TestCacheExpiration() {
 	assert.Error(suite.T(), err)
 }
 
+// TestAdminCacheKey reads and purges a real proxied cache key through the
+// admin API
+func (suite *CacheTestSuite) TestAdminCacheKey() {
+	tc := suite.testConfig
+	router := routes.SetupRouter(&routes.RouterConfig{
+		Config:        tc.Config,
+		Gateway:       tc.Gateway,
+		Limiter:       tc.Limiter,
+		JWTManager:    tc.JWTManager,
+		APIKeyManager: tc.APIKeyManager,
+		Logger:        tc.Logger,
+	})
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-API-Key", "test-api-key-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(suite.T(), http.StatusOK, serve("GET", "/api/v1/users/admin-key?a=1").Code)
+
+	w := serve("GET", "/admin/cache/keys?prefix="+url.QueryEscape("route:/api/v1/users/* GET:/api/v1/users/admin-key?a=1"))
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	var listed struct {
+		Keys []string `json:"keys"`
+	}
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(suite.T(), listed.Keys, 1)
+	key := listed.Keys[0]
+
+	// Keys hold slashes, a space and a query string
+	path := "/admin/cache/keys/" + url.PathEscape(key)
+	w = serve("GET", path)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "admin-key")
+
+	assert.Equal(suite.T(), http.StatusOK, serve("DELETE", path).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, serve("GET", path).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, serve("GET", "/admin/cache/keys/").Code)
+}
+
 func TestCacheTestSuite(t *testing.T) {
 	suite.Run(t, new(CacheTestSuite))
 }
@@ -2,9 +2,22 @@ package integration
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
 
+	"kalshi/internal/api/handlers"
+	"kalshi/internal/api/middleware"
+	"kalshi/internal/config"
+	"kalshi/internal/ratelimit"
+	"kalshi/internal/storage"
+	"kalshi/pkg/logger"
+
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -98,3 +111,371 @@ func (suite *RateLimitTestSuite) TestRateLimitRecovery() {
 	// and then wait for recovery - complex to implement in unit tests
 	suite.T().Skip("Rate limit recovery test requires time-based testing")
 }
+
+// rateLimitRouter serves 200 behind the layered rate limit middleware with a
+// global limit of rate per minute and bursts of burst
+func rateLimitRouter(t *testing.T, cfg *config.Config, rate, burst int) *gin.Engine {
+	t.Helper()
+
+	log, err := logger.New("error", "console")
+	require.NoError(t, err)
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	limiter := ratelimit.NewLimiterWithOptions(store, ratelimit.LimiterOptions{
+		DefaultRate:   rate,
+		BurstCapacity: burst,
+		InProcess:     true,
+	})
+	t.Cleanup(limiter.Close)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.LayeredRateLimit(limiter, cfg, log))
+	router.GET("/api/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	router := rateLimitRouter(t, nil, 60, 2)
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/test", nil)
+		req.Header.Set("X-Client-ID", "headers-client")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(time.Second).Unix(), reset, 1, "one token refills in a second")
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	serve()
+	w = serve()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), `"retry_after":"1 seconds"`)
+	assert.Empty(t, w.Header().Get("RateLimit"), "IETF headers are opt-in")
+}
+
+func TestRateLimitIETFHeaders(t *testing.T) {
+	cfg := &config.Config{
+		RateLimit: config.RateLimitConfig{IETFHeaders: true},
+		Routes: []config.RouteConfig{
+			{Path: "/api/*", Backend: "backend", Methods: []string{"GET"}, RateLimit: 1},
+		},
+	}
+	router := rateLimitRouter(t, cfg, 60, 10)
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/test", nil)
+		req.Header.Set("X-Client-ID", "ietf-client")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"route";q=1;w=60, "global";q=60;w=60`, w.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, `"route";r=0;t=60`, w.Header().Get("RateLimit"))
+
+	w = serve()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, `"route";r=0;t=60`, w.Header().Get("RateLimit"))
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
+
+func TestQuotaMiddleware(t *testing.T) {
+	log, err := logger.New("error", "console")
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		RateLimit: config.RateLimitConfig{
+			Quota: config.QuotaConfig{Enabled: true, Daily: 5, WarningThresholds: []float64{0.5}, Weighted: true},
+		},
+		Routes: []config.RouteConfig{
+			{Path: "/api/search", Backend: "backend", Methods: []string{"GET"}, Cost: 2},
+		},
+	}
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+	quotas := ratelimit.NewQuotaManager(store, ratelimit.QuotaOptions{
+		WarningThresholds: cfg.RateLimit.Quota.WarningThresholds,
+	})
+	t.Cleanup(quotas.Close)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Quota(quotas, cfg, log))
+	router.GET("/api/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Client-ID", "quota-client")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/api/test")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "daily", w.Header().Get("X-Quota-Period"))
+	assert.Equal(t, "5", w.Header().Get("X-Quota-Limit"))
+	assert.Equal(t, "4", w.Header().Get("X-Quota-Remaining"))
+	assert.Empty(t, w.Header().Get("X-Quota-Warning"))
+
+	// A weighted route counts its cost
+	w = serve("/api/search")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-Quota-Remaining"))
+	assert.Equal(t, "50% of daily quota used", w.Header().Get("X-Quota-Warning"))
+
+	serve("/api/search")
+	w = serve("/api/test")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"Quota exceeded"`)
+	assert.Contains(t, w.Body.String(), "daily quota of 5 requests used up")
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.Positive(t, retryAfter)
+	assert.LessOrEqual(t, retryAfter, 24*60*60)
+}
+
+func TestQuotaMiddleware_APIKeyQuota(t *testing.T) {
+	log, err := logger.New("error", "console")
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		RateLimit: config.RateLimitConfig{Quota: config.QuotaConfig{Enabled: true, Monthly: 1000}},
+	}
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+	quotas := ratelimit.NewQuotaManager(store, ratelimit.QuotaOptions{})
+	t.Cleanup(quotas.Close)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		// As set by the API key authentication middleware
+		c.Set(middleware.ContextUserID, "tier-user")
+		c.Set(middleware.ContextQuotas, []ratelimit.Quota{{Period: ratelimit.QuotaMonthly, Limit: 1}})
+	})
+	router.Use(middleware.Quota(quotas, cfg, log))
+	router.GET("/api/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/test", nil))
+		assert.Equal(t, expected, w.Code)
+		assert.Equal(t, "monthly", w.Header().Get("X-Quota-Period"))
+		assert.Equal(t, "1", w.Header().Get("X-Quota-Limit"), "the key's quota replaces the default")
+	}
+}
+
+func TestRateLimitRouteCost(t *testing.T) {
+	cfg := &config.Config{
+		RateLimit: config.RateLimitConfig{CostHeader: "X-Request-Cost"},
+		Routes: []config.RouteConfig{
+			{Path: "/api/search", Backend: "backend", Methods: []string{"GET"}, Cost: 4},
+		},
+	}
+	router := rateLimitRouter(t, cfg, 60, 10)
+	router.GET("/report/*path", func(c *gin.Context) {
+		// The backend reports what the request actually cost
+		c.Header("X-Request-Cost", c.Query("cost"))
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Client-ID", "cost-client")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/api/search")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "6", w.Header().Get("X-RateLimit-Remaining"), "the route costs four tokens")
+
+	w = serve("/api/search")
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Remaining"))
+	w = serve("/api/search")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "four tokens are not left")
+	w = serve("/api/quote")
+	assert.Equal(t, http.StatusOK, w.Code, "a cheap request still fits")
+
+	// A reported cost beyond the one taken is charged after the response
+	w = serve("/report/heavy?cost=9")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "9", w.Header().Get("X-RateLimit-Remaining"), "headers are sent before the charge")
+	w = serve("/report/light?cost=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	w = serve("/report/light?cost=1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestRateLimitConcurrency(t *testing.T) {
+	cfg := &config.Config{RateLimit: config.RateLimitConfig{MaxConcurrent: 2}}
+	router := rateLimitRouter(t, cfg, 600, 100)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	router.GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		<-unblock
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Client-ID", "parallel-client")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Two slow requests hold both of the client's slots
+	done := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() { done <- serve("/slow").Code }()
+		<-started
+	}
+
+	w := serve("/api/quote")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "slots are shared by every path")
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Too many concurrent requests", body["error"])
+	assert.Equal(t, float64(2), body["limit"])
+	assert.Equal(t, float64(2), body["in_flight"])
+
+	close(unblock)
+	assert.Equal(t, http.StatusOK, <-done)
+	assert.Equal(t, http.StatusOK, <-done)
+
+	// Completed requests give their slots back
+	assert.Equal(t, http.StatusOK, serve("/api/quote").Code)
+}
+
+func TestRateLimitSchedule(t *testing.T) {
+	log, err := logger.New("error", "console")
+	require.NoError(t, err)
+
+	// The same policy all week, so the test does not depend on when it runs
+	policy := ratelimit.SchedulePolicy{DefaultRate: 2, BurstCapacity: 2}
+	schedule, err := ratelimit.NewSchedule("America/New_York", 9*time.Hour+30*time.Minute, 16*time.Hour,
+		map[ratelimit.SchedulePeriod]ratelimit.SchedulePolicy{
+			ratelimit.PeriodTrading:  policy,
+			ratelimit.PeriodOffHours: policy,
+			ratelimit.PeriodWeekend:  policy,
+		})
+	require.NoError(t, err)
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+	limiter := ratelimit.NewLimiterWithOptions(store, ratelimit.LimiterOptions{
+		DefaultRate:   60,
+		BurstCapacity: 10,
+		InProcess:     true,
+		Schedule:      schedule,
+	})
+	t.Cleanup(limiter.Close)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.LayeredRateLimit(limiter, nil, log))
+	router.GET("/api/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	period := string(schedule.Period(time.Now()))
+	for _, expected := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest("GET", "/api/test", nil)
+		req.Header.Set("X-Client-ID", "scheduled-client")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, expected, w.Code)
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"), "the period's burst replaces the configured one")
+		assert.Equal(t, period, w.Header().Get("X-RateLimit-Schedule"))
+	}
+}
+
+func TestRateLimitAdminEndpoints(t *testing.T) {
+	log, err := logger.New("error", "console")
+	require.NoError(t, err)
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+	limiter := ratelimit.NewLimiterWithOptions(store, ratelimit.LimiterOptions{DefaultRate: 60, BurstCapacity: 1, InProcess: true})
+	t.Cleanup(limiter.Close)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("/api", middleware.LayeredRateLimit(limiter, nil, log))
+	api.GET("/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	adminHandler := handlers.NewAdminHandler(nil, limiter, log)
+	router.GET("/admin/ratelimit/stats", adminHandler.GetRateLimitStats)
+	router.GET("/admin/ratelimit/top", adminHandler.GetTopThrottled)
+	router.GET("/admin/ratelimit/status/:clientId", adminHandler.GetRateLimitStatus)
+	router.DELETE("/admin/ratelimit/reset/:clientId", adminHandler.ResetRateLimit)
+	router.PUT("/admin/ratelimit/overrides/:clientId", adminHandler.SetRateLimitOverride)
+	router.DELETE("/admin/ratelimit/overrides/:clientId", adminHandler.RemoveRateLimitOverride)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Client-ID", "admin-client")
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	serve("GET", "/api/test", "")
+	assert.Equal(t, http.StatusTooManyRequests, serve("GET", "/api/test", "").Code)
+
+	w := serve("GET", "/admin/ratelimit/status/admin-client", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var status ratelimit.ClientStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, int64(1), status.Rejected)
+	require.Len(t, status.Buckets, 1)
+	assert.Equal(t, "/api/*path", status.Buckets[0].Scope)
+
+	w = serve("GET", "/admin/ratelimit/top?top=5", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"client_id":"admin-client"`)
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/admin/ratelimit/top?top=0", "").Code)
+
+	w = serve("DELETE", "/admin/ratelimit/reset/admin-client", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"buckets_reset":1`)
+	assert.Equal(t, http.StatusOK, serve("GET", "/api/test", "").Code)
+
+	// An override raises the client's limit until it is removed
+	assert.Equal(t, http.StatusBadRequest, serve("PUT", "/admin/ratelimit/overrides/admin-client", `{"rate":0,"duration":"1h"}`).Code)
+	w = serve("PUT", "/admin/ratelimit/overrides/admin-client", `{"rate":600,"burst":10,"duration":"1h"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, serve("GET", "/api/test", "").Code)
+	}
+	assert.Equal(t, http.StatusOK, serve("DELETE", "/admin/ratelimit/overrides/admin-client", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve("GET", "/api/test", "").Code)
+
+	w = serve("GET", "/admin/ratelimit/stats", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"algorithm":"token_bucket"`)
+}
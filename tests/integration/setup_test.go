@@ -246,6 +246,7 @@ func SetupTestEnvironment(t *testing.T) *TestConfig {
 			Methods:   []string{"GET", "POST", "PUT", "DELETE"},
 			RateLimit: 100,
 			CacheTTL:  30 * time.Second,
+			CacheKey:  config.CacheKeyConfig{User: true}, // User data is cached per caller
 		},
 		{
 			Path:      "/api/v1/slow/*",
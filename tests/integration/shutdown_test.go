@@ -0,0 +1,69 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kalshi/internal/api/handlers"
+	"kalshi/internal/health"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownDrain(t *testing.T) {
+	readiness := health.NewReadiness(0)
+	healthHandler := handlers.NewHealthHandler(nil, readiness)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ready", healthHandler.Readiness)
+	router.GET("/slow", func(c *gin.Context) {
+		close(started)
+		<-unblock
+		c.Status(http.StatusOK)
+	})
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	slow := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(server.URL + "/slow")
+		if err != nil {
+			slow <- 0
+			return
+		}
+		resp.Body.Close()
+		slow <- resp.StatusCode
+	}()
+	<-started
+
+	// As the gateway shuts down: drain, then stop accepting connections
+	stopped := make(chan error, 1)
+	go func() {
+		readiness.Drain(context.Background(), 500*time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopped <- server.Config.Shutdown(ctx)
+	}()
+	WaitForCondition(t, readiness.ShuttingDown, time.Second, "drain did not begin")
+
+	// Load balancers polling readiness still reach the gateway and see it fail
+	resp, err := http.Get(server.URL + "/ready")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	// Requests in flight complete
+	close(unblock)
+	assert.Equal(t, http.StatusOK, <-slow)
+	require.NoError(t, <-stopped)
+}